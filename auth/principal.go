@@ -0,0 +1,57 @@
+// Package auth holds the authenticated principal that middleware attaches to
+// each request and the HS256 JWT codec used for user tokens.
+package auth
+
+import "github.com/gin-gonic/gin"
+
+const (
+	PrincipalAPIKey = "api_key"
+	PrincipalUser   = "user"
+)
+
+// Roles. SystemAdmin is unscoped; bank admins are scoped to a bank, branch
+// managers and tellers to a branch, auditors to either, and customers to
+// their own customer record.
+const (
+	RoleSystemAdmin   = "system_admin"
+	RoleBankAdmin     = "bank_admin"
+	RoleBranchManager = "branch_manager"
+	RoleTeller        = "teller"
+	RoleAuditor       = "auditor"
+	RoleCustomer      = "customer"
+)
+
+const contextKey = "auth.principal"
+
+// Principal is the identity a request was authenticated as. Subject is the
+// JWT subject for users and "api_key:<id>" for service clients. The scope
+// IDs are filled in outwards: a branch-scoped principal also carries its
+// bank, and a customer its branch and bank.
+type Principal struct {
+	Type       string `json:"type"`
+	Subject    string `json:"subject"`
+	Name       string `json:"name,omitempty"`
+	APIKeyID   uint   `json:"api_key_id,omitempty"`
+	Role       string `json:"role"`
+	BankID     *uint  `json:"bank_id,omitempty"`
+	BranchID   *uint  `json:"branch_id,omitempty"`
+	CustomerID *uint  `json:"customer_id,omitempty"`
+}
+
+func (p *Principal) IsAPIKey() bool {
+	return p.Type == PrincipalAPIKey
+}
+
+func SetPrincipal(c *gin.Context, principal *Principal) {
+	c.Set(contextKey, principal)
+}
+
+// FromContext returns the principal set by the authentication middleware.
+func FromContext(c *gin.Context) (*Principal, bool) {
+	value, ok := c.Get(contextKey)
+	if !ok {
+		return nil, false
+	}
+	principal, ok := value.(*Principal)
+	return principal, ok && principal != nil
+}
@@ -0,0 +1,32 @@
+package controllers
+
+import (
+	"banking-system/auth"
+	"banking-system/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// authorize writes an error response and returns false unless the caller may
+// perform permission on resource.
+func authorize(c *gin.Context, policy AccessPolicy, permission services.Permission, resource services.Resource) bool {
+	principal, _ := auth.FromContext(c)
+	if err := policy.Authorize(principal, permission, resource); err != nil {
+		respondError(c, err)
+		return false
+	}
+	return true
+}
+
+// authorizeList writes an error response and returns false unless the
+// caller may list resources under permission; otherwise it returns the scope
+// the listing is limited to.
+func authorizeList(c *gin.Context, policy AccessPolicy, permission services.Permission) (services.ListScope, bool) {
+	principal, _ := auth.FromContext(c)
+	scope, err := policy.AuthorizeList(principal, permission)
+	if err != nil {
+		respondError(c, err)
+		return services.ListScope{}, false
+	}
+	return scope, true
+}
@@ -1,8 +1,10 @@
 package controllers
 
 import (
-	"banking-system/config"
+	"banking-system/auth"
 	"banking-system/models"
+	"banking-system/money"
+	"banking-system/services"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -14,11 +16,36 @@ type OpenAccountRequest struct {
 }
 
 type UpdateAccountRequest struct {
-	Type   string  `json:"type" binding:"required,oneof=deposit withdraw"`
-	Amount float64 `json:"amount" binding:"required,gt=0"`
+	Type   string      `json:"type" binding:"required,oneof=deposit withdraw"`
+	Amount money.Money `json:"amount" binding:"required,gt=0"`
 }
 
-func OpenSavingsAccount(c *gin.Context) {
+type AccountStatusRequest struct {
+	Reason string `json:"reason" binding:"required"`
+	Note   string `json:"note"`
+}
+
+type CloseAccountRequest struct {
+	Reason              string `json:"reason" binding:"required"`
+	Note                string `json:"note"`
+	SettlementAccountID *uint  `json:"settlement_account_id"`
+}
+
+type InterestSettingsRequest struct {
+	InterestRate    *float64 `json:"interest_rate"`
+	InterestPosting string   `json:"interest_posting"`
+}
+
+type AccountController struct {
+	accounts AccountService
+	policy   AccessPolicy
+}
+
+func NewAccountController(accounts AccountService, policy AccessPolicy) *AccountController {
+	return &AccountController{accounts: accounts, policy: policy}
+}
+
+func (ac *AccountController) OpenSavingsAccount(c *gin.Context) {
 	var req OpenAccountRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -26,66 +53,153 @@ func OpenSavingsAccount(c *gin.Context) {
 		return
 	}
 
-	var customer models.Customer
-	if err := config.GetDB().First(&customer, req.CustomerID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Customer not found"})
+	if !authorize(c, ac.policy, services.PermAccountOpen, services.CustomerResource(req.CustomerID)) {
 		return
 	}
 
-	if req.HolderRole == "" {
-		req.HolderRole = "primary_holder"
+	account, customerAccount, err := ac.accounts.OpenSavingsAccount(req.CustomerID, req.HolderRole)
+	if err != nil {
+		respondError(c, err)
+		return
 	}
 
-	tx := config.GetDB().Begin()
+	c.JSON(http.StatusCreated, gin.H{
+		"account":          account,
+		"customer_account": customerAccount,
+	})
+}
 
-	account := models.SavingsAccount{
-		Balance: 0,
+func (ac *AccountController) GetAccount(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
 	}
 
-	if err := tx.Create(&account).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if !authorize(c, ac.policy, services.PermAccountRead, services.AccountResource(id)) {
 		return
 	}
 
-	customerAccount := models.CustomerAccount{
-		CustomerID: req.CustomerID,
-		AccountID:  account.ID,
-		HolderRole: req.HolderRole,
+	account, err := ac.accounts.GetAccount(id)
+	if err != nil {
+		respondError(c, err)
+		return
 	}
 
-	if err := tx.Create(&customerAccount).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	c.JSON(http.StatusOK, account)
+}
+func (ac *AccountController) ListAccounts(c *gin.Context) {
+	customerID, ok := queryID(c, "customer_id")
+	if !ok {
+		return
+	}
+	ac.listAccounts(c, customerID)
+}
+func (ac *AccountController) ListCustomerAccounts(c *gin.Context) {
+	customerID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	ac.listAccounts(c, &customerID)
+}
+func (ac *AccountController) listAccounts(c *gin.Context, customerID *uint) {
+	scope, ok := authorizeList(c, ac.policy, services.PermAccountRead)
+	if !ok {
+		return
+	}
+	list, ok := listQuery(c)
+	if !ok {
+		return
+	}
+	query := services.AccountQuery{CustomerID: customerID, Status: c.Query("status"), Scope: scope, ListQuery: list}
+	if query.BranchID, ok = queryID(c, "branch_id"); !ok {
+		return
+	}
+	if query.BankID, ok = queryID(c, "bank_id"); !ok {
+		return
+	}
+	if query.MinBalance, ok = queryMoney(c, "min_balance"); !ok {
+		return
+	}
+	if query.MaxBalance, ok = queryMoney(c, "max_balance"); !ok {
 		return
 	}
 
-	tx.Commit()
+	accounts, err := ac.accounts.ListAccounts(query)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"account":          account,
-		"customer_account": customerAccount,
-	})
+	c.JSON(http.StatusOK, accounts)
 }
+func (ac *AccountController) GetAccountHolders(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
 
-func GetAccount(c *gin.Context) {
-	id := c.Param("id")
+	if !authorize(c, ac.policy, services.PermAccountRead, services.AccountResource(id)) {
+		return
+	}
 
-	var account models.SavingsAccount
+	holders, err := ac.accounts.GetAccountHolders(id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
 
-	if err := config.GetDB().
-		Preload("CustomerAccounts.Customer").
-		Preload("Transactions").
-		First(&account, id).Error; err != nil {
+	c.JSON(http.StatusOK, holders)
+}
+func (ac *AccountController) ListTransactions(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
 
-		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+	if !authorize(c, ac.policy, services.PermAccountRead, services.AccountResource(id)) {
 		return
 	}
 
-	c.JSON(http.StatusOK, account)
+	query := services.TransactionQuery{
+		Types:  queryList(c, "type"),
+		Cursor: c.Query("cursor"),
+	}
+	if query.MinAmount, ok = queryMoney(c, "min_amount"); !ok {
+		return
+	}
+	if query.MaxAmount, ok = queryMoney(c, "max_amount"); !ok {
+		return
+	}
+	if query.From, ok = queryTime(c, "from", false); !ok {
+		return
+	}
+	if query.To, ok = queryTime(c, "to", true); !ok {
+		return
+	}
+	if query.Limit, ok = queryInt(c, "limit"); !ok {
+		return
+	}
+	if query.IncludeTotal, ok = queryBool(c, "include_total"); !ok {
+		return
+	}
+
+	page, err := ac.accounts.GetTransactionHistory(id, query)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
 }
-func UpdateAccount(c *gin.Context) {
-	id := c.Param("id")
+func (ac *AccountController) UpdateAccount(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	if !authorize(c, ac.policy, services.PermAccountTransact, services.AccountResource(id)) {
+		return
+	}
 
 	var req UpdateAccountRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -93,48 +207,156 @@ func UpdateAccount(c *gin.Context) {
 		return
 	}
 
-	db := config.GetDB()
-	tx := db.Begin()
+	var account *models.SavingsAccount
+	var err error
+	if req.Type == services.TransactionDeposit {
+		account, err = ac.accounts.Deposit(id, req.Amount)
+	} else {
+		account, err = ac.accounts.Withdraw(id, req.Amount)
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
 
-	var account models.SavingsAccount
-	if err := tx.First(&account, id).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Account updated successfully",
+		"balance": account.Balance,
+	})
+}
+func (ac *AccountController) FreezeAccount(c *gin.Context) {
+	ac.changeStatus(c, ac.accounts.Freeze)
+}
+func (ac *AccountController) UnfreezeAccount(c *gin.Context) {
+	ac.changeStatus(c, ac.accounts.Unfreeze)
+}
+func (ac *AccountController) MarkAccountDormant(c *gin.Context) {
+	ac.changeStatus(c, ac.accounts.MarkDormant)
+}
+func (ac *AccountController) ReactivateAccount(c *gin.Context) {
+	ac.changeStatus(c, ac.accounts.Reactivate)
+}
+func (ac *AccountController) changeStatus(c *gin.Context, transition func(uint, services.StatusChange) (*models.SavingsAccount, error)) {
+	id, ok := parseID(c, "id")
+	if !ok {
 		return
 	}
-	if req.Type == "deposit" {
-		account.Balance += req.Amount
-	} else {
-		if account.Balance < req.Amount {
-			tx.Rollback()
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Insufficient balance"})
-			return
-		}
-		account.Balance -= req.Amount
+
+	if !authorize(c, ac.policy, services.PermAccountManage, services.AccountResource(id)) {
+		return
 	}
 
-	if err := tx.Save(&account).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	var req AccountStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	transaction := models.Transaction{
-		AccountID: account.ID,
-		Type:      req.Type,
-		Amount:    req.Amount,
+	account, err := transition(id, statusChange(c, req.Reason, req.Note))
+	if err != nil {
+		respondError(c, err)
+		return
 	}
 
-	if err := tx.Create(&transaction).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	c.JSON(http.StatusOK, account)
+}
+func (ac *AccountController) CloseAccount(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
 		return
 	}
 
-	tx.Commit()
+	if !authorize(c, ac.policy, services.PermAccountManage, services.AccountResource(id)) {
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Account updated successfully",
-		"balance": account.Balance,
+	var req CloseAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	closure, err := ac.accounts.CloseAccount(id, statusChange(c, req.Reason, req.Note), req.SettlementAccountID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, closure)
+}
+func (ac *AccountController) GetStatusHistory(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	if !authorize(c, ac.policy, services.PermAccountRead, services.AccountResource(id)) {
+		return
+	}
+
+	history, err := ac.accounts.GetStatusHistory(id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+func (ac *AccountController) UpdateInterestSettings(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	if !authorize(c, ac.policy, services.PermAccountManage, services.AccountResource(id)) {
+		return
+	}
+
+	var req InterestSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	account, err := ac.accounts.UpdateInterestSettings(id, services.InterestSettings{
+		InterestRate:    req.InterestRate,
+		InterestPosting: req.InterestPosting,
 	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, account)
+}
+func (ac *AccountController) GetInterestAccruals(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	if !authorize(c, ac.policy, services.PermAccountRead, services.AccountResource(id)) {
+		return
+	}
+
+	accruals, err := ac.accounts.GetInterestAccruals(id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, accruals)
+}
+
+// statusChange records the authenticated principal as the author of the
+// change.
+func statusChange(c *gin.Context, reason, note string) services.StatusChange {
+	return services.StatusChange{Reason: reason, Note: note, ChangedBy: subject(c)}
+}
+
+func subject(c *gin.Context) string {
+	if principal, ok := auth.FromContext(c); ok {
+		return principal.Subject
+	}
+	return ""
 }
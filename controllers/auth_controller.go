@@ -0,0 +1,175 @@
+package controllers
+
+import (
+	"banking-system/auth"
+	"banking-system/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type IssueAPIKeyRequest struct {
+	Name       string `json:"name" binding:"required"`
+	Role       string `json:"role" binding:"required"`
+	BankID     *uint  `json:"bank_id"`
+	BranchID   *uint  `json:"branch_id"`
+	CustomerID *uint  `json:"customer_id"`
+}
+
+type IssueTokenRequest struct {
+	Subject    string `json:"subject" binding:"required"`
+	Name       string `json:"name"`
+	Role       string `json:"role" binding:"required"`
+	BankID     *uint  `json:"bank_id"`
+	BranchID   *uint  `json:"branch_id"`
+	CustomerID *uint  `json:"customer_id"`
+}
+
+type AuthController struct {
+	auth   AuthService
+	policy AccessPolicy
+}
+
+func NewAuthController(auth AuthService, policy AccessPolicy) *AuthController {
+	return &AuthController{auth: auth, policy: policy}
+}
+
+func (ac *AuthController) IssueAPIKey(c *gin.Context) {
+	var req IssueAPIKeyRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	principal, ok := ac.principal(c)
+	if !ok {
+		return
+	}
+	grant, err := ac.policy.AuthorizeGrant(principal, services.Grant{
+		Role:       req.Role,
+		BankID:     req.BankID,
+		BranchID:   req.BranchID,
+		CustomerID: req.CustomerID,
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	key, plaintext, err := ac.auth.IssueAPIKey(req.Name, principal.Subject, *grant)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"api_key": key,
+		"key":     plaintext,
+	})
+}
+func (ac *AuthController) ListAPIKeys(c *gin.Context) {
+	principal, ok := ac.principal(c)
+	if !ok || !authorize(c, ac.policy, services.PermCredentialsManage, credentialScope(principal)) {
+		return
+	}
+
+	keys, err := ac.auth.ListAPIKeys(principal.BankID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+func (ac *AuthController) RevokeAPIKey(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	principal, ok := ac.principal(c)
+	if !ok {
+		return
+	}
+	key, err := ac.auth.GetAPIKey(id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	// Only keys the caller could have issued may be revoked by it.
+	_, err = ac.policy.AuthorizeGrant(principal, services.Grant{
+		Role:       key.Role,
+		BankID:     key.BankID,
+		BranchID:   key.BranchID,
+		CustomerID: key.CustomerID,
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	key, err = ac.auth.RevokeAPIKey(id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, key)
+}
+func (ac *AuthController) IssueToken(c *gin.Context) {
+	var req IssueTokenRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	principal, ok := ac.principal(c)
+	if !ok {
+		return
+	}
+	grant, err := ac.policy.AuthorizeGrant(principal, services.Grant{
+		Role:       req.Role,
+		BankID:     req.BankID,
+		BranchID:   req.BranchID,
+		CustomerID: req.CustomerID,
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	token, err := ac.auth.IssueToken(req.Subject, req.Name, *grant)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, token)
+}
+func (ac *AuthController) GetPrincipal(c *gin.Context) {
+	principal, ok := ac.principal(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, principal)
+}
+
+func (ac *AuthController) principal(c *gin.Context) (*auth.Principal, bool) {
+	principal, ok := auth.FromContext(c)
+	if !ok {
+		respondError(c, services.ErrUnauthenticated)
+	}
+	return principal, ok
+}
+
+// credentialScope is where principal manages credentials: its bank, or
+// everywhere for unscoped principals.
+func credentialScope(principal *auth.Principal) services.Resource {
+	if principal.BankID != nil {
+		return services.BankResource(*principal.BankID)
+	}
+	return services.GlobalResource()
+}
@@ -1,14 +1,27 @@
 package controllers
 
 import (
-	"banking-system/config"
 	"banking-system/models"
+	"banking-system/services"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
-func CreateBank(c *gin.Context) {
+type BankController struct {
+	banks  BankService
+	policy AccessPolicy
+}
+
+func NewBankController(banks BankService, policy AccessPolicy) *BankController {
+	return &BankController{banks: banks, policy: policy}
+}
+
+func (bc *BankController) CreateBank(c *gin.Context) {
+	if !authorize(c, bc.policy, services.PermBankCreate, services.GlobalResource()) {
+		return
+	}
+
 	var bank models.Bank
 
 	if err := c.ShouldBindJSON(&bank); err != nil {
@@ -16,48 +29,56 @@ func CreateBank(c *gin.Context) {
 		return
 	}
 
-	if err := config.GetDB().Create(&bank).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := bc.banks.CreateBank(&bank); err != nil {
+		respondError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusCreated, bank)
 }
-func GetBank(c *gin.Context) {
-	id := c.Param("id")
-
-	var bank models.Bank
+func (bc *BankController) GetBank(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
 
-	if err := config.GetDB().
-		Preload("Branches").
-		First(&bank, id).Error; err != nil {
+	if !authorize(c, bc.policy, services.PermBankRead, services.BankResource(id)) {
+		return
+	}
 
-		c.JSON(http.StatusNotFound, gin.H{"error": "Bank not found"})
+	bank, err := bc.banks.GetBankByID(id)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, bank)
 }
-func GetAllBanks(c *gin.Context) {
-	var banks []models.Bank
-
-	if err := config.GetDB().
-		Preload("Branches").
-		Find(&banks).Error; err != nil {
+func (bc *BankController) GetAllBanks(c *gin.Context) {
+	scope, ok := authorizeList(c, bc.policy, services.PermBankRead)
+	if !ok {
+		return
+	}
+	list, ok := listQuery(c)
+	if !ok {
+		return
+	}
 
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	banks, err := bc.banks.GetAllBanks(services.BankQuery{Name: c.Query("name"), Scope: scope, ListQuery: list})
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, banks)
 }
-func UpdateBank(c *gin.Context) {
-	id := c.Param("id")
+func (bc *BankController) UpdateBank(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
 
-	db := config.GetDB()
-	var bank models.Bank
-	if err := db.First(&bank, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Bank not found"})
+	if !authorize(c, bc.policy, services.PermBankUpdate, services.BankResource(id)) {
 		return
 	}
 
@@ -66,8 +87,10 @@ func UpdateBank(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	if err := db.Model(&bank).Updates(updatedData).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+
+	bank, err := bc.banks.UpdateBank(id, updatedData)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
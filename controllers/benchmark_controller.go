@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"banking-system/models"
+	"banking-system/services"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BenchmarkRequest struct {
+	BankID uint   `json:"bank_id" binding:"required"`
+	Code   string `json:"code" binding:"required"`
+	Name   string `json:"name" binding:"required"`
+}
+
+// BenchmarkRateRequest publishes a benchmark's rate from EffectiveDate
+// (YYYY-MM-DD, default today).
+type BenchmarkRateRequest struct {
+	Rate          *float64 `json:"rate" binding:"required,gte=0,lte=100"`
+	EffectiveDate string   `json:"effective_date"`
+}
+
+type BenchmarkController struct {
+	benchmarks BenchmarkService
+	policy     AccessPolicy
+}
+
+func NewBenchmarkController(benchmarks BenchmarkService, policy AccessPolicy) *BenchmarkController {
+	return &BenchmarkController{benchmarks: benchmarks, policy: policy}
+}
+
+func (bc *BenchmarkController) CreateBenchmark(c *gin.Context) {
+	var req BenchmarkRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !authorize(c, bc.policy, services.PermProductManage, services.BankResource(req.BankID)) {
+		return
+	}
+
+	benchmark := models.Benchmark{BankID: req.BankID, Code: req.Code, Name: req.Name}
+	if err := bc.benchmarks.CreateBenchmark(&benchmark); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, benchmark)
+}
+func (bc *BenchmarkController) GetBenchmark(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	if !authorize(c, bc.policy, services.PermProductRead, services.BenchmarkResource(id)) {
+		return
+	}
+
+	benchmark, err := bc.benchmarks.GetBenchmark(id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, benchmark)
+}
+func (bc *BenchmarkController) ListBenchmarks(c *gin.Context) {
+	bankID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	if !authorize(c, bc.policy, services.PermProductRead, services.BankResource(bankID)) {
+		return
+	}
+
+	benchmarks, err := bc.benchmarks.ListBenchmarks(bankID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, benchmarks)
+}
+func (bc *BenchmarkController) PublishRate(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	if !authorize(c, bc.policy, services.PermProductManage, services.BenchmarkResource(id)) {
+		return
+	}
+
+	var req BenchmarkRateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	effective := time.Now()
+	if req.EffectiveDate != "" {
+		date, err := time.Parse(time.DateOnly, req.EffectiveDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "effective_date must be YYYY-MM-DD"})
+			return
+		}
+		effective = date
+	}
+
+	rate, err := bc.benchmarks.PublishRate(id, effective, *req.Rate, subject(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, rate)
+}
+func (bc *BenchmarkController) ListRates(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	if !authorize(c, bc.policy, services.PermProductRead, services.BenchmarkResource(id)) {
+		return
+	}
+
+	rates, err := bc.benchmarks.ListRates(id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rates)
+}
@@ -1,56 +1,103 @@
 package controllers
 
 import (
-	"banking-system/config"
 	"banking-system/models"
+	"banking-system/services"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
-func CreateBranch(c *gin.Context) {
+type BranchController struct {
+	branches BranchService
+	policy   AccessPolicy
+}
+
+func NewBranchController(branches BranchService, policy AccessPolicy) *BranchController {
+	return &BranchController{branches: branches, policy: policy}
+}
+
+func (bc *BranchController) CreateBranch(c *gin.Context) {
 	var branch models.Branch
 
 	if err := c.ShouldBindJSON(&branch); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	var bank models.Bank
-	if err := config.GetDB().First(&bank, branch.BankID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Bank not found"})
+
+	if !authorize(c, bc.policy, services.PermBranchCreate, services.BankResource(branch.BankID)) {
 		return
 	}
 
-	if err := config.GetDB().Create(&branch).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := bc.branches.CreateBranch(&branch); err != nil {
+		respondError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusCreated, branch)
 }
-func GetBranch(c *gin.Context) {
-	id := c.Param("id")
-
-	var branch models.Branch
+func (bc *BranchController) GetBranch(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
 
-	if err := config.GetDB().
-		Preload("Bank").
-		Preload("Customers").
-		First(&branch, id).Error; err != nil {
+	if !authorize(c, bc.policy, services.PermBranchRead, services.BranchResource(id)) {
+		return
+	}
 
-		c.JSON(http.StatusNotFound, gin.H{"error": "Branch not found"})
+	branch, err := bc.branches.GetBranchByID(id)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, branch)
 }
-func UpdateBranch(c *gin.Context) {
-	id := c.Param("id")
+func (bc *BranchController) ListBranches(c *gin.Context) {
+	bankID, ok := queryID(c, "bank_id")
+	if !ok {
+		return
+	}
+	bc.listBranches(c, bankID)
+}
+func (bc *BranchController) ListBankBranches(c *gin.Context) {
+	bankID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	bc.listBranches(c, &bankID)
+}
+func (bc *BranchController) listBranches(c *gin.Context, bankID *uint) {
+	scope, ok := authorizeList(c, bc.policy, services.PermBranchRead)
+	if !ok {
+		return
+	}
+	list, ok := listQuery(c)
+	if !ok {
+		return
+	}
 
-	db := config.GetDB()
-	var branch models.Branch
-	if err := db.First(&branch, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Branch not found"})
+	branches, err := bc.branches.ListBranches(services.BranchQuery{
+		BankID:    bankID,
+		Name:      c.Query("name"),
+		Scope:     scope,
+		ListQuery: list,
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, branches)
+}
+func (bc *BranchController) UpdateBranch(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	if !authorize(c, bc.policy, services.PermBranchUpdate, services.BranchResource(id)) {
 		return
 	}
 
@@ -59,16 +106,14 @@ func UpdateBranch(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	if updatedData.BankID != 0 {
-		var bank models.Bank
-		if err := db.First(&bank, updatedData.BankID).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Bank not found"})
-			return
-		}
+	// Moving a branch is creating it under the new bank.
+	if updatedData.BankID != 0 && !authorize(c, bc.policy, services.PermBranchCreate, services.BankResource(updatedData.BankID)) {
+		return
 	}
 
-	if err := db.Model(&branch).Updates(updatedData).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	branch, err := bc.branches.UpdateBranch(id, updatedData)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"banking-system/services"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CollectionController struct {
+	collections CollectionService
+	policy      AccessPolicy
+}
+
+func NewCollectionController(collections CollectionService, policy AccessPolicy) *CollectionController {
+	return &CollectionController{collections: collections, policy: policy}
+}
+
+func (cc *CollectionController) RunCollections(c *gin.Context) {
+	if !authorize(c, cc.policy, services.PermCollectionRun, services.GlobalResource()) {
+		return
+	}
+
+	date, ok := businessDate(c, time.Now())
+	if !ok {
+		return
+	}
+
+	run, err := cc.collections.Collect(date)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
+func (cc *CollectionController) RunClassification(c *gin.Context) {
+	if !authorize(c, cc.policy, services.PermCollectionRun, services.GlobalResource()) {
+		return
+	}
+
+	date, ok := businessDate(c, time.Now().AddDate(0, 0, -1))
+	if !ok {
+		return
+	}
+
+	run, err := cc.collections.Classify(date)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
+func (cc *CollectionController) RunRateResets(c *gin.Context) {
+	if !authorize(c, cc.policy, services.PermCollectionRun, services.GlobalResource()) {
+		return
+	}
+
+	date, ok := businessDate(c, time.Now())
+	if !ok {
+		return
+	}
+
+	run, err := cc.collections.ResetRates(date)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
@@ -1,57 +1,108 @@
 package controllers
 
 import (
-	"banking-system/config"
 	"banking-system/models"
+	"banking-system/services"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
-func CreateCustomer(c *gin.Context) {
+type CustomerController struct {
+	customers CustomerService
+	policy    AccessPolicy
+}
+
+func NewCustomerController(customers CustomerService, policy AccessPolicy) *CustomerController {
+	return &CustomerController{customers: customers, policy: policy}
+}
+
+func (cc *CustomerController) CreateCustomer(c *gin.Context) {
 	var customer models.Customer
 
 	if err := c.ShouldBindJSON(&customer); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	var branch models.Branch
-	if err := config.GetDB().First(&branch, customer.BranchID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Branch not found"})
+
+	if !authorize(c, cc.policy, services.PermCustomerCreate, services.BranchResource(customer.BranchID)) {
 		return
 	}
 
-	if err := config.GetDB().Create(&customer).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := cc.customers.RegisterCustomer(&customer); err != nil {
+		respondError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusCreated, customer)
 }
-func GetCustomer(c *gin.Context) {
-	id := c.Param("id")
-
-	var customer models.Customer
+func (cc *CustomerController) GetCustomer(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
 
-	if err := config.GetDB().
-		Preload("Branch").
-		Preload("CustomerAccounts.Account").
-		Preload("Loans").
-		First(&customer, id).Error; err != nil {
+	if !authorize(c, cc.policy, services.PermCustomerRead, services.CustomerResource(id)) {
+		return
+	}
 
-		c.JSON(http.StatusNotFound, gin.H{"error": "Customer not found"})
+	customer, err := cc.customers.GetCustomerByID(id)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, customer)
 }
-func UpdateCustomer(c *gin.Context) {
-	id := c.Param("id")
+func (cc *CustomerController) ListCustomers(c *gin.Context) {
+	branchID, ok := queryID(c, "branch_id")
+	if !ok {
+		return
+	}
+	cc.listCustomers(c, branchID)
+}
+func (cc *CustomerController) ListBranchCustomers(c *gin.Context) {
+	branchID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	cc.listCustomers(c, &branchID)
+}
+func (cc *CustomerController) listCustomers(c *gin.Context, branchID *uint) {
+	scope, ok := authorizeList(c, cc.policy, services.PermCustomerRead)
+	if !ok {
+		return
+	}
+	list, ok := listQuery(c)
+	if !ok {
+		return
+	}
+	query := services.CustomerQuery{
+		BranchID:  branchID,
+		Name:      c.Query("name"),
+		Email:     c.Query("email"),
+		Scope:     scope,
+		ListQuery: list,
+	}
+	if query.BankID, ok = queryID(c, "bank_id"); !ok {
+		return
+	}
 
-	db := config.GetDB()
-	var customer models.Customer
-	if err := db.First(&customer, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Customer not found"})
+	customers, err := cc.customers.ListCustomers(query)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, customers)
+}
+func (cc *CustomerController) UpdateCustomer(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	if !authorize(c, cc.policy, services.PermCustomerUpdate, services.CustomerResource(id)) {
 		return
 	}
 
@@ -60,16 +111,14 @@ func UpdateCustomer(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	if updatedData.BranchID != 0 {
-		var branch models.Branch
-		if err := db.First(&branch, updatedData.BranchID).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Branch not found"})
-			return
-		}
+	// Moving a customer is registering them at the new branch.
+	if updatedData.BranchID != 0 && !authorize(c, cc.policy, services.PermCustomerCreate, services.BranchResource(updatedData.BranchID)) {
+		return
 	}
 
-	if err := db.Model(&customer).Updates(updatedData).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	customer, err := cc.customers.UpdateCustomer(id, updatedData)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
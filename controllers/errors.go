@@ -0,0 +1,53 @@
+package controllers
+
+import (
+	"banking-system/services"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondError maps service errors to HTTP status codes.
+func respondError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, services.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, services.ErrUnauthenticated):
+		status = http.StatusUnauthorized
+	case errors.Is(err, services.ErrForbidden):
+		status = http.StatusForbidden
+	case errors.Is(err, services.ErrConflict),
+		errors.Is(err, services.ErrAccountInactive):
+		status = http.StatusConflict
+	case errors.Is(err, services.ErrInvalidArgument),
+		errors.Is(err, services.ErrInsufficientFunds),
+		errors.Is(err, services.ErrLoanClosed),
+		errors.Is(err, services.ErrExceedsPending),
+		errors.Is(err, services.ErrNotEligible):
+		status = http.StatusBadRequest
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
+
+func parseID(c *gin.Context, param string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(param), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + param})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// bindOptionalJSON binds the request body into req for endpoints whose body
+// may be left out, treating a missing body as an empty object.
+func bindOptionalJSON(c *gin.Context, req any) bool {
+	if err := c.ShouldBindJSON(req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"banking-system/services"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InterestRunRequest names the business date a job runs for, as YYYY-MM-DD.
+// Auto-debits default to today and the other nightly jobs to yesterday.
+type InterestRunRequest struct {
+	BusinessDate string `json:"business_date"`
+}
+
+type InterestController struct {
+	interest InterestService
+	policy   AccessPolicy
+}
+
+func NewInterestController(interest InterestService, policy AccessPolicy) *InterestController {
+	return &InterestController{interest: interest, policy: policy}
+}
+
+func (ic *InterestController) RunAccrual(c *gin.Context) {
+	if !authorize(c, ic.policy, services.PermInterestRun, services.GlobalResource()) {
+		return
+	}
+
+	date, ok := businessDate(c, time.Now().AddDate(0, 0, -1))
+	if !ok {
+		return
+	}
+
+	run, err := ic.interest.Accrue(date)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
+func (ic *InterestController) RunPosting(c *gin.Context) {
+	if !authorize(c, ic.policy, services.PermInterestRun, services.GlobalResource()) {
+		return
+	}
+
+	date, ok := businessDate(c, time.Now().AddDate(0, 0, -1))
+	if !ok {
+		return
+	}
+
+	run, err := ic.interest.Post(date)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
+
+func businessDate(c *gin.Context, fallback time.Time) (time.Time, bool) {
+	var req InterestRunRequest
+	if !bindOptionalJSON(c, &req) {
+		return time.Time{}, false
+	}
+	if req.BusinessDate == "" {
+		return fallback, true
+	}
+	date, err := time.Parse(time.DateOnly, req.BusinessDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "business_date must be YYYY-MM-DD"})
+		return time.Time{}, false
+	}
+	return date, true
+}
@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"banking-system/auth"
+	"banking-system/models"
+	"banking-system/money"
+	"banking-system/services"
+	"time"
+)
+
+type BankService interface {
+	CreateBank(bank *models.Bank) error
+	GetBankByID(id uint) (*models.Bank, error)
+	GetAllBanks(query services.BankQuery) (*services.Page[models.Bank], error)
+	UpdateBank(id uint, updates models.Bank) (*models.Bank, error)
+}
+
+type BranchService interface {
+	CreateBranch(branch *models.Branch) error
+	GetBranchByID(id uint) (*models.Branch, error)
+	ListBranches(query services.BranchQuery) (*services.Page[models.Branch], error)
+	UpdateBranch(id uint, updates models.Branch) (*models.Branch, error)
+}
+
+type CustomerService interface {
+	RegisterCustomer(customer *models.Customer) error
+	GetCustomerByID(id uint) (*models.Customer, error)
+	ListCustomers(query services.CustomerQuery) (*services.Page[models.Customer], error)
+	UpdateCustomer(id uint, updates models.Customer) (*models.Customer, error)
+}
+
+type AccountService interface {
+	OpenSavingsAccount(customerID uint, holderRole string) (*models.SavingsAccount, *models.CustomerAccount, error)
+	GetAccount(accountID uint) (*models.SavingsAccount, error)
+	ListAccounts(query services.AccountQuery) (*services.Page[models.SavingsAccount], error)
+	GetAccountHolders(accountID uint) ([]models.CustomerAccount, error)
+	Deposit(accountID uint, amount money.Money) (*models.SavingsAccount, error)
+	Withdraw(accountID uint, amount money.Money) (*models.SavingsAccount, error)
+	Freeze(accountID uint, change services.StatusChange) (*models.SavingsAccount, error)
+	Unfreeze(accountID uint, change services.StatusChange) (*models.SavingsAccount, error)
+	MarkDormant(accountID uint, change services.StatusChange) (*models.SavingsAccount, error)
+	Reactivate(accountID uint, change services.StatusChange) (*models.SavingsAccount, error)
+	CloseAccount(accountID uint, change services.StatusChange, settlementAccountID *uint) (*services.AccountClosure, error)
+	GetStatusHistory(accountID uint) ([]models.AccountStatusChange, error)
+	UpdateInterestSettings(accountID uint, settings services.InterestSettings) (*models.SavingsAccount, error)
+	GetInterestAccruals(accountID uint) ([]models.InterestAccrual, error)
+	GetTransactionHistory(accountID uint, query services.TransactionQuery) (*services.TransactionPage, error)
+}
+
+type InterestService interface {
+	Accrue(date time.Time) (*services.AccrualRun, error)
+	Post(date time.Time) (*services.PostingRun, error)
+}
+
+type CollectionService interface {
+	Collect(date time.Time) (*services.CollectionRun, error)
+	Classify(date time.Time) (*services.ClassificationRun, error)
+	ResetRates(date time.Time) (*services.RateResetRun, error)
+}
+
+type BenchmarkService interface {
+	CreateBenchmark(benchmark *models.Benchmark) error
+	GetBenchmark(id uint) (*models.Benchmark, error)
+	ListBenchmarks(bankID uint) ([]models.Benchmark, error)
+	PublishRate(benchmarkID uint, effective time.Time, rate float64, createdBy string) (*models.BenchmarkRate, error)
+	ListRates(benchmarkID uint) ([]models.BenchmarkRate, error)
+}
+
+type TransferService interface {
+	Transfer(fromAccountID, toAccountID uint, amount money.Money) (*services.Transfer, error)
+	GetTransfer(transferID string) ([]models.Transaction, error)
+}
+
+type LoanService interface {
+	Apply(application services.LoanApplication) (*models.Loan, error)
+	Review(loanID uint, change services.StatusChange) (*models.Loan, error)
+	Approve(loanID uint, change services.StatusChange) (*models.Loan, error)
+	Reject(loanID uint, change services.StatusChange) (*models.Loan, error)
+	Disburse(loanID uint, change services.StatusChange) (*models.Loan, error)
+	GetStatusHistory(loanID uint) ([]models.LoanStatusChange, error)
+	GetLoanByID(loanID uint) (*models.Loan, error)
+	ListLoans(query services.LoanQuery) (*services.Page[models.Loan], error)
+	GetCustomerLoans(customerID uint) ([]models.Loan, error)
+	GetLoanPaymentHistory(loanID uint) ([]models.LoanPayment, error)
+	GetLoanSchedule(loanID uint) ([]models.LoanInstallment, error)
+	CalculateInterest(loanID uint, from, to time.Time) (*services.LoanInterest, error)
+	RepayLoan(loanID, accountID uint, amount money.Money) (*models.Loan, error)
+	Prepay(loanID, accountID uint, principal money.Money, mode string) (*services.Prepayment, error)
+	QuoteForeclosure(loanID uint, asOf time.Time) (*services.ForeclosureQuote, error)
+	Foreclose(loanID, accountID uint) (*services.ForeclosureQuote, error)
+	SetRepaymentAccount(loanID, accountID uint) (*models.Loan, error)
+	GetCollections(loanID uint) ([]models.LoanCollection, error)
+	GetClassificationHistory(loanID uint) ([]models.LoanClassification, error)
+	GetRateHistory(loanID uint) ([]models.LoanRateChange, error)
+	GrantMoratorium(loanID uint, moratorium services.Moratorium) (*services.Relief, error)
+	Restructure(loanID uint, terms services.Restructure) (*services.Relief, error)
+	GetRestructurings(loanID uint) ([]models.LoanRestructuring, error)
+	GetScheduleVersions(loanID uint) ([]services.ScheduleVersion, error)
+	WriteOff(loanID uint, change services.StatusChange) (*models.LoanWriteOff, error)
+	GetWriteOff(loanID uint) (*models.LoanWriteOff, error)
+	RecordRecovery(loanID uint, recovery services.Recovery) (*models.LoanRecovery, error)
+	GetRecoveries(loanID uint) ([]models.LoanRecovery, error)
+	Portfolio(query services.PortfolioQuery) (*services.LoanPortfolio, error)
+}
+
+type LoanProductService interface {
+	CreateProduct(product *models.LoanProduct) error
+	GetProduct(id uint) (*models.LoanProduct, error)
+	ListProducts(query services.LoanProductQuery) (*services.Page[models.LoanProduct], error)
+	UpdateProduct(id uint, updates models.LoanProduct) (*models.LoanProduct, error)
+}
+
+type LedgerService interface {
+	GetEntry(entryID uint) (*models.JournalEntry, error)
+	GetAccountByCode(code string) (*models.LedgerAccount, error)
+	Balance(ledgerAccountID uint) (money.Money, error)
+	Reconcile() ([]services.LedgerMismatch, error)
+}
+
+type AuthService interface {
+	IssueAPIKey(name, createdBy string, grant services.Grant) (*models.APIKey, string, error)
+	ListAPIKeys(bankID *uint) ([]models.APIKey, error)
+	GetAPIKey(id uint) (*models.APIKey, error)
+	RevokeAPIKey(id uint) (*models.APIKey, error)
+	IssueToken(subject, name string, grant services.Grant) (*services.IssuedToken, error)
+}
+
+type AccessPolicy interface {
+	Authorize(principal *auth.Principal, permission services.Permission, resource services.Resource) error
+	AuthorizeList(principal *auth.Principal, permission services.Permission) (services.ListScope, error)
+	AuthorizeGrant(principal *auth.Principal, grant services.Grant) (*services.Grant, error)
+}
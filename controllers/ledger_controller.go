@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"banking-system/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LedgerController struct {
+	ledger LedgerService
+	policy AccessPolicy
+}
+
+func NewLedgerController(ledger LedgerService, policy AccessPolicy) *LedgerController {
+	return &LedgerController{ledger: ledger, policy: policy}
+}
+
+func (lc *LedgerController) GetJournalEntry(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	if !authorize(c, lc.policy, services.PermLedgerRead, services.GlobalResource()) {
+		return
+	}
+
+	entry, err := lc.ledger.GetEntry(id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+func (lc *LedgerController) GetLedgerAccount(c *gin.Context) {
+	if !authorize(c, lc.policy, services.PermLedgerRead, services.GlobalResource()) {
+		return
+	}
+
+	account, err := lc.ledger.GetAccountByCode(c.Param("code"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	balance, err := lc.ledger.Balance(account.ID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"account": account,
+		"balance": balance,
+	})
+}
+func (lc *LedgerController) ReconcileLedger(c *gin.Context) {
+	if !authorize(c, lc.policy, services.PermLedgerRead, services.GlobalResource()) {
+		return
+	}
+
+	mismatches, err := lc.ledger.Reconcile()
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"balanced":   len(mismatches) == 0,
+		"mismatches": mismatches,
+	})
+}
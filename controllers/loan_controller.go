@@ -1,8 +1,9 @@
 package controllers
 
 import (
-	"banking-system/config"
 	"banking-system/models"
+	"banking-system/money"
+	"banking-system/services"
 	"net/http"
 	"time"
 
@@ -10,16 +11,87 @@ import (
 )
 
 type TakeLoanRequest struct {
-	CustomerID      uint    `json:"customer_id" binding:"required"`
-	LoanType        string  `json:"loan_type" binding:"required"`
-	PrincipalAmount float64 `json:"principal_amount" binding:"required,gt=0"`
+	CustomerID            uint        `json:"customer_id" binding:"required"`
+	ProductID             uint        `json:"product_id" binding:"required"`
+	PrincipalAmount       money.Money `json:"principal_amount" binding:"required,gt=0"`
+	TenureMonths          int         `json:"tenure_months" binding:"omitempty,gt=0"`
+	RepaymentFrequency    string      `json:"repayment_frequency"`
+	DisbursementAccountID uint        `json:"disbursement_account_id" binding:"required"`
+}
+
+type LoanDecisionRequest struct {
+	Reason string `json:"reason"`
+	Note   string `json:"note"`
 }
 
 type UpdateLoanRequest struct {
-	Amount float64 `json:"amount" binding:"required,gt=0"`
+	Amount    money.Money `json:"amount" binding:"required,gt=0"`
+	AccountID uint        `json:"account_id" binding:"required"`
+}
+
+// PrepayLoanRequest pays principal off a loan early. Mode is REDUCE_TENURE or
+// REDUCE_INSTALLMENT.
+type PrepayLoanRequest struct {
+	Amount    money.Money `json:"amount" binding:"required,gt=0"`
+	AccountID uint        `json:"account_id" binding:"required"`
+	Mode      string      `json:"mode" binding:"required"`
+}
+
+// ForecloseLoanRequest names the account a loan is paid off from.
+type ForecloseLoanRequest struct {
+	AccountID uint `json:"account_id" binding:"required"`
+}
+
+// RepaymentAccountRequest nominates the account a loan is auto-debited from.
+type RepaymentAccountRequest struct {
+	AccountID uint `json:"account_id" binding:"required"`
+}
+
+// MoratoriumRequest puts a loan's installments off for Months.
+// InterestTreatment is CAPITALISE or DEFER.
+type MoratoriumRequest struct {
+	Months            int    `json:"months" binding:"required,gt=0"`
+	InterestTreatment string `json:"interest_treatment" binding:"required"`
+	Reason            string `json:"reason" binding:"required"`
+	Note              string `json:"note"`
+}
+
+// RestructureLoanRequest gives a loan new terms; fields left out keep the
+// loan's own. TenureMonths is the whole new tenure from disbursement.
+type RestructureLoanRequest struct {
+	InterestRate *float64 `json:"interest_rate" binding:"omitempty,gte=0,lte=100"`
+	Spread       *float64 `json:"spread" binding:"omitempty,gte=-100,lte=100"`
+	TenureMonths int      `json:"tenure_months" binding:"omitempty,gt=0"`
+	Reason       string   `json:"reason" binding:"required"`
+	Note         string   `json:"note"`
+}
+
+// WriteOffRequest writes a loan off as a loss for Reason.
+type WriteOffRequest struct {
+	Reason string `json:"reason" binding:"required"`
+	Note   string `json:"note"`
+}
+
+// RecoveryRequest records Amount recovered on a written-off loan, less Cost.
+// AccountID, when given, is the borrower's account it is debited from;
+// otherwise it was received in cash.
+type RecoveryRequest struct {
+	Amount    money.Money `json:"amount" binding:"required,gt=0"`
+	Cost      money.Money `json:"cost" binding:"gte=0"`
+	AccountID *uint       `json:"account_id"`
+	Note      string      `json:"note"`
+}
+
+type LoanController struct {
+	loans  LoanService
+	policy AccessPolicy
+}
+
+func NewLoanController(loans LoanService, policy AccessPolicy) *LoanController {
+	return &LoanController{loans: loans, policy: policy}
 }
 
-func TakeLoan(c *gin.Context) {
+func (lc *LoanController) TakeLoan(c *gin.Context) {
 	var req TakeLoanRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -27,105 +99,608 @@ func TakeLoan(c *gin.Context) {
 		return
 	}
 
-	var customer models.Customer
-	if err := config.GetDB().First(&customer, req.CustomerID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Customer not found"})
+	if !authorize(c, lc.policy, services.PermLoanCreate, services.CustomerResource(req.CustomerID)) {
 		return
 	}
 
-	interestRate := 12.0
-	totalPayableAmount := req.PrincipalAmount + (req.PrincipalAmount * interestRate / 100)
+	loan, err := lc.loans.Apply(services.LoanApplication{
+		CustomerID:      req.CustomerID,
+		ProductID:       req.ProductID,
+		PrincipalAmount: req.PrincipalAmount,
+		Terms: services.LoanTerms{
+			TenureMonths:       req.TenureMonths,
+			RepaymentFrequency: req.RepaymentFrequency,
+		},
+		DisbursementAccountID: req.DisbursementAccountID,
+		SubmittedBy:           subject(c),
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
 
-	loan := models.Loan{
-		CustomerID:         req.CustomerID,
-		LoanType:           req.LoanType,
-		PrincipalAmount:    req.PrincipalAmount,
-		InterestRate:       interestRate,
-		TotalPayableAmount: totalPayableAmount,
-		PendingAmount:      totalPayableAmount,
-		StartDate:          time.Now(),
-		Status:             "ACTIVE",
+	c.JSON(http.StatusCreated, loan)
+}
+func (lc *LoanController) ReviewLoan(c *gin.Context) {
+	lc.decide(c, services.PermLoanApprove, lc.loans.Review)
+}
+func (lc *LoanController) ApproveLoan(c *gin.Context) {
+	lc.decide(c, services.PermLoanApprove, lc.loans.Approve)
+}
+func (lc *LoanController) RejectLoan(c *gin.Context) {
+	lc.decide(c, services.PermLoanApprove, lc.loans.Reject)
+}
+func (lc *LoanController) DisburseLoan(c *gin.Context) {
+	lc.decide(c, services.PermLoanDisburse, lc.loans.Disburse)
+}
+func (lc *LoanController) decide(c *gin.Context, permission services.Permission, step func(uint, services.StatusChange) (*models.Loan, error)) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
 	}
 
-	if err := config.GetDB().Create(&loan).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if !authorize(c, lc.policy, permission, services.LoanResource(id)) {
 		return
 	}
 
-	c.JSON(http.StatusCreated, loan)
+	var req LoanDecisionRequest
+	if !bindOptionalJSON(c, &req) {
+		return
+	}
+
+	loan, err := step(id, statusChange(c, req.Reason, req.Note))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, loan)
 }
-func GetLoan(c *gin.Context) {
-	id := c.Param("id")
+func (lc *LoanController) GetLoanStatusHistory(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	if !authorize(c, lc.policy, services.PermLoanRead, services.LoanResource(id)) {
+		return
+	}
+
+	history, err := lc.loans.GetStatusHistory(id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
 
-	var loan models.Loan
+	c.JSON(http.StatusOK, history)
+}
+func (lc *LoanController) GetLoan(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
 
-	if err := config.GetDB().
-		Preload("Customer").
-		Preload("LoanPayments").
-		First(&loan, id).Error; err != nil {
+	if !authorize(c, lc.policy, services.PermLoanRead, services.LoanResource(id)) {
+		return
+	}
 
-		c.JSON(http.StatusNotFound, gin.H{"error": "Loan not found"})
+	loan, err := lc.loans.GetLoanByID(id)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, loan)
 }
-func UpdateLoan(c *gin.Context) {
-	id := c.Param("id")
+func (lc *LoanController) ListLoans(c *gin.Context) {
+	scope, ok := authorizeList(c, lc.policy, services.PermLoanRead)
+	if !ok {
+		return
+	}
+	list, ok := listQuery(c)
+	if !ok {
+		return
+	}
+	query := services.LoanQuery{
+		Status:    c.Query("status"),
+		LoanType:  c.Query("loan_type"),
+		Bucket:    c.Query("bucket"),
+		Scope:     scope,
+		ListQuery: list,
+	}
+	if query.CustomerID, ok = queryID(c, "customer_id"); !ok {
+		return
+	}
+	if query.BranchID, ok = queryID(c, "branch_id"); !ok {
+		return
+	}
+	if query.BankID, ok = queryID(c, "bank_id"); !ok {
+		return
+	}
+	if query.Restructured, ok = queryOptionalBool(c, "restructured"); !ok {
+		return
+	}
 
-	var req UpdateLoanRequest
+	loans, err := lc.loans.ListLoans(query)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, loans)
+}
+func (lc *LoanController) GetCustomerLoans(c *gin.Context) {
+	customerID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	if !authorize(c, lc.policy, services.PermLoanRead, services.CustomerResource(customerID)) {
+		return
+	}
+
+	loans, err := lc.loans.GetCustomerLoans(customerID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, loans)
+}
+func (lc *LoanController) GetLoanPayments(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	if !authorize(c, lc.policy, services.PermLoanRead, services.LoanResource(id)) {
+		return
+	}
+
+	payments, err := lc.loans.GetLoanPaymentHistory(id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, payments)
+}
+func (lc *LoanController) GetLoanSchedule(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	if !authorize(c, lc.policy, services.PermLoanRead, services.LoanResource(id)) {
+		return
+	}
+
+	schedule, err := lc.loans.GetLoanSchedule(id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, schedule)
+}
+
+func (lc *LoanController) GetLoanInterest(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	if !authorize(c, lc.policy, services.PermLoanRead, services.LoanResource(id)) {
+		return
+	}
+
+	from, ok := queryTime(c, "from", false)
+	if !ok {
+		return
+	}
+	if from == nil {
+		now := time.Now()
+		from = &now
+	}
+	to, ok := queryTime(c, "to", false)
+	if !ok {
+		return
+	}
+	if to == nil {
+		yearOn := from.AddDate(1, 0, 0)
+		to = &yearOn
+	}
+
+	accrued, err := lc.loans.CalculateInterest(id, *from, *to)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, accrued)
+}
+func (lc *LoanController) GetLoanRateHistory(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	if !authorize(c, lc.policy, services.PermLoanRead, services.LoanResource(id)) {
+		return
+	}
+
+	changes, err := lc.loans.GetRateHistory(id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, changes)
+}
+func (lc *LoanController) GrantMoratorium(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	if !authorize(c, lc.policy, services.PermLoanApprove, services.LoanResource(id)) {
+		return
+	}
+
+	var req MoratoriumRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	db := config.GetDB()
-	tx := db.Begin()
+	relief, err := lc.loans.GrantMoratorium(id, services.Moratorium{
+		Months:            req.Months,
+		InterestTreatment: req.InterestTreatment,
+		Reason:            req.Reason,
+		Note:              req.Note,
+		ApprovedBy:        subject(c),
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, relief)
+}
+func (lc *LoanController) RestructureLoan(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
 
-	var loan models.Loan
-	if err := tx.First(&loan, id).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusNotFound, gin.H{"error": "Loan not found"})
+	if !authorize(c, lc.policy, services.PermLoanApprove, services.LoanResource(id)) {
 		return
 	}
 
-	if loan.Status == "CLOSED" {
-		tx.Rollback()
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Loan already closed"})
+	var req RestructureLoanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	if req.Amount > loan.PendingAmount {
-		tx.Rollback()
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Amount exceeds pending balance"})
+	relief, err := lc.loans.Restructure(id, services.Restructure{
+		InterestRate: req.InterestRate,
+		Spread:       req.Spread,
+		TenureMonths: req.TenureMonths,
+		Reason:       req.Reason,
+		Note:         req.Note,
+		ApprovedBy:   subject(c),
+	})
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
-	loan.PendingAmount -= req.Amount
+	c.JSON(http.StatusOK, relief)
+}
+func (lc *LoanController) GetLoanRestructurings(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
 
-	if loan.PendingAmount == 0 {
-		loan.Status = "CLOSED"
+	if !authorize(c, lc.policy, services.PermLoanRead, services.LoanResource(id)) {
+		return
 	}
 
-	if err := tx.Save(&loan).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	restructurings, err := lc.loans.GetRestructurings(id)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
-	payment := models.LoanPayment{
-		LoanID:      loan.ID,
-		Amount:      req.Amount,
-		PaymentDate: time.Now(),
+	c.JSON(http.StatusOK, restructurings)
+}
+func (lc *LoanController) GetScheduleVersions(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
 	}
 
-	if err := tx.Create(&payment).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if !authorize(c, lc.policy, services.PermLoanRead, services.LoanResource(id)) {
 		return
 	}
 
-	tx.Commit()
+	versions, err := lc.loans.GetScheduleVersions(id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, versions)
+}
+func (lc *LoanController) WriteOffLoan(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	if !authorize(c, lc.policy, services.PermLoanWriteOff, services.LoanResource(id)) {
+		return
+	}
+
+	var req WriteOffRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	writeOff, err := lc.loans.WriteOff(id, statusChange(c, req.Reason, req.Note))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, writeOff)
+}
+func (lc *LoanController) GetLoanWriteOff(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	if !authorize(c, lc.policy, services.PermLoanRead, services.LoanResource(id)) {
+		return
+	}
+
+	writeOff, err := lc.loans.GetWriteOff(id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, writeOff)
+}
+func (lc *LoanController) RecordRecovery(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	if !authorize(c, lc.policy, services.PermLoanRecover, services.LoanResource(id)) {
+		return
+	}
+
+	var req RecoveryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	recovery, err := lc.loans.RecordRecovery(id, services.Recovery{
+		Amount:     req.Amount,
+		Cost:       req.Cost,
+		AccountID:  req.AccountID,
+		Note:       req.Note,
+		RecordedBy: subject(c),
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, recovery)
+}
+func (lc *LoanController) GetLoanRecoveries(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	if !authorize(c, lc.policy, services.PermLoanRead, services.LoanResource(id)) {
+		return
+	}
+
+	recoveries, err := lc.loans.GetRecoveries(id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, recoveries)
+}
+func (lc *LoanController) GetLoanPortfolio(c *gin.Context) {
+	scope, ok := authorizeList(c, lc.policy, services.PermLoanRead)
+	if !ok {
+		return
+	}
+	query := services.PortfolioQuery{LoanType: c.Query("loan_type"), Scope: scope}
+	if query.BranchID, ok = queryID(c, "branch_id"); !ok {
+		return
+	}
+	if query.BankID, ok = queryID(c, "bank_id"); !ok {
+		return
+	}
+
+	portfolio, err := lc.loans.Portfolio(query)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, portfolio)
+}
+func (lc *LoanController) GetLoanCollections(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	if !authorize(c, lc.policy, services.PermLoanRead, services.LoanResource(id)) {
+		return
+	}
+
+	collections, err := lc.loans.GetCollections(id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, collections)
+}
+func (lc *LoanController) GetLoanClassifications(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	if !authorize(c, lc.policy, services.PermLoanRead, services.LoanResource(id)) {
+		return
+	}
+
+	classifications, err := lc.loans.GetClassificationHistory(id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, classifications)
+}
+func (lc *LoanController) SetRepaymentAccount(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	if !authorize(c, lc.policy, services.PermLoanRepay, services.LoanResource(id)) {
+		return
+	}
+
+	var req RepaymentAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	loan, err := lc.loans.SetRepaymentAccount(id, req.AccountID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, loan)
+}
+func (lc *LoanController) PrepayLoan(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	if !authorize(c, lc.policy, services.PermLoanRepay, services.LoanResource(id)) {
+		return
+	}
+
+	var req PrepayLoanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	prepayment, err := lc.loans.Prepay(id, req.AccountID, req.Amount, req.Mode)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, prepayment)
+}
+func (lc *LoanController) GetForeclosureQuote(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	if !authorize(c, lc.policy, services.PermLoanRead, services.LoanResource(id)) {
+		return
+	}
+
+	asOf, ok := queryTime(c, "as_of", false)
+	if !ok {
+		return
+	}
+	if asOf == nil {
+		now := time.Now()
+		asOf = &now
+	}
+
+	quote, err := lc.loans.QuoteForeclosure(id, *asOf)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, quote)
+}
+func (lc *LoanController) ForecloseLoan(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	if !authorize(c, lc.policy, services.PermLoanRepay, services.LoanResource(id)) {
+		return
+	}
+
+	var req ForecloseLoanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	quote, err := lc.loans.Foreclose(id, req.AccountID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, quote)
+}
+func (lc *LoanController) UpdateLoan(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	if !authorize(c, lc.policy, services.PermLoanRepay, services.LoanResource(id)) {
+		return
+	}
+
+	var req UpdateLoanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	loan, err := lc.loans.RepayLoan(id, req.AccountID, req.Amount)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":        "Loan updated successfully",
@@ -0,0 +1,178 @@
+package controllers
+
+import (
+	"banking-system/models"
+	"banking-system/money"
+	"banking-system/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LoanProductRequest is the body of both create and update. An update
+// replaces every term; leaving active out keeps the product on offer, and
+// leaving the retry settings out takes the defaults.
+type LoanProductRequest struct {
+	BankID                uint        `json:"bank_id"`
+	Code                  string      `json:"code" binding:"required"`
+	Name                  string      `json:"name" binding:"required"`
+	LoanType              string      `json:"loan_type" binding:"required"`
+	InterestRate          float64     `json:"interest_rate" binding:"gte=0,lte=100"`
+	RateType              string      `json:"rate_type"`
+	BenchmarkID           *uint       `json:"benchmark_id"`
+	Spread                float64     `json:"spread" binding:"gte=-100,lte=100"`
+	ResetMonths           int         `json:"reset_months" binding:"gte=0"`
+	MinPrincipal          money.Money `json:"min_principal" binding:"required,gt=0"`
+	MaxPrincipal          money.Money `json:"max_principal" binding:"required,gt=0"`
+	MinTenureMonths       int         `json:"min_tenure_months" binding:"required,gt=0"`
+	MaxTenureMonths       int         `json:"max_tenure_months" binding:"required,gt=0"`
+	RepaymentFrequency    string      `json:"repayment_frequency"`
+	DayCount              string      `json:"day_count"`
+	CompoundingPerYear    int         `json:"compounding_per_year" binding:"gte=0"`
+	ProcessingFeeRate     float64     `json:"processing_fee_rate" binding:"gte=0"`
+	ProcessingFee         money.Money `json:"processing_fee" binding:"gte=0"`
+	MaxActiveLoans        int         `json:"max_active_loans" binding:"gte=0"`
+	MinSavingsBalance     money.Money `json:"min_savings_balance" binding:"gte=0"`
+	MinRelationshipDays   int         `json:"min_relationship_days" binding:"gte=0"`
+	PrepaymentPenaltyRate float64     `json:"prepayment_penalty_rate" binding:"gte=0,lt=100"`
+	BounceCharge          money.Money `json:"bounce_charge" binding:"gte=0"`
+	RetryIntervalDays     int         `json:"retry_interval_days" binding:"gte=0"`
+	MaxRetries            *int        `json:"max_retries" binding:"omitempty,gte=0"`
+	LateFee               money.Money `json:"late_fee" binding:"gte=0"`
+	PenalInterestRate     float64     `json:"penal_interest_rate" binding:"gte=0,lte=100"`
+	Active                *bool       `json:"active"`
+}
+
+func (r LoanProductRequest) product() models.LoanProduct {
+	maxRetries := services.DefaultMaxRetries
+	if r.MaxRetries != nil {
+		maxRetries = *r.MaxRetries
+	}
+	return models.LoanProduct{
+		BankID:                r.BankID,
+		Code:                  r.Code,
+		Name:                  r.Name,
+		LoanType:              r.LoanType,
+		InterestRate:          r.InterestRate,
+		RateType:              r.RateType,
+		BenchmarkID:           r.BenchmarkID,
+		Spread:                r.Spread,
+		ResetMonths:           r.ResetMonths,
+		MinPrincipal:          r.MinPrincipal,
+		MaxPrincipal:          r.MaxPrincipal,
+		MinTenureMonths:       r.MinTenureMonths,
+		MaxTenureMonths:       r.MaxTenureMonths,
+		RepaymentFrequency:    r.RepaymentFrequency,
+		DayCount:              r.DayCount,
+		CompoundingPerYear:    r.CompoundingPerYear,
+		ProcessingFeeRate:     r.ProcessingFeeRate,
+		ProcessingFee:         r.ProcessingFee,
+		MaxActiveLoans:        r.MaxActiveLoans,
+		MinSavingsBalance:     r.MinSavingsBalance,
+		MinRelationshipDays:   r.MinRelationshipDays,
+		PrepaymentPenaltyRate: r.PrepaymentPenaltyRate,
+		BounceCharge:          r.BounceCharge,
+		RetryIntervalDays:     r.RetryIntervalDays,
+		MaxRetries:            maxRetries,
+		LateFee:               r.LateFee,
+		PenalInterestRate:     r.PenalInterestRate,
+		Active:                r.Active == nil || *r.Active,
+	}
+}
+
+type LoanProductController struct {
+	products LoanProductService
+	policy   AccessPolicy
+}
+
+func NewLoanProductController(products LoanProductService, policy AccessPolicy) *LoanProductController {
+	return &LoanProductController{products: products, policy: policy}
+}
+
+func (pc *LoanProductController) CreateProduct(c *gin.Context) {
+	var req LoanProductRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !authorize(c, pc.policy, services.PermProductManage, services.BankResource(req.BankID)) {
+		return
+	}
+
+	product := req.product()
+	if err := pc.products.CreateProduct(&product); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, product)
+}
+func (pc *LoanProductController) GetProduct(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	if !authorize(c, pc.policy, services.PermProductRead, services.ProductResource(id)) {
+		return
+	}
+
+	product, err := pc.products.GetProduct(id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, product)
+}
+func (pc *LoanProductController) ListProducts(c *gin.Context) {
+	scope, ok := authorizeList(c, pc.policy, services.PermProductRead)
+	if !ok {
+		return
+	}
+	list, ok := listQuery(c)
+	if !ok {
+		return
+	}
+	query := services.LoanProductQuery{LoanType: c.Query("loan_type"), Scope: scope, ListQuery: list}
+	if query.BankID, ok = queryID(c, "bank_id"); !ok {
+		return
+	}
+	if query.Active, ok = queryOptionalBool(c, "active"); !ok {
+		return
+	}
+
+	products, err := pc.products.ListProducts(query)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, products)
+}
+func (pc *LoanProductController) UpdateProduct(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	if !authorize(c, pc.policy, services.PermProductManage, services.ProductResource(id)) {
+		return
+	}
+
+	var req LoanProductRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	product, err := pc.products.UpdateProduct(id, req.product())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, product)
+}
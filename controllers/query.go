@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"banking-system/money"
+	"banking-system/services"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The query helpers below write a 400 response and return false when a
+// parameter is present but malformed. Absent parameters are not an error.
+
+func queryInt(c *gin.Context, name string) (int, bool) {
+	raw := c.Query(name)
+	if raw == "" {
+		return 0, true
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name})
+		return 0, false
+	}
+	return n, true
+}
+
+func queryBool(c *gin.Context, name string) (bool, bool) {
+	raw := c.Query(name)
+	if raw == "" {
+		return false, true
+	}
+	b, err := strconv.ParseBool(raw)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name})
+		return false, false
+	}
+	return b, true
+}
+
+func queryMoney(c *gin.Context, name string) (*money.Money, bool) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, true
+	}
+	amount, err := money.Parse(raw)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name})
+		return nil, false
+	}
+	return &amount, true
+}
+
+// queryTime accepts RFC 3339 timestamps and plain dates. A plain date means
+// the start of that day in UTC, or the start of the next day when endOfDay is
+// set, so that an exclusive upper bound still covers the whole day.
+func queryTime(c *gin.Context, name string, endOfDay bool) (*time.Time, bool) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, true
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return &t, true
+	}
+	t, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name + ": use YYYY-MM-DD or RFC 3339"})
+		return nil, false
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, true
+}
+
+// queryList reads a parameter given as repeated values, comma-separated
+// values or both.
+func queryList(c *gin.Context, name string) []string {
+	var values []string
+	for _, raw := range c.QueryArray(name) {
+		for _, v := range strings.Split(raw, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
+
+func queryID(c *gin.Context, name string) (*uint, bool) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, true
+	}
+	id, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name})
+		return nil, false
+	}
+	v := uint(id)
+	return &v, true
+}
+
+// queryOptionalBool is queryBool for filters, where leaving the parameter
+// out means no filter rather than false.
+func queryOptionalBool(c *gin.Context, name string) (*bool, bool) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, true
+	}
+	v, err := strconv.ParseBool(raw)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name})
+		return nil, false
+	}
+	return &v, true
+}
+
+// listQuery reads the sort, cursor, limit and include_total parameters
+// shared by every collection endpoint.
+func listQuery(c *gin.Context) (services.ListQuery, bool) {
+	query := services.ListQuery{
+		Sort:   c.Query("sort"),
+		Cursor: c.Query("cursor"),
+	}
+	var ok bool
+	if query.Limit, ok = queryInt(c, "limit"); !ok {
+		return query, false
+	}
+	if query.IncludeTotal, ok = queryBool(c, "include_total"); !ok {
+		return query, false
+	}
+	return query, true
+}
@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"banking-system/money"
+	"banking-system/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TransferRequest struct {
+	FromAccountID uint        `json:"from_account_id" binding:"required"`
+	ToAccountID   uint        `json:"to_account_id" binding:"required"`
+	Amount        money.Money `json:"amount" binding:"required,gt=0"`
+}
+
+type TransferController struct {
+	transfers TransferService
+	policy    AccessPolicy
+}
+
+func NewTransferController(transfers TransferService, policy AccessPolicy) *TransferController {
+	return &TransferController{transfers: transfers, policy: policy}
+}
+
+func (tc *TransferController) CreateTransfer(c *gin.Context) {
+	var req TransferRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !authorize(c, tc.policy, services.PermTransferCreate, services.AccountResource(req.FromAccountID)) {
+		return
+	}
+
+	transfer, err := tc.transfers.Transfer(req.FromAccountID, req.ToAccountID, req.Amount)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, transfer)
+}
+func (tc *TransferController) GetTransfer(c *gin.Context) {
+	if !authorize(c, tc.policy, services.PermTransferRead, services.TransferResource(c.Param("id"))) {
+		return
+	}
+
+	transactions, err := tc.transfers.GetTransfer(c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"transfer_id":  c.Param("id"),
+		"transactions": transactions,
+	})
+}
@@ -0,0 +1,129 @@
+// Package interest computes the interest on an amount between two dates.
+//
+// Periods are measured under one of the usual day-count conventions and the
+// result is an exact rational in major units, so callers can sum several
+// periods and round once with money.FromRat. Dates are read as calendar
+// dates; the time of day is ignored.
+//
+// Rates are yearly percentages, as elsewhere in the system.
+package interest
+
+import (
+	"banking-system/money"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// DayCount is a day-count convention: how the days of a period are counted
+// and how many make a year. The zero DayCount is Actual/365.
+type DayCount string
+
+const (
+	// Actual365 counts calendar days over a year of 365 days (Actual/365
+	// Fixed), leap years included.
+	Actual365 DayCount = "ACT/365"
+	// Actual360 counts calendar days over a year of 360 days.
+	Actual360 DayCount = "ACT/360"
+	// Thirty360 counts every month as 30 days over a year of 360 days, on the
+	// bond basis: a period starting on the 31st starts on the 30th, and one
+	// ending on the 31st ends on the 30th when it starts on the 30th or 31st.
+	Thirty360 DayCount = "30/360"
+)
+
+var DayCounts = []DayCount{Actual365, Actual360, Thirty360}
+
+var ErrInvalidDayCount = errors.New("invalid day count")
+
+var ErrInvalidPeriods = errors.New("invalid compounding periods")
+
+// ParseDayCount reads a convention such as "act/360". Empty input is
+// Actual365.
+func ParseDayCount(s string) (DayCount, error) {
+	dc := DayCount(strings.ToUpper(strings.TrimSpace(s)))
+	switch dc {
+	case "":
+		return Actual365, nil
+	case Actual365, Actual360, Thirty360:
+		return dc, nil
+	}
+	return "", fmt.Errorf("%w: %q (expected %s, %s or %s)", ErrInvalidDayCount, s, Actual365, Actual360, Thirty360)
+}
+
+// Days is the number of days from from to to under the convention. It is
+// negative when to is before from.
+func (dc DayCount) Days(from, to time.Time) int {
+	if dc == Thirty360 {
+		y1, m1, d1 := from.Date()
+		y2, m2, d2 := to.Date()
+		if d1 == 31 {
+			d1 = 30
+		}
+		if d2 == 31 && d1 == 30 {
+			d2 = 30
+		}
+		return 360*(y2-y1) + 30*int(m2-m1) + d2 - d1
+	}
+	return int(date(to).Sub(date(from)).Hours() / 24)
+}
+
+// DaysPerYear is the length of the convention's year.
+func (dc DayCount) DaysPerYear() int {
+	if dc == Actual360 || dc == Thirty360 {
+		return 360
+	}
+	return 365
+}
+
+// YearFraction is the period from from to to as a fraction of a year.
+func (dc DayCount) YearFraction(from, to time.Time) *big.Rat {
+	return big.NewRat(int64(dc.Days(from, to)), int64(dc.DaysPerYear()))
+}
+
+// Simple is the simple interest on principal at percent a year from from to
+// to. A period that ends before it starts earns nothing.
+func Simple(principal money.Money, percent float64, dc DayCount, from, to time.Time) *big.Rat {
+	t := dc.YearFraction(from, to)
+	if t.Sign() <= 0 {
+		return new(big.Rat)
+	}
+	interest := principal.Rat()
+	interest.Mul(interest, money.RateRat(percent))
+	return interest.Mul(interest, t)
+}
+
+// Compound is the interest on principal at percent a year from from to to,
+// compounded periodsPerYear times a year (12 for monthly, 365 for daily
+// under Actual365). Whole periods compound; a part period left at the end
+// earns simple interest on the compounded amount. A period that ends before
+// it starts earns nothing. periodsPerYear must be at least 1.
+func Compound(principal money.Money, percent float64, dc DayCount, periodsPerYear int, from, to time.Time) (*big.Rat, error) {
+	if periodsPerYear < 1 {
+		return nil, fmt.Errorf("%w: %d a year", ErrInvalidPeriods, periodsPerYear)
+	}
+	periods := dc.YearFraction(from, to)
+	if periods.Sign() <= 0 {
+		return new(big.Rat), nil
+	}
+	periods.Mul(periods, big.NewRat(int64(periodsPerYear), 1))
+	whole := new(big.Int).Quo(periods.Num(), periods.Denom())
+	stub := new(big.Rat).Sub(periods, new(big.Rat).SetInt(whole))
+
+	r := money.RateRat(percent)
+	r.Quo(r, big.NewRat(int64(periodsPerYear), 1))
+	growth := new(big.Rat).Add(big.NewRat(1, 1), r)
+	num := new(big.Int).Exp(growth.Num(), whole, nil)
+	den := new(big.Int).Exp(growth.Denom(), whole, nil)
+	factor := new(big.Rat).SetFrac(num, den)
+	factor.Mul(factor, stub.Add(big.NewRat(1, 1), stub.Mul(stub, r)))
+
+	interest := principal.Rat()
+	return interest.Mul(interest, factor.Sub(factor, big.NewRat(1, 1))), nil
+}
+
+func date(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
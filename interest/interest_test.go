@@ -0,0 +1,152 @@
+package interest
+
+import (
+	"banking-system/money"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func day(s string) time.Time {
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestParseDayCount(t *testing.T) {
+	tests := []struct {
+		in   string
+		want DayCount
+		err  error
+	}{
+		{"", Actual365, nil},
+		{"act/360", Actual360, nil},
+		{" 30/360 ", Thirty360, nil},
+		{"ACT/ACT", "", ErrInvalidDayCount},
+	}
+	for _, tt := range tests {
+		got, err := ParseDayCount(tt.in)
+		if got != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("ParseDayCount(%q) = %q, %v, want %q, %v", tt.in, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+// The 30/360 cases follow the bond basis examples of the ISDA 2006
+// definitions, section 4.16(f).
+func TestDayCounts(t *testing.T) {
+	tests := []struct {
+		dc       DayCount
+		from, to string
+		days     int
+		fraction *big.Rat
+	}{
+		{Actual365, "2007-01-15", "2007-07-15", 181, big.NewRat(181, 365)},
+		{Actual365, "2024-01-01", "2025-01-01", 366, big.NewRat(366, 365)},
+		{Actual360, "2007-01-15", "2007-07-15", 181, big.NewRat(181, 360)},
+		{Actual360, "2007-09-30", "2008-03-31", 183, big.NewRat(183, 360)},
+		{Thirty360, "2007-01-15", "2007-07-15", 180, big.NewRat(1, 2)},
+		{Thirty360, "2007-01-31", "2007-03-31", 60, big.NewRat(1, 6)},
+		{Thirty360, "2007-02-28", "2007-08-31", 183, big.NewRat(183, 360)},
+		{Thirty360, "2007-09-30", "2008-03-31", 180, big.NewRat(1, 2)},
+		{Thirty360, "2008-02-29", "2008-08-31", 182, big.NewRat(182, 360)},
+		{Actual365, "2007-07-15", "2007-01-15", -181, big.NewRat(-181, 365)},
+	}
+	for _, tt := range tests {
+		from, to := day(tt.from), day(tt.to)
+		if got := tt.dc.Days(from, to); got != tt.days {
+			t.Errorf("%s days from %s to %s = %d, want %d", tt.dc, tt.from, tt.to, got, tt.days)
+		}
+		if got := tt.dc.YearFraction(from, to); got.Cmp(tt.fraction) != 0 {
+			t.Errorf("%s year fraction from %s to %s = %s, want %s", tt.dc, tt.from, tt.to, got, tt.fraction)
+		}
+	}
+}
+
+func TestSimple(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal string
+		percent   float64
+		dc        DayCount
+		from, to  string
+		want      string
+	}{
+		{"ACT/365 half year", "10000", 5, Actual365, "2007-01-15", "2007-07-15", "247.95"},
+		{"ACT/360 half year", "10000", 5, Actual360, "2007-01-15", "2007-07-15", "251.39"},
+		{"30/360 half year", "10000", 5, Thirty360, "2007-01-15", "2007-07-15", "250.00"},
+		{"one day", "36500", 10, Actual365, "2025-03-01", "2025-03-02", "10.00"},
+		{"leap year", "1000", 10, Actual365, "2024-01-01", "2025-01-01", "100.27"},
+		{"backwards", "1000", 10, Actual365, "2025-01-01", "2024-01-01", "0.00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := money.FromRat(Simple(money.MustParse(tt.principal), tt.percent, tt.dc, day(tt.from), day(tt.to)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != money.MustParse(tt.want) {
+				t.Errorf("Simple = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// The compound figures are the textbook ones: 1,000 at 5% a year for ten
+// years grows to 1,628.89, 10,000 at 6% compounded monthly earns an
+// effective 6.1678%, and 1,000 at 5% compounded daily earns 51.27 in a year.
+func TestCompound(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal string
+		percent   float64
+		dc        DayCount
+		periods   int
+		from, to  string
+		want      string
+	}{
+		{"annual over ten years", "1000", 5, Thirty360, 1, "2015-01-01", "2025-01-01", "628.89"},
+		{"monthly over a year", "10000", 6, Thirty360, 12, "2024-01-01", "2025-01-01", "616.78"},
+		{"daily over a year", "1000", 5, Actual365, 365, "2025-01-01", "2026-01-01", "51.27"},
+		{"part period earns simple interest", "1000", 8, Thirty360, 4, "2025-01-01", "2025-05-16", "30.20"},
+		{"within one period", "10000", 6, Thirty360, 12, "2025-01-01", "2025-01-16", "25.00"},
+		{"backwards", "1000", 5, Actual365, 12, "2026-01-01", "2025-01-01", "0.00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exact, err := Compound(money.MustParse(tt.principal), tt.percent, tt.dc, tt.periods, day(tt.from), day(tt.to))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := money.FromRat(exact)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != money.MustParse(tt.want) {
+				t.Errorf("Compound = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompoundOncePerYearMatchesSimpleForAYear(t *testing.T) {
+	from, to := day("2025-01-01"), day("2026-01-01")
+	compound, err := Compound(money.MustParse("2500"), 7.5, Actual365, 1, from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if simple := Simple(money.MustParse("2500"), 7.5, Actual365, from, to); compound.Cmp(simple) != 0 {
+		t.Errorf("Compound = %s, Simple = %s, want them equal", compound.FloatString(4), simple.FloatString(4))
+	}
+}
+
+func TestCompoundRejectsNoPeriods(t *testing.T) {
+	for _, periods := range []int{0, -12} {
+		if _, err := Compound(money.MustParse("1000"), 5, Actual365, periods, day("2025-01-01"), day("2026-01-01")); !errors.Is(err, ErrInvalidPeriods) {
+			t.Errorf("Compound with %d periods: err = %v, want ErrInvalidPeriods", periods, err)
+		}
+	}
+}
@@ -1,86 +1,274 @@
 package main
 
 import (
+	"banking-system/auth"
 	"banking-system/config"
-	"banking-system/models"
+	"banking-system/migrations"
+	"banking-system/repository"
 	"banking-system/routes"
+	"banking-system/services"
+	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
 	dbConfig := config.Config{
-		Host:     os.Getenv("DB_HOST"),
-		Port:     os.Getenv("DB_PORT"),
-		User:     os.Getenv("DB_USER"),
-		Password: os.Getenv("DB_PASSWORD"),
-		DBName:   os.Getenv("DB_NAME"),
-		SSLMode:  os.Getenv("DB_SSLMODE"),
+		Host:     getEnv("DB_HOST", "localhost"),
+		Port:     getEnv("DB_PORT", "5432"),
+		User:     getEnv("DB_USER", "postgres"),
+		Password: getEnv("DB_PASSWORD", "postgres"),
+		DBName:   getEnv("DB_NAME", "banking_system"),
+		SSLMode:  getEnv("DB_SSLMODE", "disable"),
 	}
-	if dbConfig.Host == "" {
-		dbConfig.Host = "localhost"
-	}
-	if dbConfig.Port == "" {
-		dbConfig.Port = "5432"
-	}
-	if dbConfig.User == "" {
-		dbConfig.User = "postgres"
+	if err := config.InitDB(dbConfig); err != nil {
+		log.Fatal("Failed to initialize database: ", err)
 	}
-	if dbConfig.Password == "" {
-		dbConfig.Password = "postgres"
+	db := config.GetDB()
+
+	migrator, err := migrations.New(db)
+	if err != nil {
+		log.Fatal("Failed to load migrations: ", err)
 	}
-	if dbConfig.DBName == "" {
-		dbConfig.DBName = "banking_system"
+
+	store := repository.NewGormStore(db)
+
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			runMigrate(migrator, os.Args[2:])
+			return
+		case "apikey":
+			runAPIKey(services.NewAuthService(store, nil), os.Args[2:])
+			return
+		case "interest":
+			runInterest(services.NewInterestService(store), os.Args[2:])
+			return
+		case "loans":
+			runLoans(services.NewCollectionService(store), os.Args[2:])
+			return
+		default:
+			log.Fatalf("Unknown command %q (expected: migrate, apikey, interest, loans)", os.Args[1])
+		}
 	}
-	if dbConfig.SSLMode == "" {
-		dbConfig.SSLMode = "disable"
+
+	secret := os.Getenv("JWT_SECRET")
+	if len(secret) < 32 {
+		log.Fatal("JWT_SECRET must be set to at least 32 characters")
 	}
-	if err := config.InitDB(dbConfig); err != nil {
-		log.Fatal("Failed to initialize database: ", err)
+	tokenTTL, err := time.ParseDuration(getEnv("JWT_TTL", "1h"))
+	if err != nil {
+		log.Fatal("Invalid JWT_TTL: ", err)
 	}
-	db := config.GetDB()
-	db.Migrator().DropTable(
-		&models.LoanPayment{},
-		&models.Loan{},
-		&models.Transaction{},
-		&models.CustomerAccount{},
-		&models.SavingsAccount{},
-		&models.Customer{},
-		&models.Branch{},
-		&models.Bank{},
-	)
 
-	if err := db.AutoMigrate(
-		&models.Bank{},
-		&models.Branch{},
-		&models.Customer{},
-		&models.SavingsAccount{},
-		&models.CustomerAccount{},
-		&models.Transaction{},
-		&models.Loan{},
-		&models.LoanPayment{},
-	); err != nil {
+	applied, err := migrator.Up()
+	if err != nil {
 		log.Fatal("Failed to run migrations: ", err)
 	}
+	log.Printf("Database migrations completed successfully (%d applied)\n", len(applied))
 
-	log.Println("Database migrations completed successfully")
 	router := gin.Default()
-	routes.SetupRoutes(router)
+	routes.SetupRoutes(router, store, auth.NewTokens([]byte(secret), tokenTTL))
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{
 			"status":  "OK",
 			"message": "Banking System API is running",
 		})
 	})
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
+	port := getEnv("PORT", "8080")
 
 	log.Printf("Starting Banking System API on port %s\n", port)
 	if err := router.Run(":" + port); err != nil {
 		log.Fatal("Failed to start server: ", err)
 	}
 }
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// runMigrate implements `migrate up`, `migrate down [steps]` and
+// `migrate status`.
+func runMigrate(migrator *migrations.Migrator, args []string) {
+	if len(args) == 0 {
+		log.Fatal("Usage: migrate up | down [steps] | status")
+	}
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up()
+		if err != nil {
+			log.Fatal("Migration failed: ", err)
+		}
+		for _, m := range applied {
+			fmt.Printf("applied  %04d_%s\n", m.Version, m.Name)
+		}
+		if len(applied) == 0 {
+			fmt.Println("no pending migrations")
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				log.Fatalf("Invalid step count %q", args[1])
+			}
+			steps = n
+		}
+		reverted, err := migrator.Down(steps)
+		if err != nil {
+			log.Fatal("Rollback failed: ", err)
+		}
+		for _, m := range reverted {
+			fmt.Printf("reverted %04d_%s\n", m.Version, m.Name)
+		}
+		if len(reverted) == 0 {
+			fmt.Println("nothing to revert")
+		}
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			log.Fatal("Failed to read migration status: ", err)
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.AppliedAt != nil {
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-30s %s\n", s.Version, s.Name, state)
+		}
+	default:
+		log.Fatalf("Unknown migrate command %q (expected: up, down, status)", args[0])
+	}
+}
+
+// runAPIKey implements `apikey issue <name>` and `apikey revoke <id>`. It is
+// how the first service client key is created, so issued keys are system
+// admins; narrower keys are issued through the API.
+func runAPIKey(authService *services.AuthService, args []string) {
+	if len(args) != 2 {
+		log.Fatal("Usage: apikey issue <name> | revoke <id>")
+	}
+	switch args[0] {
+	case "issue":
+		key, plaintext, err := authService.IssueAPIKey(args[1], "cli", services.Grant{Role: auth.RoleSystemAdmin})
+		if err != nil {
+			log.Fatal("Failed to issue API key: ", err)
+		}
+		fmt.Printf("issued api key %d (%s)\n%s\n", key.ID, key.Name, plaintext)
+	case "revoke":
+		id, err := strconv.ParseUint(args[1], 10, 64)
+		if err != nil {
+			log.Fatalf("Invalid API key id %q", args[1])
+		}
+		if _, err := authService.RevokeAPIKey(uint(id)); err != nil {
+			log.Fatal("Failed to revoke API key: ", err)
+		}
+		fmt.Printf("revoked api key %d\n", id)
+	default:
+		log.Fatalf("Unknown apikey command %q (expected: issue, revoke)", args[0])
+	}
+}
+
+// runInterest implements `interest accrue [date]` and `interest post [date]`
+// for a scheduler to call once a day. date is YYYY-MM-DD and defaults to
+// yesterday; rerunning a date does nothing new.
+func runInterest(interestService *services.InterestService, args []string) {
+	if len(args) == 0 || len(args) > 2 {
+		log.Fatal("Usage: interest accrue [date] | post [date]")
+	}
+	date := time.Now().AddDate(0, 0, -1)
+	if len(args) == 2 {
+		d, err := time.Parse(time.DateOnly, args[1])
+		if err != nil {
+			log.Fatalf("Invalid date %q (expected YYYY-MM-DD)", args[1])
+		}
+		date = d
+	}
+	switch args[0] {
+	case "accrue":
+		run, err := interestService.Accrue(date)
+		if err != nil {
+			log.Fatal("Interest accrual failed: ", err)
+		}
+		fmt.Printf("accrued %s for %s: %d accounts, %d already accrued\n",
+			run.Amount, run.BusinessDate.Format(time.DateOnly), run.Accrued, run.Skipped)
+		if run.Failed > 0 {
+			log.Fatalf("Interest accrual failed for %d accounts: %v", run.Failed, run.FailedAccountIDs)
+		}
+	case "post":
+		run, err := interestService.Post(date)
+		if err != nil {
+			log.Fatal("Interest posting failed: ", err)
+		}
+		fmt.Printf("posted %s for %s to %d accounts\n", run.Amount, run.BusinessDate.Format(time.DateOnly), run.Posted)
+		if run.Failed > 0 {
+			log.Fatalf("Interest posting failed for %d accounts: %v", run.Failed, run.FailedAccountIDs)
+		}
+	default:
+		log.Fatalf("Unknown interest command %q (expected: accrue, post)", args[0])
+	}
+}
+
+// runLoans implements `loans collect [date]`, which auto-debits the
+// installments due on date, `loans classify [date]`, which ages overdue loans
+// into delinquency buckets, and `loans reset-rates [date]`, which reprices
+// floating-rate loans due a reset, for a scheduler to call once a day. date
+// is YYYY-MM-DD and defaults to yesterday for classify and today otherwise;
+// rerunning a date does nothing new.
+func runLoans(collectionService *services.CollectionService, args []string) {
+	if len(args) == 0 || len(args) > 2 {
+		log.Fatal("Usage: loans collect [date] | classify [date] | reset-rates [date]")
+	}
+	date := time.Now()
+	if args[0] == "classify" {
+		date = date.AddDate(0, 0, -1)
+	}
+	if len(args) == 2 {
+		d, err := time.Parse(time.DateOnly, args[1])
+		if err != nil {
+			log.Fatalf("Invalid date %q (expected YYYY-MM-DD)", args[1])
+		}
+		date = d
+	}
+	switch args[0] {
+	case "collect":
+		run, err := collectionService.Collect(date)
+		if err != nil {
+			log.Fatal("Loan collection failed: ", err)
+		}
+		fmt.Printf("collected %s for %s: %d installments, %d bounced (%s charged)\n",
+			run.Amount, run.BusinessDate.Format(time.DateOnly), run.Collected, run.Bounced, run.Charges)
+		if run.Failed > 0 {
+			log.Fatalf("Loan collection failed for %d loans: %v", run.Failed, run.FailedLoanIDs)
+		}
+	case "classify":
+		run, err := collectionService.Classify(date)
+		if err != nil {
+			log.Fatal("Loan classification failed: ", err)
+		}
+		fmt.Printf("classified %d loans for %s (%d moved bucket, %d already classified): %s late fees, %s penal interest\n",
+			run.Classified, run.BusinessDate.Format(time.DateOnly), run.Reclassified, run.Skipped, run.LateFees, run.PenalInterest)
+		if run.Failed > 0 {
+			log.Fatalf("Loan classification failed for %d loans: %v", run.Failed, run.FailedLoanIDs)
+		}
+	case "reset-rates":
+		run, err := collectionService.ResetRates(date)
+		if err != nil {
+			log.Fatal("Rate reset failed: ", err)
+		}
+		fmt.Printf("reset %d loans for %s (%d repriced): interest changed by %s\n",
+			run.Reset, run.BusinessDate.Format(time.DateOnly), run.Repriced, run.InterestChange)
+		if run.Failed > 0 {
+			log.Fatalf("Rate reset failed for %d loans: %v", run.Failed, run.FailedLoanIDs)
+		}
+	default:
+		log.Fatalf("Unknown loans command %q (expected: collect, classify, reset-rates)", args[0])
+	}
+}
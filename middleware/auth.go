@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"banking-system/auth"
+	"banking-system/services"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const APIKeyHeader = "X-API-Key"
+
+type Authenticator interface {
+	AuthenticateAPIKey(plaintext string) (*auth.Principal, error)
+	AuthenticateToken(token string) (*auth.Principal, error)
+}
+
+// Authenticate rejects requests that carry neither a valid API key in the
+// X-API-Key header nor a valid bearer JWT, and stores the principal in the
+// context for the handlers that follow.
+func Authenticate(authenticator Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var principal *auth.Principal
+		var err error
+
+		if key := c.GetHeader(APIKeyHeader); key != "" {
+			principal, err = authenticator.AuthenticateAPIKey(key)
+		} else if token, ok := bearerToken(c.GetHeader("Authorization")); ok {
+			principal, err = authenticator.AuthenticateToken(token)
+		} else {
+			err = services.ErrUnauthenticated
+		}
+
+		if err != nil {
+			status := http.StatusUnauthorized
+			if !errors.Is(err, services.ErrUnauthenticated) {
+				status = http.StatusInternalServerError
+			}
+			c.Header("WWW-Authenticate", `Bearer realm="banking-system"`)
+			c.AbortWithStatusJSON(status, gin.H{"error": err.Error()})
+			return
+		}
+
+		auth.SetPrincipal(c, principal)
+		c.Next()
+	}
+}
+
+// RequireAPIKey only lets service clients through. It must run after
+// Authenticate.
+func RequireAPIKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := auth.FromContext(c)
+		if !ok || !principal.IsAPIKey() {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "this endpoint requires an API key"})
+			return
+		}
+		c.Next()
+	}
+}
+
+func bearerToken(header string) (string, bool) {
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
@@ -0,0 +1,140 @@
+package middleware
+
+import (
+	"banking-system/auth"
+	"banking-system/models"
+	"banking-system/repository"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const IdempotencyHeader = "Idempotency-Key"
+
+const maxIdempotencyKeyLength = 255
+
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency makes a route safe to retry. When a request carries an
+// Idempotency-Key header the first response is stored; a retry with the same
+// key and body gets that response replayed, and a retry with a different
+// body is rejected. Keys are scoped to the principal, so two clients using
+// the same key do not collide. Requests without the header run normally.
+func Idempotency(records repository.IdempotencyRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key is too long"})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		// The principal is part of the hash so that one client cannot replay
+		// another client's response by reusing its key.
+		var subject string
+		if principal, ok := auth.FromContext(c); ok {
+			subject = principal.Subject
+		}
+		hash := sha256.New()
+		hash.Write([]byte(subject + "\n"))
+		hash.Write([]byte(c.Request.Method + "\n" + c.Request.URL.Path + "\n"))
+		hash.Write(body)
+		requestHash := hex.EncodeToString(hash.Sum(nil))
+		key = scopedKey(subject, key)
+
+		record := models.IdempotencyRecord{
+			Key:         key,
+			Method:      c.Request.Method,
+			Path:        c.Request.URL.Path,
+			RequestHash: requestHash,
+		}
+		reserved, err := records.Reserve(&record)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if !reserved {
+			existing, err := records.Get(key)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			switch {
+			case existing.RequestHash != requestHash:
+				c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key was already used with a different request"})
+			case existing.StatusCode == 0:
+				c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "A request with this Idempotency-Key is still in progress"})
+			default:
+				c.Header("Idempotent-Replayed", "true")
+				c.Data(existing.StatusCode, "application/json; charset=utf-8", existing.ResponseBody)
+				c.Abort()
+			}
+			return
+		}
+
+		// A handler that panics must not leave the key in flight, or every
+		// retry would be turned away as still in progress.
+		defer func() {
+			if r := recover(); r != nil {
+				release(records, key)
+				panic(r)
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			// Server errors are not replayed so the client can retry.
+			release(records, key)
+			return
+		}
+		if err := records.Complete(key, status, recorder.body.Bytes()); err != nil {
+			log.Printf("idempotency: storing the response for key %s: %v", key, err)
+		}
+	}
+}
+
+// scopedKey is the key a request's record is stored under: the client's key
+// hashed together with the principal that sent it.
+func scopedKey(subject, key string) string {
+	sum := sha256.Sum256([]byte(subject + "\n" + key))
+	return hex.EncodeToString(sum[:])
+}
+
+func release(records repository.IdempotencyRepository, key string) {
+	if err := records.Release(key); err != nil {
+		log.Printf("idempotency: releasing key %s: %v", key, err)
+	}
+}
@@ -0,0 +1,232 @@
+package migrations_test
+
+import (
+	"banking-system/migrations"
+	"banking-system/money"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// The types below are the models as they stood when main.go still built the
+// schema with AutoMigrate. They carry the same names so that AutoMigrate
+// produces the same tables, indexes and constraints it did then.
+
+type Bank struct {
+	ID        uint     `gorm:"primaryKey"`
+	Name      string   `gorm:"not null"`
+	Branches  []Branch `gorm:"foreignKey:BankID;constraint:OnDelete:CASCADE"`
+	CreatedAt time.Time
+}
+
+type Branch struct {
+	ID        uint   `gorm:"primaryKey"`
+	BankID    uint   `gorm:"not null;index"`
+	Bank      Bank   `gorm:"foreignKey:BankID;constraint:OnDelete:CASCADE"`
+	Name      string `gorm:"not null"`
+	Address   string
+	Customers []Customer `gorm:"foreignKey:BranchID;constraint:OnDelete:CASCADE"`
+	CreatedAt time.Time
+}
+
+type Customer struct {
+	ID               uint   `gorm:"primaryKey"`
+	BranchID         uint   `gorm:"not null;index"`
+	Branch           Branch `gorm:"foreignKey:BranchID;constraint:OnDelete:CASCADE"`
+	Name             string `gorm:"not null"`
+	Email            string `gorm:"uniqueIndex"`
+	Phone            string
+	CustomerAccounts []CustomerAccount `gorm:"foreignKey:CustomerID;constraint:OnDelete:CASCADE"`
+	Loans            []Loan            `gorm:"foreignKey:CustomerID;constraint:OnDelete:CASCADE"`
+	CreatedAt        time.Time
+}
+
+type SavingsAccount struct {
+	ID               uint              `gorm:"primaryKey"`
+	Balance          money.Money       `gorm:"not null;default:0"`
+	CustomerAccounts []CustomerAccount `gorm:"foreignKey:AccountID;constraint:OnDelete:CASCADE"`
+	Transactions     []Transaction     `gorm:"foreignKey:AccountID;constraint:OnDelete:CASCADE"`
+	CreatedAt        time.Time
+}
+
+type CustomerAccount struct {
+	ID         uint           `gorm:"primaryKey"`
+	CustomerID uint           `gorm:"not null;index"`
+	Customer   Customer       `gorm:"foreignKey:CustomerID;constraint:OnDelete:CASCADE"`
+	AccountID  uint           `gorm:"not null;index"`
+	Account    SavingsAccount `gorm:"foreignKey:AccountID;constraint:OnDelete:CASCADE"`
+	HolderRole string         `gorm:"not null;default:'primary_holder'"`
+	CreatedAt  time.Time
+}
+
+type Transaction struct {
+	ID             uint           `gorm:"primaryKey"`
+	AccountID      uint           `gorm:"not null;index"`
+	Account        SavingsAccount `gorm:"foreignKey:AccountID;constraint:OnDelete:CASCADE"`
+	Type           string         `gorm:"not null"`
+	Amount         money.Money    `gorm:"not null"`
+	TransferID     string         `gorm:"index"`
+	JournalEntryID *uint          `gorm:"index"`
+	CreatedAt      time.Time
+}
+
+type Loan struct {
+	ID                 uint        `gorm:"primaryKey"`
+	CustomerID         uint        `gorm:"not null;index"`
+	Customer           Customer    `gorm:"foreignKey:CustomerID;constraint:OnDelete:CASCADE"`
+	LoanType           string      `gorm:"not null"`
+	PrincipalAmount    money.Money `gorm:"not null"`
+	InterestRate       float64     `gorm:"not null;default:12"`
+	TotalPayableAmount money.Money `gorm:"not null"`
+	PendingAmount      money.Money `gorm:"not null"`
+	StartDate          time.Time   `gorm:"not null"`
+	EndDate            *time.Time
+	Status             string        `gorm:"not null;default:'ACTIVE'"`
+	LoanPayments       []LoanPayment `gorm:"foreignKey:LoanID;constraint:OnDelete:CASCADE"`
+	CreatedAt          time.Time
+}
+
+type LoanPayment struct {
+	ID             uint        `gorm:"primaryKey"`
+	LoanID         uint        `gorm:"not null;index"`
+	Loan           Loan        `gorm:"foreignKey:LoanID;constraint:OnDelete:CASCADE"`
+	Amount         money.Money `gorm:"not null"`
+	PaymentDate    time.Time   `gorm:"not null"`
+	JournalEntryID *uint       `gorm:"index"`
+	CreatedAt      time.Time
+}
+
+type LedgerAccount struct {
+	ID               uint   `gorm:"primaryKey"`
+	Code             string `gorm:"not null;uniqueIndex"`
+	Name             string `gorm:"not null"`
+	Type             string `gorm:"not null"`
+	SavingsAccountID *uint  `gorm:"index"`
+	LoanID           *uint  `gorm:"index"`
+	CreatedAt        time.Time
+}
+
+type JournalEntry struct {
+	ID          uint   `gorm:"primaryKey"`
+	Type        string `gorm:"not null;index"`
+	Description string
+	Postings    []Posting `gorm:"foreignKey:JournalEntryID;constraint:OnDelete:CASCADE"`
+	CreatedAt   time.Time
+}
+
+type Posting struct {
+	ID              uint          `gorm:"primaryKey"`
+	JournalEntryID  uint          `gorm:"not null;index"`
+	LedgerAccountID uint          `gorm:"not null;index"`
+	LedgerAccount   LedgerAccount `gorm:"foreignKey:LedgerAccountID"`
+	Amount          money.Money   `gorm:"not null"`
+	CreatedAt       time.Time
+}
+
+type IdempotencyRecord struct {
+	Key          string `gorm:"primaryKey;size:255"`
+	Method       string `gorm:"not null"`
+	Path         string `gorm:"not null"`
+	RequestHash  string `gorm:"not null"`
+	StatusCode   int    `gorm:"not null;default:0"`
+	ResponseBody []byte
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// openSchema connects to TEST_DATABASE_DSN inside a fresh schema that is
+// dropped when the test ends. The test is skipped when the variable is unset.
+func openSchema(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+	admin, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := fmt.Sprintf("migrations_test_%d", time.Now().UnixNano())
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+	})
+
+	db, err := gorm.Open(postgres.Open(dsn+" search_path="+schema), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func TestUpOnAnEmptyDatabase(t *testing.T) {
+	db := openSchema(t)
+	assertUpAppliesEverything(t, db)
+}
+
+func TestUpAdoptsASchemaBuiltByAutoMigrate(t *testing.T) {
+	db := openSchema(t)
+	err := db.AutoMigrate(
+		&Bank{},
+		&Branch{},
+		&Customer{},
+		&SavingsAccount{},
+		&CustomerAccount{},
+		&Transaction{},
+		&Loan{},
+		&LoanPayment{},
+		&LedgerAccount{},
+		&JournalEntry{},
+		&Posting{},
+		&IdempotencyRecord{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bank := Bank{Name: "Legacy Bank"}
+	if err := db.Create(&bank).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	assertUpAppliesEverything(t, db)
+
+	var count int64
+	if err := db.Table("banks").Where("name = ?", "Legacy Bank").Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Fatalf("found %d legacy banks after migrating, want 1", count)
+	}
+}
+
+func assertUpAppliesEverything(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	migrator, err := migrations.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	statuses, err := migrator.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			t.Errorf("migration %d_%s was not applied", status.Version, status.Name)
+		}
+	}
+}
@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS idempotency_records;
+DROP TABLE IF EXISTS loan_payments;
+DROP TABLE IF EXISTS loans;
+DROP TABLE IF EXISTS transactions;
+DROP TABLE IF EXISTS postings;
+DROP TABLE IF EXISTS journal_entries;
+DROP TABLE IF EXISTS ledger_accounts;
+DROP TABLE IF EXISTS customer_accounts;
+DROP TABLE IF EXISTS savings_accounts;
+DROP TABLE IF EXISTS customers;
+DROP TABLE IF EXISTS branches;
+DROP TABLE IF EXISTS banks;
@@ -0,0 +1,126 @@
+-- Deployments that predate these migrations already have the tables below,
+-- built by GORM AutoMigrate under the same names, so every statement leaves
+-- an existing table or index alone.
+
+CREATE TABLE IF NOT EXISTS banks (
+    id         BIGSERIAL PRIMARY KEY,
+    name       TEXT        NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS branches (
+    id         BIGSERIAL PRIMARY KEY,
+    bank_id    BIGINT      NOT NULL REFERENCES banks (id) ON DELETE CASCADE,
+    name       TEXT        NOT NULL,
+    address    TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_branches_bank_id ON branches (bank_id);
+
+CREATE TABLE IF NOT EXISTS customers (
+    id         BIGSERIAL PRIMARY KEY,
+    branch_id  BIGINT      NOT NULL REFERENCES branches (id) ON DELETE CASCADE,
+    name       TEXT        NOT NULL,
+    email      TEXT,
+    phone      TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_customers_branch_id ON customers (branch_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_customers_email ON customers (email);
+
+CREATE TABLE IF NOT EXISTS savings_accounts (
+    id         BIGSERIAL PRIMARY KEY,
+    balance    BIGINT      NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS customer_accounts (
+    id          BIGSERIAL PRIMARY KEY,
+    customer_id BIGINT      NOT NULL REFERENCES customers (id) ON DELETE CASCADE,
+    account_id  BIGINT      NOT NULL REFERENCES savings_accounts (id) ON DELETE CASCADE,
+    holder_role TEXT        NOT NULL DEFAULT 'primary_holder',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_customer_accounts_customer_id ON customer_accounts (customer_id);
+CREATE INDEX IF NOT EXISTS idx_customer_accounts_account_id ON customer_accounts (account_id);
+
+CREATE TABLE IF NOT EXISTS ledger_accounts (
+    id                 BIGSERIAL PRIMARY KEY,
+    code               TEXT        NOT NULL,
+    name               TEXT        NOT NULL,
+    type               TEXT        NOT NULL,
+    savings_account_id BIGINT,
+    loan_id            BIGINT,
+    created_at         TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ledger_accounts_code ON ledger_accounts (code);
+CREATE INDEX IF NOT EXISTS idx_ledger_accounts_savings_account_id ON ledger_accounts (savings_account_id);
+CREATE INDEX IF NOT EXISTS idx_ledger_accounts_loan_id ON ledger_accounts (loan_id);
+
+CREATE TABLE IF NOT EXISTS journal_entries (
+    id          BIGSERIAL PRIMARY KEY,
+    type        TEXT        NOT NULL,
+    description TEXT,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_journal_entries_type ON journal_entries (type);
+
+CREATE TABLE IF NOT EXISTS postings (
+    id                BIGSERIAL PRIMARY KEY,
+    journal_entry_id  BIGINT      NOT NULL REFERENCES journal_entries (id) ON DELETE CASCADE,
+    ledger_account_id BIGINT      NOT NULL REFERENCES ledger_accounts (id),
+    amount            BIGINT      NOT NULL,
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_postings_journal_entry_id ON postings (journal_entry_id);
+CREATE INDEX IF NOT EXISTS idx_postings_ledger_account_id ON postings (ledger_account_id);
+
+CREATE TABLE IF NOT EXISTS transactions (
+    id               BIGSERIAL PRIMARY KEY,
+    account_id       BIGINT      NOT NULL REFERENCES savings_accounts (id) ON DELETE CASCADE,
+    type             TEXT        NOT NULL,
+    amount           BIGINT      NOT NULL,
+    transfer_id      TEXT,
+    journal_entry_id BIGINT REFERENCES journal_entries (id),
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_transactions_account_id ON transactions (account_id);
+CREATE INDEX IF NOT EXISTS idx_transactions_transfer_id ON transactions (transfer_id);
+CREATE INDEX IF NOT EXISTS idx_transactions_journal_entry_id ON transactions (journal_entry_id);
+
+CREATE TABLE IF NOT EXISTS loans (
+    id                   BIGSERIAL PRIMARY KEY,
+    customer_id          BIGINT      NOT NULL REFERENCES customers (id) ON DELETE CASCADE,
+    loan_type            TEXT        NOT NULL,
+    principal_amount     BIGINT      NOT NULL,
+    interest_rate        NUMERIC     NOT NULL DEFAULT 12,
+    total_payable_amount BIGINT      NOT NULL,
+    pending_amount       BIGINT      NOT NULL,
+    start_date           TIMESTAMPTZ NOT NULL,
+    end_date             TIMESTAMPTZ,
+    status               TEXT        NOT NULL DEFAULT 'ACTIVE',
+    created_at           TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_loans_customer_id ON loans (customer_id);
+
+CREATE TABLE IF NOT EXISTS loan_payments (
+    id               BIGSERIAL PRIMARY KEY,
+    loan_id          BIGINT      NOT NULL REFERENCES loans (id) ON DELETE CASCADE,
+    amount           BIGINT      NOT NULL,
+    payment_date     TIMESTAMPTZ NOT NULL,
+    journal_entry_id BIGINT REFERENCES journal_entries (id),
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_loan_payments_loan_id ON loan_payments (loan_id);
+CREATE INDEX IF NOT EXISTS idx_loan_payments_journal_entry_id ON loan_payments (journal_entry_id);
+
+CREATE TABLE IF NOT EXISTS idempotency_records (
+    key           VARCHAR(255) PRIMARY KEY,
+    method        TEXT        NOT NULL,
+    path          TEXT        NOT NULL,
+    request_hash  TEXT        NOT NULL,
+    status_code   BIGINT      NOT NULL DEFAULT 0,
+    response_body BYTEA,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
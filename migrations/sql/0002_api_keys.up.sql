@@ -0,0 +1,10 @@
+CREATE TABLE api_keys (
+    id         BIGSERIAL PRIMARY KEY,
+    name       TEXT        NOT NULL,
+    prefix     TEXT        NOT NULL,
+    key_hash   TEXT        NOT NULL,
+    created_by TEXT,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE UNIQUE INDEX idx_api_keys_key_hash ON api_keys (key_hash);
@@ -0,0 +1,4 @@
+ALTER TABLE api_keys DROP COLUMN customer_id;
+ALTER TABLE api_keys DROP COLUMN branch_id;
+ALTER TABLE api_keys DROP COLUMN bank_id;
+ALTER TABLE api_keys DROP COLUMN role;
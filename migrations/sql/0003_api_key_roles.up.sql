@@ -0,0 +1,9 @@
+-- Keys issued before roles existed had full access; keep them working as
+-- system admins.
+ALTER TABLE api_keys ADD COLUMN role TEXT NOT NULL DEFAULT 'system_admin';
+ALTER TABLE api_keys ALTER COLUMN role DROP DEFAULT;
+ALTER TABLE api_keys ADD COLUMN bank_id BIGINT REFERENCES banks (id) ON DELETE CASCADE;
+ALTER TABLE api_keys ADD COLUMN branch_id BIGINT REFERENCES branches (id) ON DELETE CASCADE;
+ALTER TABLE api_keys ADD COLUMN customer_id BIGINT REFERENCES customers (id) ON DELETE CASCADE;
+CREATE INDEX idx_api_keys_bank_id ON api_keys (bank_id);
+CREATE INDEX idx_api_keys_branch_id ON api_keys (branch_id);
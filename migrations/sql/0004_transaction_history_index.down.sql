@@ -0,0 +1,2 @@
+CREATE INDEX idx_transactions_account_id ON transactions (account_id);
+DROP INDEX IF EXISTS idx_transactions_account_created_id;
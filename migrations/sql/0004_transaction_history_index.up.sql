@@ -0,0 +1,3 @@
+-- Serves keyset pagination of an account's history, newest first.
+CREATE INDEX idx_transactions_account_created_id ON transactions (account_id, created_at DESC, id DESC);
+DROP INDEX IF EXISTS idx_transactions_account_id;
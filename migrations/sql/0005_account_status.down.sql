@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS account_status_changes;
+DROP INDEX IF EXISTS idx_savings_accounts_status;
+ALTER TABLE savings_accounts DROP COLUMN closed_at;
+ALTER TABLE savings_accounts DROP COLUMN status;
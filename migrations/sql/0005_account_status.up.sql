@@ -0,0 +1,17 @@
+ALTER TABLE savings_accounts ADD COLUMN status TEXT NOT NULL DEFAULT 'ACTIVE';
+ALTER TABLE savings_accounts ADD COLUMN closed_at TIMESTAMPTZ;
+CREATE INDEX idx_savings_accounts_status ON savings_accounts (status);
+
+CREATE TABLE account_status_changes (
+    id               BIGSERIAL PRIMARY KEY,
+    account_id       BIGINT      NOT NULL REFERENCES savings_accounts (id) ON DELETE CASCADE,
+    from_status      TEXT        NOT NULL,
+    to_status        TEXT        NOT NULL,
+    reason           TEXT        NOT NULL,
+    note             TEXT,
+    changed_by       TEXT,
+    journal_entry_id BIGINT REFERENCES journal_entries (id),
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX idx_account_status_changes_account_id ON account_status_changes (account_id);
+CREATE INDEX idx_account_status_changes_journal_entry_id ON account_status_changes (journal_entry_id);
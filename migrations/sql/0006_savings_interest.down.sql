@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS interest_accruals;
+ALTER TABLE savings_accounts DROP COLUMN interest_posting;
+ALTER TABLE savings_accounts DROP COLUMN interest_rate;
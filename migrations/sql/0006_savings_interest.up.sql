@@ -0,0 +1,20 @@
+-- Existing accounts start earning the default savings rate.
+ALTER TABLE savings_accounts ADD COLUMN interest_rate NUMERIC NOT NULL DEFAULT 3.5;
+ALTER TABLE savings_accounts ALTER COLUMN interest_rate SET DEFAULT 0;
+ALTER TABLE savings_accounts ADD COLUMN interest_posting TEXT NOT NULL DEFAULT 'MONTHLY';
+
+CREATE TABLE interest_accruals (
+    id               BIGSERIAL PRIMARY KEY,
+    account_id       BIGINT      NOT NULL REFERENCES savings_accounts (id) ON DELETE CASCADE,
+    business_date    DATE        NOT NULL,
+    balance          BIGINT      NOT NULL,
+    interest_rate    NUMERIC     NOT NULL,
+    amount           BIGINT      NOT NULL,
+    journal_entry_id BIGINT REFERENCES journal_entries (id),
+    transaction_id   BIGINT REFERENCES transactions (id),
+    posted_at        TIMESTAMPTZ,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE UNIQUE INDEX idx_interest_accruals_account_date ON interest_accruals (account_id, business_date);
+CREATE INDEX idx_interest_accruals_journal_entry_id ON interest_accruals (journal_entry_id);
+CREATE INDEX idx_interest_accruals_transaction_id ON interest_accruals (transaction_id);
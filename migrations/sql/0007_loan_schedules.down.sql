@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS loan_payment_allocations;
+DROP TABLE IF EXISTS loan_installments;
+ALTER TABLE loan_payments DROP COLUMN interest_paid;
+ALTER TABLE loan_payments DROP COLUMN principal_paid;
+ALTER TABLE loans DROP COLUMN installment_amount;
+ALTER TABLE loans DROP COLUMN repayment_frequency;
+ALTER TABLE loans DROP COLUMN tenure_months;
@@ -0,0 +1,37 @@
+-- Loans taken before schedules existed keep a tenure of 0 and no
+-- installments; repayments on them are split between principal and interest
+-- in proportion to the payable amount rather than allocated to installments.
+ALTER TABLE loans ADD COLUMN tenure_months INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE loans ADD COLUMN repayment_frequency TEXT NOT NULL DEFAULT 'MONTHLY';
+ALTER TABLE loans ADD COLUMN installment_amount BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE loan_payments ADD COLUMN principal_paid BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE loan_payments ADD COLUMN interest_paid BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE loan_installments (
+    id                    BIGSERIAL PRIMARY KEY,
+    loan_id               BIGINT      NOT NULL REFERENCES loans (id) ON DELETE CASCADE,
+    number                INTEGER     NOT NULL,
+    due_date              DATE        NOT NULL,
+    amount                BIGINT      NOT NULL,
+    principal_due         BIGINT      NOT NULL,
+    interest_due          BIGINT      NOT NULL,
+    outstanding_principal BIGINT      NOT NULL,
+    principal_paid        BIGINT      NOT NULL DEFAULT 0,
+    interest_paid         BIGINT      NOT NULL DEFAULT 0,
+    status                TEXT        NOT NULL DEFAULT 'PENDING',
+    paid_at               TIMESTAMPTZ,
+    created_at            TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE UNIQUE INDEX idx_loan_installments_loan_number ON loan_installments (loan_id, number);
+
+CREATE TABLE loan_payment_allocations (
+    id             BIGSERIAL PRIMARY KEY,
+    payment_id     BIGINT      NOT NULL REFERENCES loan_payments (id) ON DELETE CASCADE,
+    installment_id BIGINT      NOT NULL REFERENCES loan_installments (id) ON DELETE CASCADE,
+    principal      BIGINT      NOT NULL,
+    interest       BIGINT      NOT NULL,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX idx_loan_payment_allocations_payment_id ON loan_payment_allocations (payment_id);
+CREATE INDEX idx_loan_payment_allocations_installment_id ON loan_payment_allocations (installment_id);
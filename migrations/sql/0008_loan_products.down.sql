@@ -0,0 +1,3 @@
+ALTER TABLE loans DROP COLUMN processing_fee;
+ALTER TABLE loans DROP COLUMN product_id;
+DROP TABLE IF EXISTS loan_products;
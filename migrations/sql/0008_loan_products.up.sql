@@ -0,0 +1,26 @@
+CREATE TABLE loan_products (
+    id                    BIGSERIAL PRIMARY KEY,
+    bank_id               BIGINT      NOT NULL REFERENCES banks (id) ON DELETE CASCADE,
+    code                  TEXT        NOT NULL,
+    name                  TEXT        NOT NULL,
+    loan_type             TEXT        NOT NULL,
+    interest_rate         NUMERIC     NOT NULL,
+    min_principal         BIGINT      NOT NULL,
+    max_principal         BIGINT      NOT NULL,
+    min_tenure_months     INTEGER     NOT NULL,
+    max_tenure_months     INTEGER     NOT NULL,
+    repayment_frequency   TEXT,
+    processing_fee_rate   NUMERIC     NOT NULL DEFAULT 0,
+    processing_fee        BIGINT      NOT NULL DEFAULT 0,
+    max_active_loans      INTEGER     NOT NULL DEFAULT 0,
+    min_savings_balance   BIGINT      NOT NULL DEFAULT 0,
+    min_relationship_days INTEGER     NOT NULL DEFAULT 0,
+    active                BOOLEAN     NOT NULL,
+    created_at            TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at            TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE UNIQUE INDEX idx_loan_products_bank_code ON loan_products (bank_id, code);
+
+ALTER TABLE loans ADD COLUMN product_id BIGINT REFERENCES loan_products (id);
+ALTER TABLE loans ADD COLUMN processing_fee BIGINT NOT NULL DEFAULT 0;
+CREATE INDEX idx_loans_product_id ON loans (product_id);
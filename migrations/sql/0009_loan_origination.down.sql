@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS loan_status_changes;
+ALTER TABLE loans DROP COLUMN disbursed_at;
+ALTER TABLE loans DROP COLUMN disbursement_transaction_id;
+ALTER TABLE loans DROP COLUMN disbursement_account_id;
+ALTER TABLE loans DROP COLUMN approved_by;
+ALTER TABLE loans DROP COLUMN submitted_by;
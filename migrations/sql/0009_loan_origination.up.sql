@@ -0,0 +1,22 @@
+ALTER TABLE loans ADD COLUMN submitted_by TEXT;
+ALTER TABLE loans ADD COLUMN approved_by TEXT;
+ALTER TABLE loans ADD COLUMN disbursement_account_id BIGINT REFERENCES savings_accounts (id);
+ALTER TABLE loans ADD COLUMN disbursement_transaction_id BIGINT REFERENCES transactions (id);
+ALTER TABLE loans ADD COLUMN disbursed_at TIMESTAMPTZ;
+CREATE INDEX idx_loans_disbursement_account_id ON loans (disbursement_account_id);
+
+-- Loans from before the application workflow were paid out in cash when
+-- they were created.
+UPDATE loans SET disbursed_at = start_date;
+
+CREATE TABLE loan_status_changes (
+    id          BIGSERIAL PRIMARY KEY,
+    loan_id     BIGINT      NOT NULL REFERENCES loans (id) ON DELETE CASCADE,
+    from_status TEXT        NOT NULL,
+    to_status   TEXT        NOT NULL,
+    reason      TEXT,
+    note        TEXT,
+    changed_by  TEXT,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX idx_loan_status_changes_loan_id ON loan_status_changes (loan_id);
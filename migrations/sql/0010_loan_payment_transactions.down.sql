@@ -0,0 +1 @@
+ALTER TABLE loan_payments DROP COLUMN transaction_id;
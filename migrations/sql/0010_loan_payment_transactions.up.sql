@@ -0,0 +1,2 @@
+ALTER TABLE loan_payments ADD COLUMN transaction_id BIGINT REFERENCES transactions (id);
+CREATE INDEX idx_loan_payments_transaction_id ON loan_payments (transaction_id);
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS loan_collections;
+ALTER TABLE loan_products DROP COLUMN max_retries;
+ALTER TABLE loan_products DROP COLUMN retry_interval_days;
+ALTER TABLE loan_products DROP COLUMN bounce_charge;
+ALTER TABLE loan_payments DROP COLUMN charges_paid;
+ALTER TABLE loans DROP COLUMN repayment_account_id;
+ALTER TABLE loans DROP COLUMN charges_due;
@@ -0,0 +1,29 @@
+ALTER TABLE loans ADD COLUMN charges_due BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE loans ADD COLUMN repayment_account_id BIGINT REFERENCES savings_accounts (id);
+CREATE INDEX idx_loans_repayment_account_id ON loans (repayment_account_id);
+UPDATE loans SET repayment_account_id = disbursement_account_id;
+
+ALTER TABLE loan_payments ADD COLUMN charges_paid BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE loan_products ADD COLUMN bounce_charge BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE loan_products ADD COLUMN retry_interval_days INTEGER NOT NULL DEFAULT 3;
+ALTER TABLE loan_products ADD COLUMN max_retries INTEGER NOT NULL DEFAULT 3;
+
+CREATE TABLE loan_collections (
+    id                BIGSERIAL PRIMARY KEY,
+    loan_id           BIGINT      NOT NULL REFERENCES loans (id) ON DELETE CASCADE,
+    installment_id    BIGINT      NOT NULL REFERENCES loan_installments (id) ON DELETE CASCADE,
+    account_id        BIGINT      NOT NULL REFERENCES savings_accounts (id),
+    business_date     DATE        NOT NULL,
+    attempt           INTEGER     NOT NULL,
+    amount            BIGINT      NOT NULL,
+    status            TEXT        NOT NULL,
+    failure_reason    TEXT,
+    payment_id        BIGINT REFERENCES loan_payments (id),
+    bounce_charge     BIGINT      NOT NULL DEFAULT 0,
+    charge_entry_id   BIGINT REFERENCES journal_entries (id),
+    next_attempt_date DATE,
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX idx_loan_collections_loan_id ON loan_collections (loan_id);
+CREATE UNIQUE INDEX idx_loan_collections_installment_date ON loan_collections (installment_id, business_date);
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS loan_classifications;
+ALTER TABLE loan_products DROP COLUMN penal_interest_rate;
+ALTER TABLE loan_products DROP COLUMN late_fee;
+ALTER TABLE loan_installments DROP COLUMN late_fee;
+DROP INDEX IF EXISTS idx_loans_delinquency_bucket;
+ALTER TABLE loans DROP COLUMN classified_through;
+ALTER TABLE loans DROP COLUMN delinquency_bucket;
+ALTER TABLE loans DROP COLUMN overdue_amount;
+ALTER TABLE loans DROP COLUMN days_past_due;
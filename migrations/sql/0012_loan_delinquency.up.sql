@@ -0,0 +1,22 @@
+ALTER TABLE loans ADD COLUMN days_past_due INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE loans ADD COLUMN overdue_amount BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE loans ADD COLUMN delinquency_bucket TEXT NOT NULL DEFAULT 'CURRENT';
+ALTER TABLE loans ADD COLUMN classified_through DATE;
+CREATE INDEX idx_loans_delinquency_bucket ON loans (delinquency_bucket);
+
+ALTER TABLE loan_installments ADD COLUMN late_fee BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE loan_products ADD COLUMN late_fee BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE loan_products ADD COLUMN penal_interest_rate NUMERIC NOT NULL DEFAULT 0;
+
+CREATE TABLE loan_classifications (
+    id             BIGSERIAL PRIMARY KEY,
+    loan_id        BIGINT      NOT NULL REFERENCES loans (id) ON DELETE CASCADE,
+    business_date  DATE        NOT NULL,
+    from_bucket    TEXT        NOT NULL,
+    to_bucket      TEXT        NOT NULL,
+    days_past_due  INTEGER     NOT NULL,
+    overdue_amount BIGINT      NOT NULL,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX idx_loan_classifications_loan_id ON loan_classifications (loan_id);
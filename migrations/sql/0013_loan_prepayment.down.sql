@@ -0,0 +1 @@
+ALTER TABLE loan_products DROP COLUMN prepayment_penalty_rate;
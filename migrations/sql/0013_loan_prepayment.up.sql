@@ -0,0 +1 @@
+ALTER TABLE loan_products ADD COLUMN prepayment_penalty_rate NUMERIC NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+ALTER TABLE loans DROP COLUMN day_count;
+ALTER TABLE loan_products DROP COLUMN day_count;
@@ -0,0 +1,2 @@
+ALTER TABLE loan_products ADD COLUMN day_count TEXT NOT NULL DEFAULT 'ACT/365';
+ALTER TABLE loans ADD COLUMN day_count TEXT NOT NULL DEFAULT 'ACT/365';
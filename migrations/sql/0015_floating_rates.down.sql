@@ -0,0 +1,13 @@
+DROP TABLE IF EXISTS loan_rate_changes;
+DROP INDEX IF EXISTS idx_loans_next_reset_date;
+ALTER TABLE loans DROP COLUMN next_reset_date;
+ALTER TABLE loans DROP COLUMN reset_months;
+ALTER TABLE loans DROP COLUMN spread;
+ALTER TABLE loans DROP COLUMN benchmark_id;
+ALTER TABLE loans DROP COLUMN rate_type;
+ALTER TABLE loan_products DROP COLUMN reset_months;
+ALTER TABLE loan_products DROP COLUMN spread;
+ALTER TABLE loan_products DROP COLUMN benchmark_id;
+ALTER TABLE loan_products DROP COLUMN rate_type;
+DROP TABLE IF EXISTS benchmark_rates;
+DROP TABLE IF EXISTS benchmarks;
@@ -0,0 +1,45 @@
+CREATE TABLE benchmarks (
+    id         BIGSERIAL PRIMARY KEY,
+    bank_id    BIGINT      NOT NULL REFERENCES banks (id) ON DELETE CASCADE,
+    code       TEXT        NOT NULL,
+    name       TEXT        NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE UNIQUE INDEX idx_benchmarks_bank_code ON benchmarks (bank_id, code);
+
+CREATE TABLE benchmark_rates (
+    id             BIGSERIAL PRIMARY KEY,
+    benchmark_id   BIGINT      NOT NULL REFERENCES benchmarks (id) ON DELETE CASCADE,
+    effective_date DATE        NOT NULL,
+    rate           NUMERIC     NOT NULL,
+    created_by     TEXT,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE UNIQUE INDEX idx_benchmark_rates_benchmark_date ON benchmark_rates (benchmark_id, effective_date);
+
+ALTER TABLE loan_products ADD COLUMN rate_type TEXT NOT NULL DEFAULT 'FIXED';
+ALTER TABLE loan_products ADD COLUMN benchmark_id BIGINT REFERENCES benchmarks (id);
+ALTER TABLE loan_products ADD COLUMN spread NUMERIC NOT NULL DEFAULT 0;
+ALTER TABLE loan_products ADD COLUMN reset_months INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE loans ADD COLUMN rate_type TEXT NOT NULL DEFAULT 'FIXED';
+ALTER TABLE loans ADD COLUMN benchmark_id BIGINT REFERENCES benchmarks (id);
+ALTER TABLE loans ADD COLUMN spread NUMERIC NOT NULL DEFAULT 0;
+ALTER TABLE loans ADD COLUMN reset_months INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE loans ADD COLUMN next_reset_date DATE;
+CREATE INDEX idx_loans_next_reset_date ON loans (next_reset_date);
+
+CREATE TABLE loan_rate_changes (
+    id                 BIGSERIAL PRIMARY KEY,
+    loan_id            BIGINT      NOT NULL REFERENCES loans (id) ON DELETE CASCADE,
+    effective_date     DATE        NOT NULL,
+    reason             TEXT        NOT NULL,
+    benchmark_rate     NUMERIC,
+    spread             NUMERIC     NOT NULL DEFAULT 0,
+    previous_rate      NUMERIC     NOT NULL,
+    interest_rate      NUMERIC     NOT NULL,
+    installment_amount BIGINT      NOT NULL,
+    interest_change    BIGINT      NOT NULL DEFAULT 0,
+    created_at         TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX idx_loan_rate_changes_loan_id ON loan_rate_changes (loan_id);
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS loan_schedule_snapshots;
+DROP TABLE IF EXISTS loan_restructurings;
+ALTER TABLE loan_installments DROP COLUMN deferred_interest;
+DROP INDEX IF EXISTS idx_loans_restructured;
+ALTER TABLE loans DROP COLUMN restructured_on;
+ALTER TABLE loans DROP COLUMN restructured;
+ALTER TABLE loans DROP COLUMN moratorium_months;
+ALTER TABLE loans DROP COLUMN schedule_version;
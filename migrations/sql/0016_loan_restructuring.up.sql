@@ -0,0 +1,51 @@
+ALTER TABLE loans ADD COLUMN schedule_version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE loans ADD COLUMN moratorium_months INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE loans ADD COLUMN restructured BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE loans ADD COLUMN restructured_on DATE;
+CREATE INDEX idx_loans_restructured ON loans (restructured);
+
+ALTER TABLE loan_installments ADD COLUMN deferred_interest BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE loan_restructurings (
+    id                     BIGSERIAL PRIMARY KEY,
+    loan_id                BIGINT      NOT NULL REFERENCES loans (id) ON DELETE CASCADE,
+    kind                   TEXT        NOT NULL,
+    effective_date         DATE        NOT NULL,
+    reason                 TEXT        NOT NULL,
+    note                   TEXT,
+    moratorium_months      INTEGER     NOT NULL DEFAULT 0,
+    interest_treatment     TEXT,
+    moratorium_interest    BIGINT      NOT NULL DEFAULT 0,
+    previous_rate          NUMERIC     NOT NULL,
+    interest_rate          NUMERIC     NOT NULL,
+    previous_tenure_months INTEGER     NOT NULL,
+    tenure_months          INTEGER     NOT NULL,
+    previous_installment   BIGINT      NOT NULL,
+    installment_amount     BIGINT      NOT NULL,
+    interest_change        BIGINT      NOT NULL DEFAULT 0,
+    schedule_version       INTEGER     NOT NULL,
+    approved_by            TEXT,
+    created_at             TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX idx_loan_restructurings_loan_id ON loan_restructurings (loan_id);
+
+CREATE TABLE loan_schedule_snapshots (
+    id                    BIGSERIAL PRIMARY KEY,
+    loan_id               BIGINT      NOT NULL REFERENCES loans (id) ON DELETE CASCADE,
+    version               INTEGER     NOT NULL,
+    installment_id        BIGINT      NOT NULL,
+    number                INTEGER     NOT NULL,
+    due_date              DATE        NOT NULL,
+    amount                BIGINT      NOT NULL,
+    principal_due         BIGINT      NOT NULL,
+    interest_due          BIGINT      NOT NULL,
+    deferred_interest     BIGINT      NOT NULL DEFAULT 0,
+    outstanding_principal BIGINT      NOT NULL,
+    principal_paid        BIGINT      NOT NULL DEFAULT 0,
+    interest_paid         BIGINT      NOT NULL DEFAULT 0,
+    late_fee              BIGINT      NOT NULL DEFAULT 0,
+    status                TEXT        NOT NULL,
+    paid_at               TIMESTAMPTZ,
+    created_at            TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE UNIQUE INDEX idx_loan_schedule_snapshots_version ON loan_schedule_snapshots (loan_id, version, number);
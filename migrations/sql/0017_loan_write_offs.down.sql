@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS loan_recoveries;
+DROP TABLE IF EXISTS loan_write_offs;
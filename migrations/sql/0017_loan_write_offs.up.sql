@@ -0,0 +1,35 @@
+CREATE TABLE loan_write_offs (
+    id               BIGSERIAL PRIMARY KEY,
+    loan_id          BIGINT      NOT NULL REFERENCES loans (id) ON DELETE CASCADE,
+    write_off_date   DATE        NOT NULL,
+    principal        BIGINT      NOT NULL,
+    interest         BIGINT      NOT NULL,
+    charges          BIGINT      NOT NULL,
+    amount           BIGINT      NOT NULL,
+    loss             BIGINT      NOT NULL,
+    gross_recovered  BIGINT      NOT NULL DEFAULT 0,
+    recovery_costs   BIGINT      NOT NULL DEFAULT 0,
+    reason           TEXT        NOT NULL,
+    note             TEXT,
+    approved_by      TEXT        NOT NULL,
+    journal_entry_id BIGINT REFERENCES journal_entries (id),
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE UNIQUE INDEX idx_loan_write_offs_loan_id ON loan_write_offs (loan_id);
+
+CREATE TABLE loan_recoveries (
+    id               BIGSERIAL PRIMARY KEY,
+    loan_id          BIGINT      NOT NULL REFERENCES loans (id) ON DELETE CASCADE,
+    write_off_id     BIGINT      NOT NULL REFERENCES loan_write_offs (id) ON DELETE CASCADE,
+    recovery_date    DATE        NOT NULL,
+    amount           BIGINT      NOT NULL,
+    cost             BIGINT      NOT NULL DEFAULT 0,
+    net_amount       BIGINT      NOT NULL,
+    account_id       BIGINT REFERENCES savings_accounts (id),
+    transaction_id   BIGINT REFERENCES transactions (id),
+    journal_entry_id BIGINT REFERENCES journal_entries (id),
+    note             TEXT,
+    recorded_by      TEXT,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX idx_loan_recoveries_loan_id ON loan_recoveries (loan_id);
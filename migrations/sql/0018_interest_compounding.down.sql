@@ -0,0 +1,2 @@
+ALTER TABLE loans DROP COLUMN compounding_per_year;
+ALTER TABLE loan_products DROP COLUMN compounding_per_year;
@@ -0,0 +1,2 @@
+ALTER TABLE loan_products ADD COLUMN compounding_per_year INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE loans ADD COLUMN compounding_per_year INTEGER NOT NULL DEFAULT 0;
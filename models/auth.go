@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// APIKey is a service client credential. Only the SHA-256 hash of the key is
+// stored; Prefix keeps the first characters so a key can be recognised in
+// listings without revealing it. Role and the scope IDs are what the key is
+// allowed to do and where.
+type APIKey struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	Name       string     `gorm:"not null" json:"name"`
+	Prefix     string     `gorm:"not null" json:"prefix"`
+	KeyHash    string     `gorm:"not null;uniqueIndex" json:"-"`
+	Role       string     `gorm:"not null" json:"role"`
+	BankID     *uint      `gorm:"index" json:"bank_id,omitempty"`
+	BranchID   *uint      `gorm:"index" json:"branch_id,omitempty"`
+	CustomerID *uint      `json:"customer_id,omitempty"`
+	CreatedBy  string     `json:"created_by"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
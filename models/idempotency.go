@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// IdempotencyRecord stores the outcome of a money-moving request so that a
+// retry with the same Idempotency-Key replays it instead of running again.
+// A StatusCode of zero means the original request is still in flight.
+type IdempotencyRecord struct {
+	Key          string    `gorm:"primaryKey;size:255" json:"key"`
+	Method       string    `gorm:"not null" json:"method"`
+	Path         string    `gorm:"not null" json:"path"`
+	RequestHash  string    `gorm:"not null" json:"request_hash"`
+	StatusCode   int       `gorm:"not null;default:0" json:"status_code"`
+	ResponseBody []byte    `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
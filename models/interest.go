@@ -0,0 +1,43 @@
+package models
+
+import (
+	"banking-system/money"
+	"time"
+)
+
+// InterestAccrual is the interest a savings account earned on one business
+// date from its end-of-day balance. Accruals stay unposted until PostedAt is
+// set; TransactionID is the interest credit that paid them out.
+type InterestAccrual struct {
+	ID             uint        `gorm:"primaryKey" json:"id"`
+	AccountID      uint        `gorm:"not null;uniqueIndex:idx_interest_accruals_account_date" json:"account_id"`
+	BusinessDate   time.Time   `gorm:"type:date;not null;uniqueIndex:idx_interest_accruals_account_date" json:"business_date"`
+	Balance        money.Money `gorm:"not null" json:"balance"`
+	InterestRate   float64     `gorm:"type:numeric;not null" json:"interest_rate"`
+	Amount         money.Money `gorm:"not null" json:"amount"`
+	JournalEntryID *uint       `gorm:"index" json:"journal_entry_id,omitempty"`
+	TransactionID  *uint       `gorm:"index" json:"transaction_id,omitempty"`
+	PostedAt       *time.Time  `json:"posted_at,omitempty"`
+	CreatedAt      time.Time   `json:"created_at"`
+}
+
+// Benchmark is a reference rate a bank publishes, such as its base rate,
+// that floating-rate loans are priced off.
+type Benchmark struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	BankID    uint      `gorm:"not null;uniqueIndex:idx_benchmarks_bank_code" json:"bank_id"`
+	Code      string    `gorm:"not null;uniqueIndex:idx_benchmarks_bank_code" json:"code"`
+	Name      string    `gorm:"not null" json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BenchmarkRate is the value of a benchmark from EffectiveDate until the
+// next rate takes effect.
+type BenchmarkRate struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	BenchmarkID   uint      `gorm:"not null;uniqueIndex:idx_benchmark_rates_benchmark_date" json:"benchmark_id"`
+	EffectiveDate time.Time `gorm:"type:date;not null;uniqueIndex:idx_benchmark_rates_benchmark_date" json:"effective_date"`
+	Rate          float64   `gorm:"type:numeric;not null" json:"rate"`
+	CreatedBy     string    `json:"created_by,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
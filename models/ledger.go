@@ -0,0 +1,47 @@
+package models
+
+import (
+	"banking-system/money"
+	"time"
+)
+
+const (
+	LedgerTypeAsset     = "ASSET"
+	LedgerTypeLiability = "LIABILITY"
+	LedgerTypeIncome    = "INCOME"
+	LedgerTypeExpense   = "EXPENSE"
+)
+
+// LedgerAccount is an account in the general ledger. Customer-facing savings
+// accounts and loans each have their own ledger account; bank-side accounts
+// such as cash and interest income are shared.
+type LedgerAccount struct {
+	ID               uint      `gorm:"primaryKey" json:"id"`
+	Code             string    `gorm:"not null;uniqueIndex" json:"code"`
+	Name             string    `gorm:"not null" json:"name"`
+	Type             string    `gorm:"not null" json:"type"`
+	SavingsAccountID *uint     `gorm:"index" json:"savings_account_id,omitempty"`
+	LoanID           *uint     `gorm:"index" json:"loan_id,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// JournalEntry groups the postings of one business event. The amounts of its
+// postings always sum to zero.
+type JournalEntry struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Type        string    `gorm:"not null;index" json:"type"`
+	Description string    `json:"description"`
+	Postings    []Posting `gorm:"foreignKey:JournalEntryID;constraint:OnDelete:CASCADE" json:"postings,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Posting is one line of a journal entry. Debits are positive amounts and
+// credits are negative amounts.
+type Posting struct {
+	ID              uint          `gorm:"primaryKey" json:"id"`
+	JournalEntryID  uint          `gorm:"not null;index" json:"journal_entry_id"`
+	LedgerAccountID uint          `gorm:"not null;index" json:"ledger_account_id"`
+	LedgerAccount   LedgerAccount `gorm:"foreignKey:LedgerAccountID" json:"ledger_account,omitempty"`
+	Amount          money.Money   `gorm:"not null" json:"amount"`
+	CreatedAt       time.Time     `json:"created_at"`
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"banking-system/money"
+	"time"
+)
+
+// LoanProduct is a loan a bank offers. Its terms are copied onto each loan
+// at origination, so changing a product never changes existing loans. An
+// empty RepaymentFrequency lets the borrower choose; zero eligibility limits
+// are not enforced. A FLOATING product prices its loans at its benchmark's
+// rate plus Spread, reset every ResetMonths, and ignores InterestRate.
+// DayCount is the convention interest accrues under between due dates, and
+// CompoundingPerYear how many times a year it compounds (0 for simple).
+// PrepaymentPenaltyRate is a percentage of the principal paid off early.
+// The collection settings (bounce charge and retries) and late-payment
+// charges (late fee and penal interest) are operational and apply to the
+// product's loans as they stand.
+type LoanProduct struct {
+	ID                    uint        `gorm:"primaryKey" json:"id"`
+	BankID                uint        `gorm:"not null;uniqueIndex:idx_loan_products_bank_code" json:"bank_id"`
+	Code                  string      `gorm:"not null;uniqueIndex:idx_loan_products_bank_code" json:"code"`
+	Name                  string      `gorm:"not null" json:"name"`
+	LoanType              string      `gorm:"not null" json:"loan_type"`
+	InterestRate          float64     `gorm:"type:numeric;not null" json:"interest_rate"`
+	RateType              string      `gorm:"not null;default:'FIXED'" json:"rate_type"`
+	BenchmarkID           *uint       `json:"benchmark_id,omitempty"`
+	Spread                float64     `gorm:"type:numeric;not null;default:0" json:"spread"`
+	ResetMonths           int         `gorm:"not null;default:0" json:"reset_months,omitempty"`
+	MinPrincipal          money.Money `gorm:"not null" json:"min_principal"`
+	MaxPrincipal          money.Money `gorm:"not null" json:"max_principal"`
+	MinTenureMonths       int         `gorm:"not null" json:"min_tenure_months"`
+	MaxTenureMonths       int         `gorm:"not null" json:"max_tenure_months"`
+	RepaymentFrequency    string      `json:"repayment_frequency,omitempty"`
+	DayCount              string      `gorm:"not null;default:'ACT/365'" json:"day_count"`
+	CompoundingPerYear    int         `gorm:"not null;default:0" json:"compounding_per_year"`
+	ProcessingFeeRate     float64     `gorm:"type:numeric;not null;default:0" json:"processing_fee_rate"`
+	ProcessingFee         money.Money `gorm:"not null;default:0" json:"processing_fee"`
+	MaxActiveLoans        int         `gorm:"not null;default:0" json:"max_active_loans"`
+	MinSavingsBalance     money.Money `gorm:"not null;default:0" json:"min_savings_balance"`
+	MinRelationshipDays   int         `gorm:"not null;default:0" json:"min_relationship_days"`
+	BounceCharge          money.Money `gorm:"not null;default:0" json:"bounce_charge"`
+	RetryIntervalDays     int         `gorm:"not null;default:3" json:"retry_interval_days"`
+	MaxRetries            int         `gorm:"not null;default:3" json:"max_retries"`
+	PrepaymentPenaltyRate float64     `gorm:"type:numeric;not null;default:0" json:"prepayment_penalty_rate"`
+	LateFee               money.Money `gorm:"not null;default:0" json:"late_fee"`
+	PenalInterestRate     float64     `gorm:"type:numeric;not null;default:0" json:"penal_interest_rate"`
+	Active                bool        `gorm:"not null" json:"active"`
+	CreatedAt             time.Time   `json:"created_at"`
+	UpdatedAt             time.Time   `json:"updated_at"`
+}
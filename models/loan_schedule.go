@@ -0,0 +1,184 @@
+package models
+
+import (
+	"banking-system/money"
+	"time"
+)
+
+// LoanInstallment is one row of a loan's amortization schedule. Amount is
+// PrincipalDue plus InterestDue, and OutstandingPrincipal is what is left of
+// the principal once the installment is paid. DeferredInterest is the part
+// of InterestDue put off from a moratorium.
+type LoanInstallment struct {
+	ID                   uint        `gorm:"primaryKey" json:"id"`
+	LoanID               uint        `gorm:"not null;uniqueIndex:idx_loan_installments_loan_number" json:"loan_id"`
+	Number               int         `gorm:"not null;uniqueIndex:idx_loan_installments_loan_number" json:"number"`
+	DueDate              time.Time   `gorm:"type:date;not null" json:"due_date"`
+	Amount               money.Money `gorm:"not null" json:"amount"`
+	PrincipalDue         money.Money `gorm:"not null" json:"principal_due"`
+	InterestDue          money.Money `gorm:"not null" json:"interest_due"`
+	DeferredInterest     money.Money `gorm:"not null;default:0" json:"deferred_interest"`
+	OutstandingPrincipal money.Money `gorm:"not null" json:"outstanding_principal"`
+	PrincipalPaid        money.Money `gorm:"not null;default:0" json:"principal_paid"`
+	InterestPaid         money.Money `gorm:"not null;default:0" json:"interest_paid"`
+	LateFee              money.Money `gorm:"not null;default:0" json:"late_fee"`
+	Status               string      `gorm:"not null;default:'PENDING'" json:"status"`
+	PaidAt               *time.Time  `json:"paid_at,omitempty"`
+	CreatedAt            time.Time   `json:"created_at"`
+}
+
+// LoanCollection is one attempt to auto-debit an installment from the loan's
+// repayment account. A bounced attempt records the charge it incurred and,
+// while retries remain, when the next attempt is due.
+type LoanCollection struct {
+	ID              uint        `gorm:"primaryKey" json:"id"`
+	LoanID          uint        `gorm:"not null;index" json:"loan_id"`
+	InstallmentID   uint        `gorm:"not null;uniqueIndex:idx_loan_collections_installment_date" json:"installment_id"`
+	AccountID       uint        `gorm:"not null" json:"account_id"`
+	BusinessDate    time.Time   `gorm:"type:date;not null;uniqueIndex:idx_loan_collections_installment_date" json:"business_date"`
+	Attempt         int         `gorm:"not null" json:"attempt"`
+	Amount          money.Money `gorm:"not null" json:"amount"`
+	Status          string      `gorm:"not null" json:"status"`
+	FailureReason   string      `json:"failure_reason,omitempty"`
+	PaymentID       *uint       `json:"payment_id,omitempty"`
+	BounceCharge    money.Money `gorm:"not null;default:0" json:"bounce_charge"`
+	ChargeEntryID   *uint       `json:"charge_entry_id,omitempty"`
+	NextAttemptDate *time.Time  `gorm:"type:date" json:"next_attempt_date,omitempty"`
+	CreatedAt       time.Time   `json:"created_at"`
+}
+
+// LoanClassification records a loan moving between delinquency buckets, as
+// found by the nightly classification for BusinessDate.
+type LoanClassification struct {
+	ID            uint        `gorm:"primaryKey" json:"id"`
+	LoanID        uint        `gorm:"not null;index" json:"loan_id"`
+	BusinessDate  time.Time   `gorm:"type:date;not null" json:"business_date"`
+	FromBucket    string      `gorm:"not null" json:"from_bucket"`
+	ToBucket      string      `gorm:"not null" json:"to_bucket"`
+	DaysPastDue   int         `gorm:"not null" json:"days_past_due"`
+	OverdueAmount money.Money `gorm:"not null" json:"overdue_amount"`
+	CreatedAt     time.Time   `json:"created_at"`
+}
+
+// LoanRateChange records the rate a loan was priced at from EffectiveDate:
+// once at disbursement, and for floating-rate loans at each reset.
+// BenchmarkRate is the benchmark's value that the rate was set from, and
+// InterestChange how much the scheduled interest went up or down.
+type LoanRateChange struct {
+	ID                uint        `gorm:"primaryKey" json:"id"`
+	LoanID            uint        `gorm:"not null;index" json:"loan_id"`
+	EffectiveDate     time.Time   `gorm:"type:date;not null" json:"effective_date"`
+	Reason            string      `gorm:"not null" json:"reason"`
+	BenchmarkRate     *float64    `gorm:"type:numeric" json:"benchmark_rate,omitempty"`
+	Spread            float64     `gorm:"type:numeric;not null;default:0" json:"spread"`
+	PreviousRate      float64     `gorm:"type:numeric;not null" json:"previous_rate"`
+	InterestRate      float64     `gorm:"type:numeric;not null" json:"interest_rate"`
+	InstallmentAmount money.Money `gorm:"not null" json:"installment_amount"`
+	InterestChange    money.Money `gorm:"not null;default:0" json:"interest_change"`
+	CreatedAt         time.Time   `json:"created_at"`
+}
+
+// LoanRestructuring records relief granted to a borrower in hardship: a
+// MORATORIUM putting the installments still to come off by
+// MoratoriumMonths, or a RESTRUCTURE of the loan's rate and tenure. Each
+// replaces the loan's schedule with ScheduleVersion; the version it replaced
+// is kept as LoanScheduleSnapshot rows.
+type LoanRestructuring struct {
+	ID                   uint        `gorm:"primaryKey" json:"id"`
+	LoanID               uint        `gorm:"not null;index" json:"loan_id"`
+	Kind                 string      `gorm:"not null" json:"kind"`
+	EffectiveDate        time.Time   `gorm:"type:date;not null" json:"effective_date"`
+	Reason               string      `gorm:"not null" json:"reason"`
+	Note                 string      `json:"note,omitempty"`
+	MoratoriumMonths     int         `gorm:"not null;default:0" json:"moratorium_months,omitempty"`
+	InterestTreatment    string      `json:"interest_treatment,omitempty"`
+	MoratoriumInterest   money.Money `gorm:"not null;default:0" json:"moratorium_interest"`
+	PreviousRate         float64     `gorm:"type:numeric;not null" json:"previous_rate"`
+	InterestRate         float64     `gorm:"type:numeric;not null" json:"interest_rate"`
+	PreviousTenureMonths int         `gorm:"not null" json:"previous_tenure_months"`
+	TenureMonths         int         `gorm:"not null" json:"tenure_months"`
+	PreviousInstallment  money.Money `gorm:"not null" json:"previous_installment"`
+	InstallmentAmount    money.Money `gorm:"not null" json:"installment_amount"`
+	InterestChange       money.Money `gorm:"not null;default:0" json:"interest_change"`
+	ScheduleVersion      int         `gorm:"not null" json:"schedule_version"`
+	ApprovedBy           string      `json:"approved_by,omitempty"`
+	CreatedAt            time.Time   `json:"created_at"`
+}
+
+// LoanScheduleSnapshot is one installment of a schedule version that a
+// restructuring replaced, as it stood at the time. InstallmentID is the
+// installment it was copied from, which may since have changed or gone.
+type LoanScheduleSnapshot struct {
+	ID                   uint        `gorm:"primaryKey" json:"id"`
+	LoanID               uint        `gorm:"not null;uniqueIndex:idx_loan_schedule_snapshots_version" json:"loan_id"`
+	Version              int         `gorm:"not null;uniqueIndex:idx_loan_schedule_snapshots_version" json:"version"`
+	InstallmentID        uint        `gorm:"not null" json:"installment_id"`
+	Number               int         `gorm:"not null;uniqueIndex:idx_loan_schedule_snapshots_version" json:"number"`
+	DueDate              time.Time   `gorm:"type:date;not null" json:"due_date"`
+	Amount               money.Money `gorm:"not null" json:"amount"`
+	PrincipalDue         money.Money `gorm:"not null" json:"principal_due"`
+	InterestDue          money.Money `gorm:"not null" json:"interest_due"`
+	DeferredInterest     money.Money `gorm:"not null;default:0" json:"deferred_interest"`
+	OutstandingPrincipal money.Money `gorm:"not null" json:"outstanding_principal"`
+	PrincipalPaid        money.Money `gorm:"not null;default:0" json:"principal_paid"`
+	InterestPaid         money.Money `gorm:"not null;default:0" json:"interest_paid"`
+	LateFee              money.Money `gorm:"not null;default:0" json:"late_fee"`
+	Status               string      `gorm:"not null" json:"status"`
+	PaidAt               *time.Time  `json:"paid_at,omitempty"`
+	CreatedAt            time.Time   `json:"created_at"`
+}
+
+// LoanPaymentAllocation is the part of a repayment applied to one
+// installment.
+type LoanPaymentAllocation struct {
+	ID            uint        `gorm:"primaryKey" json:"id"`
+	PaymentID     uint        `gorm:"not null;index" json:"payment_id"`
+	InstallmentID uint        `gorm:"not null;index" json:"installment_id"`
+	Principal     money.Money `gorm:"not null" json:"principal"`
+	Interest      money.Money `gorm:"not null" json:"interest"`
+	CreatedAt     time.Time   `json:"created_at"`
+}
+
+// LoanWriteOff is the approval of writing a loan off as a loss. Amount is
+// what the borrower still owed: Principal, Interest and Charges. The
+// interest had never been earned, so Loss, what was charged to loan losses,
+// leaves it out. GrossRecovered and RecoveryCosts add up the loan's
+// recoveries since.
+type LoanWriteOff struct {
+	ID             uint        `gorm:"primaryKey" json:"id"`
+	LoanID         uint        `gorm:"not null;uniqueIndex" json:"loan_id"`
+	WriteOffDate   time.Time   `gorm:"type:date;not null" json:"write_off_date"`
+	Principal      money.Money `gorm:"not null" json:"principal"`
+	Interest       money.Money `gorm:"not null" json:"interest"`
+	Charges        money.Money `gorm:"not null" json:"charges"`
+	Amount         money.Money `gorm:"not null" json:"amount"`
+	Loss           money.Money `gorm:"not null" json:"loss"`
+	GrossRecovered money.Money `gorm:"not null;default:0" json:"gross_recovered"`
+	RecoveryCosts  money.Money `gorm:"not null;default:0" json:"recovery_costs"`
+	Reason         string      `gorm:"not null" json:"reason"`
+	Note           string      `json:"note,omitempty"`
+	ApprovedBy     string      `gorm:"not null" json:"approved_by"`
+	JournalEntryID *uint       `json:"journal_entry_id,omitempty"`
+	CreatedAt      time.Time   `json:"created_at"`
+}
+
+// LoanRecovery is money recovered on a written-off loan, kept apart from the
+// loan's payments. Amount is the gross recovered, Cost what recovering it
+// cost (agency or legal fees) and NetAmount the difference. AccountID is set
+// when it was debited from the borrower's savings account rather than
+// received in cash.
+type LoanRecovery struct {
+	ID             uint        `gorm:"primaryKey" json:"id"`
+	LoanID         uint        `gorm:"not null;index" json:"loan_id"`
+	WriteOffID     uint        `gorm:"not null" json:"write_off_id"`
+	RecoveryDate   time.Time   `gorm:"type:date;not null" json:"recovery_date"`
+	Amount         money.Money `gorm:"not null" json:"amount"`
+	Cost           money.Money `gorm:"not null;default:0" json:"cost"`
+	NetAmount      money.Money `gorm:"not null" json:"net_amount"`
+	AccountID      *uint       `json:"account_id,omitempty"`
+	TransactionID  *uint       `json:"transaction_id,omitempty"`
+	JournalEntryID *uint       `json:"journal_entry_id,omitempty"`
+	Note           string      `json:"note,omitempty"`
+	RecordedBy     string      `json:"recorded_by,omitempty"`
+	CreatedAt      time.Time   `json:"created_at"`
+}
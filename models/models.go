@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"banking-system/money"
+	"time"
+)
 
 type Bank struct {
 	ID        uint      `gorm:"primaryKey" json:"id"`
@@ -33,12 +36,30 @@ type Customer struct {
 
 type SavingsAccount struct {
 	ID               uint              `gorm:"primaryKey" json:"id"`
-	Balance          float64           `gorm:"not null;default:0" json:"balance"`
+	Balance          money.Money       `gorm:"not null;default:0" json:"balance"`
+	Status           string            `gorm:"not null;default:'ACTIVE';index" json:"status"`
+	InterestRate     float64           `gorm:"type:numeric;not null;default:0" json:"interest_rate"`
+	InterestPosting  string            `gorm:"not null;default:'MONTHLY'" json:"interest_posting"`
+	ClosedAt         *time.Time        `json:"closed_at,omitempty"`
 	CustomerAccounts []CustomerAccount `gorm:"foreignKey:AccountID;constraint:OnDelete:CASCADE" json:"customer_accounts,omitempty"`
 	Transactions     []Transaction     `gorm:"foreignKey:AccountID;constraint:OnDelete:CASCADE" json:"transactions,omitempty"`
 	CreatedAt        time.Time         `json:"created_at"`
 }
 
+// AccountStatusChange records one move of a savings account between
+// statuses. JournalEntryID is set on closures that settled a balance.
+type AccountStatusChange struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	AccountID      uint      `gorm:"not null;index" json:"account_id"`
+	FromStatus     string    `gorm:"not null" json:"from_status"`
+	ToStatus       string    `gorm:"not null" json:"to_status"`
+	Reason         string    `gorm:"not null" json:"reason"`
+	Note           string    `json:"note,omitempty"`
+	ChangedBy      string    `json:"changed_by,omitempty"`
+	JournalEntryID *uint     `gorm:"index" json:"journal_entry_id,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
 type CustomerAccount struct {
 	ID         uint           `gorm:"primaryKey" json:"id"`
 	CustomerID uint           `gorm:"not null;index" json:"customer_id"`
@@ -50,35 +71,82 @@ type CustomerAccount struct {
 }
 
 type Transaction struct {
-	ID        uint           `gorm:"primaryKey" json:"id"`
-	AccountID uint           `gorm:"not null;index" json:"account_id"`
-	Account   SavingsAccount `gorm:"foreignKey:AccountID;constraint:OnDelete:CASCADE" json:"account,omitempty"`
-	Type      string         `gorm:"not null" json:"type"`
-	Amount    float64        `gorm:"not null" json:"amount"`
-	CreatedAt time.Time      `json:"created_at"`
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	AccountID      uint           `gorm:"not null;index" json:"account_id"`
+	Account        SavingsAccount `gorm:"foreignKey:AccountID;constraint:OnDelete:CASCADE" json:"account,omitempty"`
+	Type           string         `gorm:"not null" json:"type"`
+	Amount         money.Money    `gorm:"not null" json:"amount"`
+	TransferID     string         `gorm:"index" json:"transfer_id,omitempty"`
+	JournalEntryID *uint          `gorm:"index" json:"journal_entry_id,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
 }
 
 type Loan struct {
-	ID                 uint          `gorm:"primaryKey" json:"id"`
-	CustomerID         uint          `gorm:"not null;index" json:"customer_id"`
-	Customer           Customer      `gorm:"foreignKey:CustomerID;constraint:OnDelete:CASCADE" json:"customer,omitempty"`
-	LoanType           string        `gorm:"not null" json:"loan_type"`
-	PrincipalAmount    float64       `gorm:"not null" json:"principal_amount"`
-	InterestRate       float64       `gorm:"not null;default:12" json:"interest_rate"`
-	TotalPayableAmount float64       `gorm:"not null" json:"total_payable_amount"`
-	PendingAmount      float64       `gorm:"not null" json:"pending_amount"`
-	StartDate          time.Time     `gorm:"not null" json:"start_date"`
-	EndDate            *time.Time    `json:"end_date,omitempty"`
-	Status             string        `gorm:"not null;default:'ACTIVE'" json:"status"`
-	LoanPayments       []LoanPayment `gorm:"foreignKey:LoanID;constraint:OnDelete:CASCADE" json:"loan_payments,omitempty"`
-	CreatedAt          time.Time     `json:"created_at"`
+	ID                        uint          `gorm:"primaryKey" json:"id"`
+	CustomerID                uint          `gorm:"not null;index" json:"customer_id"`
+	Customer                  Customer      `gorm:"foreignKey:CustomerID;constraint:OnDelete:CASCADE" json:"customer,omitempty"`
+	ProductID                 *uint         `gorm:"index" json:"product_id,omitempty"`
+	LoanType                  string        `gorm:"not null" json:"loan_type"`
+	PrincipalAmount           money.Money   `gorm:"not null" json:"principal_amount"`
+	InterestRate              float64       `gorm:"not null;default:12" json:"interest_rate"`
+	RateType                  string        `gorm:"not null;default:'FIXED'" json:"rate_type"`
+	BenchmarkID               *uint         `json:"benchmark_id,omitempty"`
+	Spread                    float64       `gorm:"type:numeric;not null;default:0" json:"spread"`
+	ResetMonths               int           `gorm:"not null;default:0" json:"reset_months,omitempty"`
+	NextResetDate             *time.Time    `gorm:"type:date" json:"next_reset_date,omitempty"`
+	TenureMonths              int           `gorm:"not null;default:0" json:"tenure_months"`
+	RepaymentFrequency        string        `gorm:"not null;default:'MONTHLY'" json:"repayment_frequency"`
+	DayCount                  string        `gorm:"not null;default:'ACT/365'" json:"day_count"`
+	CompoundingPerYear        int           `gorm:"not null;default:0" json:"compounding_per_year"`
+	InstallmentAmount         money.Money   `gorm:"not null;default:0" json:"installment_amount"`
+	ProcessingFee             money.Money   `gorm:"not null;default:0" json:"processing_fee"`
+	ChargesDue                money.Money   `gorm:"not null;default:0" json:"charges_due"`
+	TotalPayableAmount        money.Money   `gorm:"not null" json:"total_payable_amount"`
+	PendingAmount             money.Money   `gorm:"not null" json:"pending_amount"`
+	StartDate                 time.Time     `gorm:"not null" json:"start_date"`
+	EndDate                   *time.Time    `json:"end_date,omitempty"`
+	Status                    string        `gorm:"not null;default:'ACTIVE'" json:"status"`
+	SubmittedBy               string        `json:"submitted_by,omitempty"`
+	ApprovedBy                string        `json:"approved_by,omitempty"`
+	DisbursementAccountID     *uint         `gorm:"index" json:"disbursement_account_id,omitempty"`
+	DisbursementTransactionID *uint         `json:"disbursement_transaction_id,omitempty"`
+	DisbursedAt               *time.Time    `json:"disbursed_at,omitempty"`
+	RepaymentAccountID        *uint         `gorm:"index" json:"repayment_account_id,omitempty"`
+	DaysPastDue               int           `gorm:"not null;default:0" json:"days_past_due"`
+	OverdueAmount             money.Money   `gorm:"not null;default:0" json:"overdue_amount"`
+	DelinquencyBucket         string        `gorm:"not null;default:'CURRENT'" json:"delinquency_bucket"`
+	ClassifiedThrough         *time.Time    `gorm:"type:date" json:"classified_through,omitempty"`
+	ScheduleVersion           int           `gorm:"not null;default:1" json:"schedule_version"`
+	MoratoriumMonths          int           `gorm:"not null;default:0" json:"moratorium_months,omitempty"`
+	Restructured              bool          `gorm:"not null;default:false;index" json:"restructured"`
+	RestructuredOn            *time.Time    `gorm:"type:date" json:"restructured_on,omitempty"`
+	LoanPayments              []LoanPayment `gorm:"foreignKey:LoanID;constraint:OnDelete:CASCADE" json:"loan_payments,omitempty"`
+	CreatedAt                 time.Time     `json:"created_at"`
+}
+
+// LoanStatusChange records one step of a loan through its application and
+// life.
+type LoanStatusChange struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	LoanID     uint      `gorm:"not null;index" json:"loan_id"`
+	FromStatus string    `gorm:"not null" json:"from_status"`
+	ToStatus   string    `gorm:"not null" json:"to_status"`
+	Reason     string    `json:"reason,omitempty"`
+	Note       string    `json:"note,omitempty"`
+	ChangedBy  string    `json:"changed_by,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
 }
 
 type LoanPayment struct {
-	ID          uint      `gorm:"primaryKey" json:"id"`
-	LoanID      uint      `gorm:"not null;index" json:"loan_id"`
-	Loan        Loan      `gorm:"foreignKey:LoanID;constraint:OnDelete:CASCADE" json:"loan,omitempty"`
-	Amount      float64   `gorm:"not null" json:"amount"`
-	PaymentDate time.Time `gorm:"not null" json:"payment_date"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID             uint        `gorm:"primaryKey" json:"id"`
+	LoanID         uint        `gorm:"not null;index" json:"loan_id"`
+	Loan           Loan        `gorm:"foreignKey:LoanID;constraint:OnDelete:CASCADE" json:"loan,omitempty"`
+	Amount         money.Money `gorm:"not null" json:"amount"`
+	PrincipalPaid  money.Money `gorm:"not null;default:0" json:"principal_paid"`
+	InterestPaid   money.Money `gorm:"not null;default:0" json:"interest_paid"`
+	ChargesPaid    money.Money `gorm:"not null;default:0" json:"charges_paid"`
+	PaymentDate    time.Time   `gorm:"not null" json:"payment_date"`
+	JournalEntryID *uint       `gorm:"index" json:"journal_entry_id,omitempty"`
+	TransactionID  *uint       `gorm:"index" json:"transaction_id,omitempty"`
+	CreatedAt      time.Time   `json:"created_at"`
 }
@@ -0,0 +1,235 @@
+// Package money provides an exact fixed-point currency amount.
+//
+// Amounts are held as integer minor units (cents) and stored in Postgres as
+// BIGINT. In JSON they are encoded as decimal strings such as "1250.50".
+//
+// Rounding rules:
+//   - Parsing never rounds. Input with more than two fractional digits is
+//     rejected, so a client cannot create fractions of a cent.
+//   - Derived amounts (interest, fees, installments) are computed with exact
+//     rational arithmetic and rounded once to the nearest minor unit, with
+//     ties rounded half away from zero.
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Money is an amount of currency in minor units.
+type Money int64
+
+// Scale is the number of minor units in one major unit.
+const Scale = 100
+
+const Zero Money = 0
+
+var ErrInvalid = errors.New("invalid money amount")
+
+func FromMinorUnits(v int64) Money {
+	return Money(v)
+}
+
+func (m Money) MinorUnits() int64 {
+	return int64(m)
+}
+
+// Parse reads a decimal string such as "12", "-3.5" or "1000.25".
+func Parse(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, ErrInvalid
+	}
+	neg := false
+	switch s[0] {
+	case '-':
+		neg = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+	whole, frac, hasFrac := strings.Cut(s, ".")
+	if whole == "" && frac == "" {
+		return 0, ErrInvalid
+	}
+	if hasFrac && frac == "" {
+		return 0, ErrInvalid
+	}
+	if len(frac) > 2 {
+		return 0, fmt.Errorf("%w: %q has more than two decimal places", ErrInvalid, s)
+	}
+	for _, r := range whole + frac {
+		if r < '0' || r > '9' {
+			return 0, fmt.Errorf("%w: %q", ErrInvalid, s)
+		}
+	}
+	for len(frac) < 2 {
+		frac += "0"
+	}
+	if whole == "" {
+		whole = "0"
+	}
+	units, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q out of range", ErrInvalid, s)
+	}
+	if neg {
+		units = -units
+	}
+	return Money(units), nil
+}
+
+func MustParse(s string) Money {
+	m, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func (m Money) String() string {
+	units := int64(m)
+	sign := ""
+	if units < 0 {
+		sign = "-"
+		units = -units
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, units/Scale, units%Scale)
+}
+
+func (m Money) IsZero() bool     { return m == 0 }
+func (m Money) IsPositive() bool { return m > 0 }
+func (m Money) IsNegative() bool { return m < 0 }
+
+func (m Money) Neg() Money { return -m }
+
+func (m Money) Abs() Money {
+	if m < 0 {
+		return -m
+	}
+	return m
+}
+
+// Rat returns the amount in major units as an exact rational.
+func (m Money) Rat() *big.Rat {
+	return big.NewRat(int64(m), Scale)
+}
+
+// FromRat converts an amount in major units to Money, rounding half away
+// from zero. It fails with ErrInvalid when the result does not fit.
+func FromRat(r *big.Rat) (Money, error) {
+	scaled := new(big.Rat).Mul(r, big.NewRat(Scale, 1))
+	num := new(big.Int).Set(scaled.Num())
+	den := scaled.Denom()
+
+	neg := num.Sign() < 0
+	num.Abs(num)
+	quo, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if rem.Lsh(rem, 1).Cmp(den) >= 0 {
+		quo.Add(quo, big.NewInt(1))
+	}
+	if neg {
+		quo.Neg(quo)
+	}
+	if !quo.IsInt64() {
+		return 0, fmt.Errorf("%w: %s is out of range", ErrInvalid, r.FloatString(2))
+	}
+	return Money(quo.Int64()), nil
+}
+
+// RateRat converts a percentage rate such as 12.5 to the exact fraction
+// 0.125. The rate is read through its shortest decimal representation, so
+// 12.1 means 121/1000 rather than the nearest binary float.
+func RateRat(percent float64) *big.Rat {
+	r, ok := new(big.Rat).SetString(strconv.FormatFloat(percent, 'f', -1, 64))
+	if !ok {
+		return new(big.Rat)
+	}
+	return r.Quo(r, big.NewRat(100, 1))
+}
+
+// MulRate returns m * percent / 100, rounded half away from zero.
+func (m Money) MulRate(percent float64) (Money, error) {
+	return FromRat(new(big.Rat).Mul(m.Rat(), RateRat(percent)))
+}
+
+func Min(a, b Money) Money {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func Max(a, b Money) Money {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func Sum(amounts ...Money) Money {
+	var total Money
+	for _, a := range amounts {
+		total += a
+	}
+	return total
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(m.String())), nil
+}
+
+// UnmarshalJSON accepts a decimal string or a bare JSON number. Numbers are
+// read from their literal text, never through float64.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	if len(s) >= 2 && s[0] == '"' {
+		unquoted, err := strconv.Unquote(s)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalid, s)
+		}
+		s = unquoted
+	}
+	parsed, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+func (m Money) Value() (driver.Value, error) {
+	return int64(m), nil
+}
+
+func (m *Money) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*m = 0
+	case int64:
+		*m = Money(v)
+	case []byte:
+		return m.scanText(string(v))
+	case string:
+		return m.scanText(v)
+	default:
+		return fmt.Errorf("money: cannot scan %T", src)
+	}
+	return nil
+}
+
+func (m *Money) scanText(s string) error {
+	units, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("money: cannot scan %q", s)
+	}
+	*m = Money(units)
+	return nil
+}
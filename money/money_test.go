@@ -0,0 +1,268 @@
+package money
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Money
+		err  error
+	}{
+		{"12", 1200, nil},
+		{"1000.25", 100025, nil},
+		{"-3.5", -350, nil},
+		{"+3.05", 305, nil},
+		{".5", 50, nil},
+		{"-.05", -5, nil},
+		{"0", 0, nil},
+		{"  12.50\t", 1250, nil},
+		{"92233720368547758.07", math.MaxInt64, nil},
+		{"-92233720368547758.07", -math.MaxInt64, nil},
+		{"1.234", 0, ErrInvalid},
+		{"0.001", 0, ErrInvalid},
+		{"1.", 0, ErrInvalid},
+		{".", 0, ErrInvalid},
+		{"", 0, ErrInvalid},
+		{"   ", 0, ErrInvalid},
+		{"-", 0, ErrInvalid},
+		{"--1", 0, ErrInvalid},
+		{"+-1", 0, ErrInvalid},
+		{"- 1", 0, ErrInvalid},
+		{"1 000", 0, ErrInvalid},
+		{"1,000.00", 0, ErrInvalid},
+		{"1e3", 0, ErrInvalid},
+		{"abc", 0, ErrInvalid},
+		{"92233720368547758.08", 0, ErrInvalid},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if got != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("Parse(%q) = %d, %v, want %d, %v", tt.in, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		in   Money
+		want string
+	}{
+		{0, "0.00"},
+		{5, "0.05"},
+		{-5, "-0.05"},
+		{125050, "1250.50"},
+		{-100, "-1.00"},
+	}
+	for _, tt := range tests {
+		if got := tt.in.String(); got != tt.want {
+			t.Errorf("Money(%d).String() = %q, want %q", int64(tt.in), got, tt.want)
+		}
+	}
+}
+
+func TestFromRatRoundsHalfAwayFromZero(t *testing.T) {
+	tests := []struct {
+		num, den int64
+		want     Money
+	}{
+		{1, 200, 1},        // 0.005
+		{-1, 200, -1},      // -0.005
+		{3, 200, 2},        // 0.015
+		{-3, 200, -2},      // -0.015
+		{5, 200, 3},        // 0.025, where half to even would give 0.02
+		{-5, 200, -3},      // -0.025
+		{4999, 1000000, 0}, // 0.004999
+		{-4999, 1000000, 0},
+		{1, 3, 33},   // 0.333...
+		{2, 3, 67},   // 0.666...
+		{-2, 3, -67}, // -0.666...
+		{1250, 1, 125000},
+	}
+	for _, tt := range tests {
+		got, err := FromRat(big.NewRat(tt.num, tt.den))
+		if err != nil {
+			t.Fatalf("FromRat(%d/%d): %v", tt.num, tt.den, err)
+		}
+		if got != tt.want {
+			t.Errorf("FromRat(%d/%d) = %d, want %d", tt.num, tt.den, int64(got), int64(tt.want))
+		}
+	}
+}
+
+func TestFromRatFailsOnOverflow(t *testing.T) {
+	largest := new(big.Rat).SetFrac(big.NewInt(math.MaxInt64), big.NewInt(Scale))
+	if got, err := FromRat(largest); err != nil || got != math.MaxInt64 {
+		t.Fatalf("FromRat(largest) = %d, %v, want %d", int64(got), err, int64(math.MaxInt64))
+	}
+	smallest := new(big.Rat).SetFrac(big.NewInt(math.MinInt64), big.NewInt(Scale))
+	if got, err := FromRat(smallest); err != nil || got != math.MinInt64 {
+		t.Fatalf("FromRat(smallest) = %d, %v, want %d", int64(got), err, int64(math.MinInt64))
+	}
+
+	oneCent := big.NewRat(1, Scale)
+	for _, r := range []*big.Rat{
+		new(big.Rat).Add(largest, oneCent),
+		new(big.Rat).Sub(smallest, oneCent),
+		// Rounds up past the largest amount.
+		new(big.Rat).Add(largest, big.NewRat(1, 2*Scale)),
+		new(big.Rat).SetFrac(new(big.Int).Lsh(big.NewInt(1), 80), big.NewInt(1)),
+	} {
+		if got, err := FromRat(r); !errors.Is(err, ErrInvalid) {
+			t.Errorf("FromRat(%s) = %d, %v, want ErrInvalid", r.FloatString(3), int64(got), err)
+		}
+	}
+}
+
+func TestMulRate(t *testing.T) {
+	tests := []struct {
+		amount  string
+		percent float64
+		want    string
+	}{
+		{"0.50", 1, "0.01"},
+		{"-0.50", 1, "-0.01"},
+		{"1.50", 1, "0.02"},
+		{"2.50", 1, "0.03"},
+		{"-2.50", 1, "-0.03"},
+		{"0.10", 12.5, "0.01"},
+		{"-0.10", 12.5, "-0.01"},
+		{"0.49", 1, "0.00"},
+		{"100", 12.1, "12.10"},
+		{"1000", 0, "0.00"},
+		{"1000", 100, "1000.00"},
+	}
+	for _, tt := range tests {
+		got, err := MustParse(tt.amount).MulRate(tt.percent)
+		if err != nil {
+			t.Fatalf("%s.MulRate(%v): %v", tt.amount, tt.percent, err)
+		}
+		if got != MustParse(tt.want) {
+			t.Errorf("%s.MulRate(%v) = %s, want %s", tt.amount, tt.percent, got, tt.want)
+		}
+	}
+
+	if _, err := Money(math.MaxInt64).MulRate(200); !errors.Is(err, ErrInvalid) {
+		t.Errorf("MulRate past the largest amount: err = %v, want ErrInvalid", err)
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	got, err := json.Marshal(struct {
+		Amount Money `json:"amount"`
+	}{MustParse("-1250.5")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"amount":"-1250.50"}`; string(got) != want {
+		t.Errorf("json.Marshal = %s, want %s", got, want)
+	}
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Money
+		err  bool
+	}{
+		{`"1250.50"`, 125050, false},
+		{`"-0.05"`, -5, false},
+		{`" 7 "`, 700, false},
+		{`1250.5`, 125050, false},
+		{`-3`, -300, false},
+		{`0.1`, 10, false},
+		{`"1.234"`, 0, true},
+		{`1.234`, 0, true},
+		{`1e3`, 0, true},
+		{`""`, 0, true},
+		{`"abc"`, 0, true},
+		{`true`, 0, true},
+	}
+	for _, tt := range tests {
+		var got struct {
+			Amount Money `json:"amount"`
+		}
+		err := json.Unmarshal([]byte(`{"amount":`+tt.in+`}`), &got)
+		if tt.err {
+			if err == nil {
+				t.Errorf("unmarshalling %s = %s, want an error", tt.in, got.Amount)
+			}
+			continue
+		}
+		if err != nil || got.Amount != tt.want {
+			t.Errorf("unmarshalling %s = %s, %v, want %s", tt.in, got.Amount, err, tt.want)
+		}
+	}
+
+	m := MustParse("9.99")
+	if err := json.Unmarshal([]byte(`null`), &m); err != nil || m != MustParse("9.99") {
+		t.Errorf("unmarshalling null = %s, %v, want the amount left at 9.99", m, err)
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	for _, s := range []string{"0", "0.01", "-0.01", "1250.50", "-92233720368547758.07"} {
+		want := MustParse(s)
+		data, err := json.Marshal(want)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got Money
+		if err := json.Unmarshal(data, &got); err != nil || got != want {
+			t.Errorf("round trip of %s through %s = %s, %v", want, data, got, err)
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		src  interface{}
+		want Money
+		err  bool
+	}{
+		{int64(125050), 125050, false},
+		{int64(-5), -5, false},
+		{[]byte("125050"), 125050, false},
+		{"-300", -300, false},
+		{nil, 0, false},
+		{"12.50", 0, true},
+		{[]byte("abc"), 0, true},
+		{float64(1.5), 0, true},
+		{true, 0, true},
+	}
+	for _, tt := range tests {
+		got := MustParse("1")
+		err := got.Scan(tt.src)
+		if tt.err {
+			if err == nil {
+				t.Errorf("Scan(%#v) = %s, want an error", tt.src, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Scan(%#v) = %s, %v, want %s", tt.src, got, err, tt.want)
+		}
+	}
+}
+
+func TestValue(t *testing.T) {
+	var valuer driver.Valuer = MustParse("-1250.50")
+	got, err := valuer.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != int64(-125050) {
+		t.Errorf("Value = %#v, want int64(-125050)", got)
+	}
+
+	var scanned Money
+	if err := scanned.Scan(got); err != nil || scanned != MustParse("-1250.50") {
+		t.Errorf("scanning Value back = %s, %v, want -1250.50", scanned, err)
+	}
+}
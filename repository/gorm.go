@@ -0,0 +1,897 @@
+package repository
+
+import (
+	"banking-system/models"
+	"banking-system/money"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GormStore is the Postgres-backed Store.
+type GormStore struct {
+	db *gorm.DB
+}
+
+func NewGormStore(db *gorm.DB) *GormStore {
+	return &GormStore{db: db}
+}
+
+func (s *GormStore) Banks() BankRepository               { return &gormBankRepository{s.db} }
+func (s *GormStore) Branches() BranchRepository          { return &gormBranchRepository{s.db} }
+func (s *GormStore) Customers() CustomerRepository       { return &gormCustomerRepository{s.db} }
+func (s *GormStore) Accounts() AccountRepository         { return &gormAccountRepository{s.db} }
+func (s *GormStore) Transactions() TransactionRepository { return &gormTransactionRepository{s.db} }
+func (s *GormStore) Loans() LoanRepository               { return &gormLoanRepository{s.db} }
+func (s *GormStore) LoanProducts() LoanProductRepository { return &gormLoanProductRepository{s.db} }
+func (s *GormStore) Ledger() LedgerRepository            { return &gormLedgerRepository{s.db} }
+func (s *GormStore) Interest() InterestRepository        { return &gormInterestRepository{s.db} }
+func (s *GormStore) Benchmarks() BenchmarkRepository     { return &gormBenchmarkRepository{s.db} }
+func (s *GormStore) Idempotency() IdempotencyRepository  { return &gormIdempotencyRepository{s.db} }
+func (s *GormStore) APIKeys() APIKeyRepository           { return &gormAPIKeyRepository{s.db} }
+
+func (s *GormStore) Atomic(fn func(tx Store) error) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&GormStore{db: tx})
+	})
+}
+
+// translate maps gorm errors onto the repository sentinels.
+func translate(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return ErrDuplicate
+	}
+	return err
+}
+
+func forUpdate(db *gorm.DB) *gorm.DB {
+	return db.Clauses(clause.Locking{Strength: "UPDATE"})
+}
+
+func paged(q *gorm.DB, opts ListOptions) *gorm.DB {
+	if opts.Sort != "" && opts.Sort != "id" {
+		q = q.Order(clause.OrderByColumn{Column: clause.Column{Name: opts.Sort}, Desc: opts.Desc})
+	}
+	q = q.Order(clause.OrderByColumn{Column: clause.Column{Name: "id"}, Desc: opts.Desc})
+	if opts.Offset > 0 {
+		q = q.Offset(opts.Offset)
+	}
+	if opts.Limit > 0 {
+		q = q.Limit(opts.Limit)
+	}
+	return q
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// nameLike filters column by a case-insensitive substring.
+func nameLike(q *gorm.DB, column, substring string) *gorm.DB {
+	if substring == "" {
+		return q
+	}
+	return q.Where("LOWER("+column+") LIKE ?", "%"+likeEscaper.Replace(strings.ToLower(substring))+"%")
+}
+
+// customerScope is a subquery of the ids of customers at branchID and bankID,
+// whichever are set.
+func customerScope(db *gorm.DB, branchID, bankID *uint) *gorm.DB {
+	sub := db.Session(&gorm.Session{NewDB: true}).Table("customers").Select("customers.id")
+	if branchID != nil {
+		sub = sub.Where("customers.branch_id = ?", *branchID)
+	}
+	if bankID != nil {
+		sub = sub.Where("customers.branch_id IN (?)", db.Session(&gorm.Session{NewDB: true}).Table("branches").Select("id").Where("bank_id = ?", *bankID))
+	}
+	return sub
+}
+
+type gormBankRepository struct{ db *gorm.DB }
+
+func (r *gormBankRepository) Create(bank *models.Bank) error {
+	return translate(r.db.Omit(clause.Associations).Create(bank).Error)
+}
+
+func (r *gormBankRepository) Get(id uint) (*models.Bank, error) {
+	var bank models.Bank
+	if err := r.db.Preload("Branches").First(&bank, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &bank, nil
+}
+
+func (r *gormBankRepository) filtered(filter BankFilter) *gorm.DB {
+	q := r.db.Model(&models.Bank{})
+	if filter.ID != nil {
+		q = q.Where("id = ?", *filter.ID)
+	}
+	return nameLike(q, "name", filter.Name)
+}
+
+func (r *gormBankRepository) Find(filter BankFilter) ([]models.Bank, error) {
+	var banks []models.Bank
+	if err := paged(r.filtered(filter), filter.ListOptions).Find(&banks).Error; err != nil {
+		return nil, translate(err)
+	}
+	return banks, nil
+}
+
+func (r *gormBankRepository) Count(filter BankFilter) (int64, error) {
+	var count int64
+	if err := r.filtered(filter).Count(&count).Error; err != nil {
+		return 0, translate(err)
+	}
+	return count, nil
+}
+
+func (r *gormBankRepository) Update(bank *models.Bank) error {
+	return translate(r.db.Omit(clause.Associations).Save(bank).Error)
+}
+
+type gormBranchRepository struct{ db *gorm.DB }
+
+func (r *gormBranchRepository) Create(branch *models.Branch) error {
+	return translate(r.db.Omit(clause.Associations).Create(branch).Error)
+}
+
+func (r *gormBranchRepository) Get(id uint) (*models.Branch, error) {
+	var branch models.Branch
+	if err := r.db.Preload("Bank").Preload("Customers").First(&branch, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &branch, nil
+}
+
+func (r *gormBranchRepository) filtered(filter BranchFilter) *gorm.DB {
+	q := r.db.Model(&models.Branch{})
+	if filter.ID != nil {
+		q = q.Where("id = ?", *filter.ID)
+	}
+	if filter.BankID != nil {
+		q = q.Where("bank_id = ?", *filter.BankID)
+	}
+	return nameLike(q, "name", filter.Name)
+}
+
+func (r *gormBranchRepository) Find(filter BranchFilter) ([]models.Branch, error) {
+	var branches []models.Branch
+	if err := paged(r.filtered(filter), filter.ListOptions).Find(&branches).Error; err != nil {
+		return nil, translate(err)
+	}
+	return branches, nil
+}
+
+func (r *gormBranchRepository) Count(filter BranchFilter) (int64, error) {
+	var count int64
+	if err := r.filtered(filter).Count(&count).Error; err != nil {
+		return 0, translate(err)
+	}
+	return count, nil
+}
+
+func (r *gormBranchRepository) Update(branch *models.Branch) error {
+	return translate(r.db.Omit(clause.Associations).Save(branch).Error)
+}
+
+type gormCustomerRepository struct{ db *gorm.DB }
+
+func (r *gormCustomerRepository) Create(customer *models.Customer) error {
+	return translate(r.db.Omit(clause.Associations).Create(customer).Error)
+}
+
+func (r *gormCustomerRepository) Get(id uint) (*models.Customer, error) {
+	var customer models.Customer
+	err := r.db.
+		Preload("Branch").
+		Preload("CustomerAccounts.Account").
+		Preload("Loans").
+		First(&customer, id).Error
+	if err != nil {
+		return nil, translate(err)
+	}
+	return &customer, nil
+}
+
+func (r *gormCustomerRepository) filtered(filter CustomerFilter) *gorm.DB {
+	q := r.db.Model(&models.Customer{})
+	if filter.ID != nil {
+		q = q.Where("id = ?", *filter.ID)
+	}
+	if filter.BranchID != nil || filter.BankID != nil {
+		q = q.Where("id IN (?)", customerScope(r.db, filter.BranchID, filter.BankID))
+	}
+	if filter.Email != "" {
+		q = q.Where("email = ?", filter.Email)
+	}
+	return nameLike(q, "name", filter.Name)
+}
+
+func (r *gormCustomerRepository) Find(filter CustomerFilter) ([]models.Customer, error) {
+	var customers []models.Customer
+	if err := paged(r.filtered(filter), filter.ListOptions).Find(&customers).Error; err != nil {
+		return nil, translate(err)
+	}
+	return customers, nil
+}
+
+func (r *gormCustomerRepository) Count(filter CustomerFilter) (int64, error) {
+	var count int64
+	if err := r.filtered(filter).Count(&count).Error; err != nil {
+		return 0, translate(err)
+	}
+	return count, nil
+}
+
+func (r *gormCustomerRepository) Update(customer *models.Customer) error {
+	return translate(r.db.Omit(clause.Associations).Save(customer).Error)
+}
+
+type gormAccountRepository struct{ db *gorm.DB }
+
+func (r *gormAccountRepository) Create(account *models.SavingsAccount) error {
+	return translate(r.db.Omit(clause.Associations).Create(account).Error)
+}
+
+func (r *gormAccountRepository) Get(id uint) (*models.SavingsAccount, error) {
+	var account models.SavingsAccount
+	if err := r.db.Preload("CustomerAccounts.Customer").First(&account, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &account, nil
+}
+
+func (r *gormAccountRepository) GetForUpdate(id uint) (*models.SavingsAccount, error) {
+	var account models.SavingsAccount
+	if err := forUpdate(r.db).First(&account, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &account, nil
+}
+
+func (r *gormAccountRepository) Update(account *models.SavingsAccount) error {
+	return translate(r.db.Omit(clause.Associations).Save(account).Error)
+}
+
+func (r *gormAccountRepository) List() ([]models.SavingsAccount, error) {
+	var accounts []models.SavingsAccount
+	if err := r.db.Order("id").Find(&accounts).Error; err != nil {
+		return nil, translate(err)
+	}
+	return accounts, nil
+}
+
+func (r *gormAccountRepository) filtered(filter AccountFilter) *gorm.DB {
+	q := r.db.Model(&models.SavingsAccount{})
+	if filter.CustomerID != nil || filter.BranchID != nil || filter.BankID != nil {
+		holders := r.db.Session(&gorm.Session{NewDB: true}).Table("customer_accounts").Select("account_id")
+		if filter.CustomerID != nil {
+			holders = holders.Where("customer_id = ?", *filter.CustomerID)
+		}
+		if filter.BranchID != nil || filter.BankID != nil {
+			holders = holders.Where("customer_id IN (?)", customerScope(r.db, filter.BranchID, filter.BankID))
+		}
+		q = q.Where("id IN (?)", holders)
+	}
+	if filter.MinBalance != nil {
+		q = q.Where("balance >= ?", *filter.MinBalance)
+	}
+	if filter.MaxBalance != nil {
+		q = q.Where("balance <= ?", *filter.MaxBalance)
+	}
+	if filter.Status != "" {
+		q = q.Where("status = ?", filter.Status)
+	}
+	return q
+}
+
+func (r *gormAccountRepository) Find(filter AccountFilter) ([]models.SavingsAccount, error) {
+	var accounts []models.SavingsAccount
+	if err := paged(r.filtered(filter), filter.ListOptions).Find(&accounts).Error; err != nil {
+		return nil, translate(err)
+	}
+	return accounts, nil
+}
+
+func (r *gormAccountRepository) Count(filter AccountFilter) (int64, error) {
+	var count int64
+	if err := r.filtered(filter).Count(&count).Error; err != nil {
+		return 0, translate(err)
+	}
+	return count, nil
+}
+
+func (r *gormAccountRepository) AddHolder(holder *models.CustomerAccount) error {
+	return translate(r.db.Omit(clause.Associations).Create(holder).Error)
+}
+
+func (r *gormAccountRepository) FindHolder(accountID, customerID uint) (*models.CustomerAccount, error) {
+	var holder models.CustomerAccount
+	err := r.db.Where("account_id = ? AND customer_id = ?", accountID, customerID).First(&holder).Error
+	if err != nil {
+		return nil, translate(err)
+	}
+	return &holder, nil
+}
+
+func (r *gormAccountRepository) ListHolders(accountID uint) ([]models.CustomerAccount, error) {
+	var holders []models.CustomerAccount
+	err := r.db.Where("account_id = ?", accountID).Preload("Customer").Order("id").Find(&holders).Error
+	if err != nil {
+		return nil, translate(err)
+	}
+	return holders, nil
+}
+
+func (r *gormAccountRepository) AddStatusChange(change *models.AccountStatusChange) error {
+	return translate(r.db.Create(change).Error)
+}
+
+func (r *gormAccountRepository) StatusHistory(accountID uint) ([]models.AccountStatusChange, error) {
+	var changes []models.AccountStatusChange
+	if err := r.db.Where("account_id = ?", accountID).Order("id").Find(&changes).Error; err != nil {
+		return nil, translate(err)
+	}
+	return changes, nil
+}
+
+type gormTransactionRepository struct{ db *gorm.DB }
+
+func (r *gormTransactionRepository) Create(transaction *models.Transaction) error {
+	return translate(r.db.Omit(clause.Associations).Create(transaction).Error)
+}
+
+func (r *gormTransactionRepository) filtered(filter TransactionFilter) *gorm.DB {
+	q := r.db.Model(&models.Transaction{}).Where("account_id = ?", filter.AccountID)
+	if len(filter.Types) > 0 {
+		q = q.Where("type IN ?", filter.Types)
+	}
+	if filter.MinAmount != nil {
+		q = q.Where("amount >= ?", *filter.MinAmount)
+	}
+	if filter.MaxAmount != nil {
+		q = q.Where("amount <= ?", *filter.MaxAmount)
+	}
+	if filter.From != nil {
+		q = q.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		q = q.Where("created_at < ?", *filter.To)
+	}
+	return q
+}
+
+func (r *gormTransactionRepository) Find(filter TransactionFilter) ([]models.Transaction, error) {
+	q := r.filtered(filter)
+	if filter.After != nil {
+		q = q.Where("(created_at, id) < (?, ?)", filter.After.CreatedAt, filter.After.ID)
+	}
+	if filter.Limit > 0 {
+		q = q.Limit(filter.Limit)
+	}
+	var transactions []models.Transaction
+	if err := q.Order("created_at DESC, id DESC").Find(&transactions).Error; err != nil {
+		return nil, translate(err)
+	}
+	return transactions, nil
+}
+
+func (r *gormTransactionRepository) Count(filter TransactionFilter) (int64, error) {
+	var count int64
+	if err := r.filtered(filter).Count(&count).Error; err != nil {
+		return 0, translate(err)
+	}
+	return count, nil
+}
+
+func (r *gormTransactionRepository) ListByTransfer(transferID string) ([]models.Transaction, error) {
+	var transactions []models.Transaction
+	if err := r.db.Where("transfer_id = ?", transferID).Order("id").Find(&transactions).Error; err != nil {
+		return nil, translate(err)
+	}
+	return transactions, nil
+}
+
+type gormLoanRepository struct{ db *gorm.DB }
+
+func (r *gormLoanRepository) Create(loan *models.Loan) error {
+	return translate(r.db.Omit(clause.Associations).Create(loan).Error)
+}
+
+func (r *gormLoanRepository) Get(id uint) (*models.Loan, error) {
+	var loan models.Loan
+	if err := r.db.Preload("Customer").Preload("LoanPayments").First(&loan, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &loan, nil
+}
+
+func (r *gormLoanRepository) GetForUpdate(id uint) (*models.Loan, error) {
+	var loan models.Loan
+	if err := forUpdate(r.db).First(&loan, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &loan, nil
+}
+
+func (r *gormLoanRepository) Update(loan *models.Loan) error {
+	return translate(r.db.Omit(clause.Associations).Save(loan).Error)
+}
+
+func (r *gormLoanRepository) List() ([]models.Loan, error) {
+	var loans []models.Loan
+	if err := r.db.Order("id").Find(&loans).Error; err != nil {
+		return nil, translate(err)
+	}
+	return loans, nil
+}
+
+func (r *gormLoanRepository) filtered(filter LoanFilter) *gorm.DB {
+	q := r.db.Model(&models.Loan{})
+	if filter.CustomerID != nil {
+		q = q.Where("customer_id = ?", *filter.CustomerID)
+	}
+	if filter.BranchID != nil || filter.BankID != nil {
+		q = q.Where("customer_id IN (?)", customerScope(r.db, filter.BranchID, filter.BankID))
+	}
+	if filter.Status != "" {
+		q = q.Where("status = ?", filter.Status)
+	}
+	if filter.LoanType != "" {
+		q = q.Where("loan_type = ?", filter.LoanType)
+	}
+	if filter.Bucket != "" {
+		q = q.Where("delinquency_bucket = ?", filter.Bucket)
+	}
+	if filter.Restructured != nil {
+		q = q.Where("restructured = ?", *filter.Restructured)
+	}
+	if filter.ResetThrough != nil {
+		q = q.Where("next_reset_date <= ?", *filter.ResetThrough)
+	}
+	return q
+}
+
+func (r *gormLoanRepository) Find(filter LoanFilter) ([]models.Loan, error) {
+	var loans []models.Loan
+	if err := paged(r.filtered(filter), filter.ListOptions).Find(&loans).Error; err != nil {
+		return nil, translate(err)
+	}
+	return loans, nil
+}
+
+func (r *gormLoanRepository) Count(filter LoanFilter) (int64, error) {
+	var count int64
+	if err := r.filtered(filter).Count(&count).Error; err != nil {
+		return 0, translate(err)
+	}
+	return count, nil
+}
+
+func (r *gormLoanRepository) ListByCustomer(customerID uint) ([]models.Loan, error) {
+	var loans []models.Loan
+	err := r.db.Where("customer_id = ?", customerID).Preload("LoanPayments").Order("id").Find(&loans).Error
+	if err != nil {
+		return nil, translate(err)
+	}
+	return loans, nil
+}
+
+func (r *gormLoanRepository) CreatePayment(payment *models.LoanPayment) error {
+	return translate(r.db.Omit(clause.Associations).Create(payment).Error)
+}
+
+func (r *gormLoanRepository) ListPayments(loanID uint) ([]models.LoanPayment, error) {
+	var payments []models.LoanPayment
+	err := r.db.Where("loan_id = ?", loanID).Order("payment_date DESC, id DESC").Find(&payments).Error
+	if err != nil {
+		return nil, translate(err)
+	}
+	return payments, nil
+}
+
+func (r *gormLoanRepository) CreateInstallment(installment *models.LoanInstallment) error {
+	return translate(r.db.Create(installment).Error)
+}
+
+func (r *gormLoanRepository) ListInstallments(loanID uint) ([]models.LoanInstallment, error) {
+	var installments []models.LoanInstallment
+	if err := r.db.Where("loan_id = ?", loanID).Order("number").Find(&installments).Error; err != nil {
+		return nil, translate(err)
+	}
+	return installments, nil
+}
+
+func (r *gormLoanRepository) UpdateInstallment(installment *models.LoanInstallment) error {
+	return translate(r.db.Save(installment).Error)
+}
+
+func (r *gormLoanRepository) DeleteInstallment(id uint) error {
+	result := r.db.Delete(&models.LoanInstallment{}, id)
+	if result.Error != nil {
+		return translate(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormLoanRepository) CreateAllocation(allocation *models.LoanPaymentAllocation) error {
+	return translate(r.db.Create(allocation).Error)
+}
+
+func (r *gormLoanRepository) AddStatusChange(change *models.LoanStatusChange) error {
+	return translate(r.db.Create(change).Error)
+}
+
+func (r *gormLoanRepository) CreateCollection(collection *models.LoanCollection) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(collection)
+	if result.Error != nil {
+		return false, translate(result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *gormLoanRepository) ListCollections(loanID uint) ([]models.LoanCollection, error) {
+	var collections []models.LoanCollection
+	if err := r.db.Where("loan_id = ?", loanID).Order("id").Find(&collections).Error; err != nil {
+		return nil, translate(err)
+	}
+	return collections, nil
+}
+
+func (r *gormLoanRepository) AddClassification(classification *models.LoanClassification) error {
+	return translate(r.db.Create(classification).Error)
+}
+
+func (r *gormLoanRepository) ClassificationHistory(loanID uint) ([]models.LoanClassification, error) {
+	var classifications []models.LoanClassification
+	if err := r.db.Where("loan_id = ?", loanID).Order("id").Find(&classifications).Error; err != nil {
+		return nil, translate(err)
+	}
+	return classifications, nil
+}
+
+func (r *gormLoanRepository) AddRateChange(change *models.LoanRateChange) error {
+	return translate(r.db.Create(change).Error)
+}
+
+func (r *gormLoanRepository) RateHistory(loanID uint) ([]models.LoanRateChange, error) {
+	var changes []models.LoanRateChange
+	if err := r.db.Where("loan_id = ?", loanID).Order("id").Find(&changes).Error; err != nil {
+		return nil, translate(err)
+	}
+	return changes, nil
+}
+
+func (r *gormLoanRepository) AddRestructuring(restructuring *models.LoanRestructuring) error {
+	return translate(r.db.Create(restructuring).Error)
+}
+
+func (r *gormLoanRepository) ListRestructurings(loanID uint) ([]models.LoanRestructuring, error) {
+	var restructurings []models.LoanRestructuring
+	if err := r.db.Where("loan_id = ?", loanID).Order("id").Find(&restructurings).Error; err != nil {
+		return nil, translate(err)
+	}
+	return restructurings, nil
+}
+
+func (r *gormLoanRepository) ArchiveSchedule(snapshots []models.LoanScheduleSnapshot) error {
+	if len(snapshots) == 0 {
+		return nil
+	}
+	return translate(r.db.Create(&snapshots).Error)
+}
+
+func (r *gormLoanRepository) ListSnapshots(loanID uint) ([]models.LoanScheduleSnapshot, error) {
+	var snapshots []models.LoanScheduleSnapshot
+	if err := r.db.Where("loan_id = ?", loanID).Order("version").Order("number").Find(&snapshots).Error; err != nil {
+		return nil, translate(err)
+	}
+	return snapshots, nil
+}
+
+func (r *gormLoanRepository) CreateWriteOff(writeOff *models.LoanWriteOff) error {
+	return translate(r.db.Create(writeOff).Error)
+}
+
+func (r *gormLoanRepository) GetWriteOff(loanID uint) (*models.LoanWriteOff, error) {
+	var writeOff models.LoanWriteOff
+	if err := r.db.Where("loan_id = ?", loanID).First(&writeOff).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &writeOff, nil
+}
+
+func (r *gormLoanRepository) UpdateWriteOff(writeOff *models.LoanWriteOff) error {
+	return translate(r.db.Save(writeOff).Error)
+}
+
+func (r *gormLoanRepository) FindWriteOffs(filter LoanFilter) ([]models.LoanWriteOff, error) {
+	var writeOffs []models.LoanWriteOff
+	err := r.db.Where("loan_id IN (?)", r.filtered(filter).Select("id")).Order("id").Find(&writeOffs).Error
+	if err != nil {
+		return nil, translate(err)
+	}
+	return writeOffs, nil
+}
+
+func (r *gormLoanRepository) AddRecovery(recovery *models.LoanRecovery) error {
+	return translate(r.db.Create(recovery).Error)
+}
+
+func (r *gormLoanRepository) ListRecoveries(loanID uint) ([]models.LoanRecovery, error) {
+	var recoveries []models.LoanRecovery
+	if err := r.db.Where("loan_id = ?", loanID).Order("id").Find(&recoveries).Error; err != nil {
+		return nil, translate(err)
+	}
+	return recoveries, nil
+}
+
+func (r *gormLoanRepository) StatusHistory(loanID uint) ([]models.LoanStatusChange, error) {
+	var changes []models.LoanStatusChange
+	if err := r.db.Where("loan_id = ?", loanID).Order("id").Find(&changes).Error; err != nil {
+		return nil, translate(err)
+	}
+	return changes, nil
+}
+
+type gormLoanProductRepository struct{ db *gorm.DB }
+
+func (r *gormLoanProductRepository) Create(product *models.LoanProduct) error {
+	return translate(r.db.Create(product).Error)
+}
+
+func (r *gormLoanProductRepository) Get(id uint) (*models.LoanProduct, error) {
+	var product models.LoanProduct
+	if err := r.db.First(&product, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &product, nil
+}
+
+func (r *gormLoanProductRepository) Update(product *models.LoanProduct) error {
+	return translate(r.db.Save(product).Error)
+}
+
+func (r *gormLoanProductRepository) filtered(filter LoanProductFilter) *gorm.DB {
+	q := r.db.Model(&models.LoanProduct{})
+	if filter.BankID != nil {
+		q = q.Where("bank_id = ?", *filter.BankID)
+	}
+	if filter.LoanType != "" {
+		q = q.Where("loan_type = ?", filter.LoanType)
+	}
+	if filter.Active != nil {
+		q = q.Where("active = ?", *filter.Active)
+	}
+	return q
+}
+
+func (r *gormLoanProductRepository) Find(filter LoanProductFilter) ([]models.LoanProduct, error) {
+	var products []models.LoanProduct
+	if err := paged(r.filtered(filter), filter.ListOptions).Find(&products).Error; err != nil {
+		return nil, translate(err)
+	}
+	return products, nil
+}
+
+func (r *gormLoanProductRepository) Count(filter LoanProductFilter) (int64, error) {
+	var count int64
+	if err := r.filtered(filter).Count(&count).Error; err != nil {
+		return 0, translate(err)
+	}
+	return count, nil
+}
+
+type gormBenchmarkRepository struct{ db *gorm.DB }
+
+func (r *gormBenchmarkRepository) Create(benchmark *models.Benchmark) error {
+	return translate(r.db.Create(benchmark).Error)
+}
+
+func (r *gormBenchmarkRepository) Get(id uint) (*models.Benchmark, error) {
+	var benchmark models.Benchmark
+	if err := r.db.First(&benchmark, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &benchmark, nil
+}
+
+func (r *gormBenchmarkRepository) ListByBank(bankID uint) ([]models.Benchmark, error) {
+	var benchmarks []models.Benchmark
+	if err := r.db.Where("bank_id = ?", bankID).Order("code").Find(&benchmarks).Error; err != nil {
+		return nil, translate(err)
+	}
+	return benchmarks, nil
+}
+
+func (r *gormBenchmarkRepository) AddRate(rate *models.BenchmarkRate) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(rate)
+	if result.Error != nil {
+		return false, translate(result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *gormBenchmarkRepository) ListRates(benchmarkID uint) ([]models.BenchmarkRate, error) {
+	var rates []models.BenchmarkRate
+	if err := r.db.Where("benchmark_id = ?", benchmarkID).Order("effective_date").Find(&rates).Error; err != nil {
+		return nil, translate(err)
+	}
+	return rates, nil
+}
+
+func (r *gormBenchmarkRepository) RateAsOf(benchmarkID uint, date time.Time) (*models.BenchmarkRate, error) {
+	var rate models.BenchmarkRate
+	err := r.db.Where("benchmark_id = ? AND effective_date <= ?", benchmarkID, date).
+		Order("effective_date DESC").First(&rate).Error
+	if err != nil {
+		return nil, translate(err)
+	}
+	return &rate, nil
+}
+
+type gormLedgerRepository struct{ db *gorm.DB }
+
+// EnsureAccount inserts with ON CONFLICT DO NOTHING so that callers racing on
+// the same code all end up with the single committed row.
+func (r *gormLedgerRepository) EnsureAccount(account models.LedgerAccount) (*models.LedgerAccount, error) {
+	result := r.db.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "code"}}, DoNothing: true}).Create(&account)
+	if result.Error != nil {
+		return nil, translate(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return r.GetAccountByCode(account.Code)
+	}
+	return &account, nil
+}
+
+func (r *gormLedgerRepository) GetAccountByCode(code string) (*models.LedgerAccount, error) {
+	var account models.LedgerAccount
+	if err := r.db.Where("code = ?", code).First(&account).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &account, nil
+}
+
+func (r *gormLedgerRepository) CreateEntry(entry *models.JournalEntry) error {
+	return translate(r.db.Create(entry).Error)
+}
+
+func (r *gormLedgerRepository) GetEntry(id uint) (*models.JournalEntry, error) {
+	var entry models.JournalEntry
+	if err := r.db.Preload("Postings.LedgerAccount").First(&entry, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &entry, nil
+}
+
+func (r *gormLedgerRepository) Balance(ledgerAccountID uint) (money.Money, error) {
+	var total int64
+	err := r.db.Model(&models.Posting{}).
+		Where("ledger_account_id = ?", ledgerAccountID).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&total).Error
+	if err != nil {
+		return 0, translate(err)
+	}
+	return money.FromMinorUnits(total), nil
+}
+
+func (r *gormLedgerRepository) BalanceAsOf(ledgerAccountID uint, at time.Time) (money.Money, error) {
+	var total int64
+	err := r.db.Model(&models.Posting{}).
+		Where("ledger_account_id = ? AND created_at < ?", ledgerAccountID, at).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&total).Error
+	if err != nil {
+		return 0, translate(err)
+	}
+	return money.FromMinorUnits(total), nil
+}
+
+type gormInterestRepository struct{ db *gorm.DB }
+
+func (r *gormInterestRepository) CreateAccrual(accrual *models.InterestAccrual) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(accrual)
+	if result.Error != nil {
+		return false, translate(result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *gormInterestRepository) FindAccruals(filter AccrualFilter) ([]models.InterestAccrual, error) {
+	q := r.db.Model(&models.InterestAccrual{})
+	if filter.AccountID != nil {
+		q = q.Where("account_id = ?", *filter.AccountID)
+	}
+	if filter.Unposted {
+		q = q.Where("posted_at IS NULL")
+	}
+	if filter.Through != nil {
+		q = q.Where("business_date <= ?", *filter.Through)
+	}
+	var accruals []models.InterestAccrual
+	if err := q.Order("account_id, business_date").Find(&accruals).Error; err != nil {
+		return nil, translate(err)
+	}
+	return accruals, nil
+}
+
+func (r *gormInterestRepository) MarkPosted(accrualIDs []uint, transactionID *uint, postedAt time.Time) error {
+	return translate(r.db.Model(&models.InterestAccrual{}).Where("id IN ?", accrualIDs).Updates(map[string]interface{}{
+		"transaction_id": transactionID,
+		"posted_at":      postedAt,
+	}).Error)
+}
+
+type gormIdempotencyRepository struct{ db *gorm.DB }
+
+func (r *gormIdempotencyRepository) Reserve(record *models.IdempotencyRecord) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+	if result.Error != nil {
+		return false, translate(result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *gormIdempotencyRepository) Get(key string) (*models.IdempotencyRecord, error) {
+	var record models.IdempotencyRecord
+	if err := r.db.First(&record, "key = ?", key).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &record, nil
+}
+
+func (r *gormIdempotencyRepository) Complete(key string, statusCode int, responseBody []byte) error {
+	return translate(r.db.Model(&models.IdempotencyRecord{}).Where("key = ?", key).Updates(map[string]interface{}{
+		"status_code":   statusCode,
+		"response_body": responseBody,
+	}).Error)
+}
+
+func (r *gormIdempotencyRepository) Release(key string) error {
+	return translate(r.db.Delete(&models.IdempotencyRecord{}, "key = ?", key).Error)
+}
+
+type gormAPIKeyRepository struct{ db *gorm.DB }
+
+func (r *gormAPIKeyRepository) Create(key *models.APIKey) error {
+	return translate(r.db.Create(key).Error)
+}
+
+func (r *gormAPIKeyRepository) Get(id uint) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := r.db.First(&key, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &key, nil
+}
+
+func (r *gormAPIKeyRepository) GetByHash(keyHash string) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := r.db.Where("key_hash = ?", keyHash).First(&key).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &key, nil
+}
+
+func (r *gormAPIKeyRepository) Update(key *models.APIKey) error {
+	return translate(r.db.Save(key).Error)
+}
+
+func (r *gormAPIKeyRepository) List() ([]models.APIKey, error) {
+	var keys []models.APIKey
+	if err := r.db.Order("id").Find(&keys).Error; err != nil {
+		return nil, translate(err)
+	}
+	return keys, nil
+}
@@ -0,0 +1,1524 @@
+package repository
+
+import (
+	"banking-system/models"
+	"banking-system/money"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type memoryState struct {
+	banks           *table[models.Bank]
+	branches        *table[models.Branch]
+	customers       *table[models.Customer]
+	accounts        *table[models.SavingsAccount]
+	holders         *table[models.CustomerAccount]
+	statusChanges   *table[models.AccountStatusChange]
+	transactions    *table[models.Transaction]
+	loans           *table[models.Loan]
+	loanProducts    *table[models.LoanProduct]
+	payments        *table[models.LoanPayment]
+	installments    *table[models.LoanInstallment]
+	allocations     *table[models.LoanPaymentAllocation]
+	loanChanges     *table[models.LoanStatusChange]
+	collections     *table[models.LoanCollection]
+	classifications *table[models.LoanClassification]
+	rateChanges     *table[models.LoanRateChange]
+	restructurings  *table[models.LoanRestructuring]
+	snapshots       *table[models.LoanScheduleSnapshot]
+	writeOffs       *table[models.LoanWriteOff]
+	recoveries      *table[models.LoanRecovery]
+	ledgerAccounts  *table[models.LedgerAccount]
+	entries         *table[models.JournalEntry]
+	postings        *table[models.Posting]
+	accruals        *table[models.InterestAccrual]
+	benchmarks      *table[models.Benchmark]
+	benchmarkRates  *table[models.BenchmarkRate]
+	idempotency     *layer[string, models.IdempotencyRecord]
+	apiKeys         *table[models.APIKey]
+}
+
+func newMemoryState() *memoryState {
+	return &memoryState{
+		banks:           newTable[models.Bank](),
+		branches:        newTable[models.Branch](),
+		customers:       newTable[models.Customer](),
+		accounts:        newTable[models.SavingsAccount](),
+		holders:         newTable[models.CustomerAccount](),
+		statusChanges:   newTable[models.AccountStatusChange](),
+		transactions:    newTable[models.Transaction](),
+		loans:           newTable[models.Loan](),
+		loanProducts:    newTable[models.LoanProduct](),
+		payments:        newTable[models.LoanPayment](),
+		installments:    newTable[models.LoanInstallment](),
+		allocations:     newTable[models.LoanPaymentAllocation](),
+		loanChanges:     newTable[models.LoanStatusChange](),
+		collections:     newTable[models.LoanCollection](),
+		classifications: newTable[models.LoanClassification](),
+		rateChanges:     newTable[models.LoanRateChange](),
+		restructurings:  newTable[models.LoanRestructuring](),
+		snapshots:       newTable[models.LoanScheduleSnapshot](),
+		writeOffs:       newTable[models.LoanWriteOff](),
+		recoveries:      newTable[models.LoanRecovery](),
+		ledgerAccounts:  newTable[models.LedgerAccount](),
+		entries:         newTable[models.JournalEntry](),
+		postings:        newTable[models.Posting](),
+		accruals:        newTable[models.InterestAccrual](),
+		benchmarks:      newTable[models.Benchmark](),
+		benchmarkRates:  newTable[models.BenchmarkRate](),
+		idempotency:     newLayer[string, models.IdempotencyRecord](),
+		apiKeys:         newTable[models.APIKey](),
+	}
+}
+
+// fork starts a transaction on s. Writes to the returned state stay in it
+// until commit applies them to s.
+func (s *memoryState) fork() (tx *memoryState, commit func()) {
+	var commits []func()
+	tx = &memoryState{
+		banks:           forkTable(s.banks, &commits),
+		branches:        forkTable(s.branches, &commits),
+		customers:       forkTable(s.customers, &commits),
+		accounts:        forkTable(s.accounts, &commits),
+		holders:         forkTable(s.holders, &commits),
+		statusChanges:   forkTable(s.statusChanges, &commits),
+		transactions:    forkTable(s.transactions, &commits),
+		loans:           forkTable(s.loans, &commits),
+		loanProducts:    forkTable(s.loanProducts, &commits),
+		payments:        forkTable(s.payments, &commits),
+		installments:    forkTable(s.installments, &commits),
+		allocations:     forkTable(s.allocations, &commits),
+		loanChanges:     forkTable(s.loanChanges, &commits),
+		collections:     forkTable(s.collections, &commits),
+		classifications: forkTable(s.classifications, &commits),
+		rateChanges:     forkTable(s.rateChanges, &commits),
+		restructurings:  forkTable(s.restructurings, &commits),
+		snapshots:       forkTable(s.snapshots, &commits),
+		writeOffs:       forkTable(s.writeOffs, &commits),
+		recoveries:      forkTable(s.recoveries, &commits),
+		ledgerAccounts:  forkTable(s.ledgerAccounts, &commits),
+		entries:         forkTable(s.entries, &commits),
+		postings:        forkTable(s.postings, &commits),
+		accruals:        forkTable(s.accruals, &commits),
+		benchmarks:      forkTable(s.benchmarks, &commits),
+		benchmarkRates:  forkTable(s.benchmarkRates, &commits),
+		idempotency:     forkLayer(s.idempotency, &commits),
+		apiKeys:         forkTable(s.apiKeys, &commits),
+	}
+	return tx, func() {
+		for _, c := range commits {
+			c()
+		}
+	}
+}
+
+func forkTable[T any](t *table[T], commits *[]func()) *table[T] {
+	tx := t.fork()
+	*commits = append(*commits, func() { tx.commit(t) })
+	return tx
+}
+
+func forkLayer[K comparable, V any](l *layer[K, V], commits *[]func()) *layer[K, V] {
+	tx := l.fork()
+	*commits = append(*commits, tx.commit)
+	return tx
+}
+
+// MemoryStore is an in-memory Store for tests. Transactions are serialized:
+// Atomic holds a store-wide lock and works on a fork of the state whose
+// writes are applied only when fn succeeds, which gives the same isolation
+// and rollback behaviour as the row locks in GormStore.
+type MemoryStore struct {
+	mu    *sync.Mutex
+	state *memoryState
+	inTx  bool
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{mu: &sync.Mutex{}, state: newMemoryState()}
+}
+
+func (s *MemoryStore) Atomic(fn func(tx Store) error) error {
+	if !s.inTx {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+	}
+	tx, commit := s.state.fork()
+	if err := fn(&MemoryStore{mu: s.mu, state: tx, inTx: true}); err != nil {
+		return err
+	}
+	commit()
+	return nil
+}
+
+// with runs fn against the committed state, or against the transaction's own
+// fork when called inside Atomic.
+func (s *MemoryStore) with(fn func(st *memoryState) error) error {
+	if !s.inTx {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+	}
+	return fn(s.state)
+}
+
+func (s *MemoryStore) Banks() BankRepository               { return &memoryBankRepository{s} }
+func (s *MemoryStore) Branches() BranchRepository          { return &memoryBranchRepository{s} }
+func (s *MemoryStore) Customers() CustomerRepository       { return &memoryCustomerRepository{s} }
+func (s *MemoryStore) Accounts() AccountRepository         { return &memoryAccountRepository{s} }
+func (s *MemoryStore) Transactions() TransactionRepository { return &memoryTransactionRepository{s} }
+func (s *MemoryStore) Loans() LoanRepository               { return &memoryLoanRepository{s} }
+func (s *MemoryStore) LoanProducts() LoanProductRepository { return &memoryLoanProductRepository{s} }
+func (s *MemoryStore) Ledger() LedgerRepository            { return &memoryLedgerRepository{s} }
+func (s *MemoryStore) Interest() InterestRepository        { return &memoryInterestRepository{s} }
+func (s *MemoryStore) Benchmarks() BenchmarkRepository     { return &memoryBenchmarkRepository{s} }
+func (s *MemoryStore) Idempotency() IdempotencyRepository  { return &memoryIdempotencyRepository{s} }
+func (s *MemoryStore) APIKeys() APIKeyRepository           { return &memoryAPIKeyRepository{s} }
+
+// listed sorts and pages rows that have already been filtered.
+func listed[T any](rows []T, opts ListOptions) []T {
+	sortRows(rows, opts.Sort, opts.Desc)
+	return pageRows(rows, opts.Offset, opts.Limit)
+}
+
+func idMatches(id uint, want *uint) bool {
+	return want == nil || id == *want
+}
+
+func nameMatches(name, substring string) bool {
+	return strings.Contains(strings.ToLower(name), strings.ToLower(substring))
+}
+
+// customerInScope reports whether the customer is at branchID and bankID,
+// whichever are set.
+func customerInScope(st *memoryState, customerID uint, branchID, bankID *uint) bool {
+	customer, ok := st.customers.get(customerID)
+	if !ok || !idMatches(customer.BranchID, branchID) {
+		return false
+	}
+	if bankID == nil {
+		return true
+	}
+	branch, ok := st.branches.get(customer.BranchID)
+	return ok && branch.BankID == *bankID
+}
+
+type memoryBankRepository struct{ s *MemoryStore }
+
+func (r *memoryBankRepository) Create(bank *models.Bank) error {
+	return r.s.with(func(st *memoryState) error {
+		bank.Branches = nil
+		st.banks.insert(bank)
+		return nil
+	})
+}
+
+func (r *memoryBankRepository) Get(id uint) (*models.Bank, error) {
+	var bank models.Bank
+	err := r.s.with(func(st *memoryState) error {
+		row, ok := st.banks.get(id)
+		if !ok {
+			return ErrNotFound
+		}
+		bank = withBranches(st, row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &bank, nil
+}
+
+func matchesBank(b models.Bank, filter BankFilter) bool {
+	return idMatches(b.ID, filter.ID) && nameMatches(b.Name, filter.Name)
+}
+
+func (r *memoryBankRepository) Find(filter BankFilter) ([]models.Bank, error) {
+	var banks []models.Bank
+	err := r.s.with(func(st *memoryState) error {
+		banks = listed(st.banks.find(func(b models.Bank) bool { return matchesBank(b, filter) }), filter.ListOptions)
+		return nil
+	})
+	return banks, err
+}
+
+func (r *memoryBankRepository) Count(filter BankFilter) (int64, error) {
+	var count int64
+	err := r.s.with(func(st *memoryState) error {
+		count = int64(len(st.banks.find(func(b models.Bank) bool { return matchesBank(b, filter) })))
+		return nil
+	})
+	return count, err
+}
+
+func (r *memoryBankRepository) Update(bank *models.Bank) error {
+	return r.s.with(func(st *memoryState) error {
+		row := *bank
+		row.Branches = nil
+		if !st.banks.update(&row) {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+func withBranches(st *memoryState, bank models.Bank) models.Bank {
+	bank.Branches = st.branches.find(func(b models.Branch) bool { return b.BankID == bank.ID })
+	return bank
+}
+
+type memoryBranchRepository struct{ s *MemoryStore }
+
+func (r *memoryBranchRepository) Create(branch *models.Branch) error {
+	return r.s.with(func(st *memoryState) error {
+		if _, ok := st.banks.get(branch.BankID); !ok {
+			return ErrNotFound
+		}
+		row := stripBranch(*branch)
+		st.branches.insert(&row)
+		branch.ID, branch.CreatedAt = row.ID, row.CreatedAt
+		return nil
+	})
+}
+
+func (r *memoryBranchRepository) Get(id uint) (*models.Branch, error) {
+	var branch models.Branch
+	err := r.s.with(func(st *memoryState) error {
+		row, ok := st.branches.get(id)
+		if !ok {
+			return ErrNotFound
+		}
+		row.Bank, _ = st.banks.get(row.BankID)
+		row.Customers = st.customers.find(func(c models.Customer) bool { return c.BranchID == row.ID })
+		branch = row
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &branch, nil
+}
+
+func matchesBranch(b models.Branch, filter BranchFilter) bool {
+	return idMatches(b.ID, filter.ID) && idMatches(b.BankID, filter.BankID) && nameMatches(b.Name, filter.Name)
+}
+
+func (r *memoryBranchRepository) Find(filter BranchFilter) ([]models.Branch, error) {
+	var branches []models.Branch
+	err := r.s.with(func(st *memoryState) error {
+		branches = listed(st.branches.find(func(b models.Branch) bool { return matchesBranch(b, filter) }), filter.ListOptions)
+		return nil
+	})
+	return branches, err
+}
+
+func (r *memoryBranchRepository) Count(filter BranchFilter) (int64, error) {
+	var count int64
+	err := r.s.with(func(st *memoryState) error {
+		count = int64(len(st.branches.find(func(b models.Branch) bool { return matchesBranch(b, filter) })))
+		return nil
+	})
+	return count, err
+}
+
+func (r *memoryBranchRepository) Update(branch *models.Branch) error {
+	return r.s.with(func(st *memoryState) error {
+		row := stripBranch(*branch)
+		if !st.branches.update(&row) {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+func stripBranch(branch models.Branch) models.Branch {
+	branch.Bank = models.Bank{}
+	branch.Customers = nil
+	return branch
+}
+
+type memoryCustomerRepository struct{ s *MemoryStore }
+
+func (r *memoryCustomerRepository) Create(customer *models.Customer) error {
+	return r.s.with(func(st *memoryState) error {
+		if _, ok := st.branches.get(customer.BranchID); !ok {
+			return ErrNotFound
+		}
+		if emailTaken(st, customer.Email, 0) {
+			return ErrDuplicate
+		}
+		row := stripCustomer(*customer)
+		st.customers.insert(&row)
+		customer.ID, customer.CreatedAt = row.ID, row.CreatedAt
+		return nil
+	})
+}
+
+func (r *memoryCustomerRepository) Get(id uint) (*models.Customer, error) {
+	var customer models.Customer
+	err := r.s.with(func(st *memoryState) error {
+		row, ok := st.customers.get(id)
+		if !ok {
+			return ErrNotFound
+		}
+		row.Branch, _ = st.branches.get(row.BranchID)
+		row.CustomerAccounts = st.holders.find(func(h models.CustomerAccount) bool { return h.CustomerID == row.ID })
+		for i := range row.CustomerAccounts {
+			row.CustomerAccounts[i].Account, _ = st.accounts.get(row.CustomerAccounts[i].AccountID)
+		}
+		row.Loans = st.loans.find(func(l models.Loan) bool { return l.CustomerID == row.ID })
+		customer = row
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &customer, nil
+}
+
+func matchesCustomer(st *memoryState, c models.Customer, filter CustomerFilter) bool {
+	return idMatches(c.ID, filter.ID) &&
+		(filter.Email == "" || c.Email == filter.Email) &&
+		nameMatches(c.Name, filter.Name) &&
+		customerInScope(st, c.ID, filter.BranchID, filter.BankID)
+}
+
+func (r *memoryCustomerRepository) Find(filter CustomerFilter) ([]models.Customer, error) {
+	var customers []models.Customer
+	err := r.s.with(func(st *memoryState) error {
+		customers = listed(st.customers.find(func(c models.Customer) bool { return matchesCustomer(st, c, filter) }), filter.ListOptions)
+		return nil
+	})
+	return customers, err
+}
+
+func (r *memoryCustomerRepository) Count(filter CustomerFilter) (int64, error) {
+	var count int64
+	err := r.s.with(func(st *memoryState) error {
+		count = int64(len(st.customers.find(func(c models.Customer) bool { return matchesCustomer(st, c, filter) })))
+		return nil
+	})
+	return count, err
+}
+
+func (r *memoryCustomerRepository) Update(customer *models.Customer) error {
+	return r.s.with(func(st *memoryState) error {
+		if emailTaken(st, customer.Email, customer.ID) {
+			return ErrDuplicate
+		}
+		row := stripCustomer(*customer)
+		if !st.customers.update(&row) {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+func emailTaken(st *memoryState, email string, exceptID uint) bool {
+	_, taken := st.customers.first(func(c models.Customer) bool {
+		return c.Email == email && c.ID != exceptID
+	})
+	return taken
+}
+
+func stripCustomer(customer models.Customer) models.Customer {
+	customer.Branch = models.Branch{}
+	customer.CustomerAccounts = nil
+	customer.Loans = nil
+	return customer
+}
+
+type memoryAccountRepository struct{ s *MemoryStore }
+
+func (r *memoryAccountRepository) Create(account *models.SavingsAccount) error {
+	return r.s.with(func(st *memoryState) error {
+		row := stripAccount(*account)
+		st.accounts.insert(&row)
+		account.ID, account.CreatedAt = row.ID, row.CreatedAt
+		return nil
+	})
+}
+
+func (r *memoryAccountRepository) Get(id uint) (*models.SavingsAccount, error) {
+	var account models.SavingsAccount
+	err := r.s.with(func(st *memoryState) error {
+		row, ok := st.accounts.get(id)
+		if !ok {
+			return ErrNotFound
+		}
+		row.CustomerAccounts = holdersOf(st, row.ID)
+		account = row
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (r *memoryAccountRepository) GetForUpdate(id uint) (*models.SavingsAccount, error) {
+	var account models.SavingsAccount
+	err := r.s.with(func(st *memoryState) error {
+		row, ok := st.accounts.get(id)
+		if !ok {
+			return ErrNotFound
+		}
+		account = row
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (r *memoryAccountRepository) Update(account *models.SavingsAccount) error {
+	return r.s.with(func(st *memoryState) error {
+		row := stripAccount(*account)
+		if !st.accounts.update(&row) {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+func (r *memoryAccountRepository) List() ([]models.SavingsAccount, error) {
+	var accounts []models.SavingsAccount
+	err := r.s.with(func(st *memoryState) error {
+		accounts = st.accounts.find(nil)
+		return nil
+	})
+	return accounts, err
+}
+
+func matchesAccount(st *memoryState, a models.SavingsAccount, filter AccountFilter) bool {
+	if filter.MinBalance != nil && a.Balance < *filter.MinBalance ||
+		filter.MaxBalance != nil && a.Balance > *filter.MaxBalance ||
+		filter.Status != "" && a.Status != filter.Status {
+		return false
+	}
+	if filter.CustomerID == nil && filter.BranchID == nil && filter.BankID == nil {
+		return true
+	}
+	_, held := st.holders.first(func(h models.CustomerAccount) bool {
+		return h.AccountID == a.ID &&
+			idMatches(h.CustomerID, filter.CustomerID) &&
+			customerInScope(st, h.CustomerID, filter.BranchID, filter.BankID)
+	})
+	return held
+}
+
+func (r *memoryAccountRepository) Find(filter AccountFilter) ([]models.SavingsAccount, error) {
+	var accounts []models.SavingsAccount
+	err := r.s.with(func(st *memoryState) error {
+		accounts = listed(st.accounts.find(func(a models.SavingsAccount) bool { return matchesAccount(st, a, filter) }), filter.ListOptions)
+		return nil
+	})
+	return accounts, err
+}
+
+func (r *memoryAccountRepository) Count(filter AccountFilter) (int64, error) {
+	var count int64
+	err := r.s.with(func(st *memoryState) error {
+		count = int64(len(st.accounts.find(func(a models.SavingsAccount) bool { return matchesAccount(st, a, filter) })))
+		return nil
+	})
+	return count, err
+}
+
+func (r *memoryAccountRepository) AddHolder(holder *models.CustomerAccount) error {
+	return r.s.with(func(st *memoryState) error {
+		if _, ok := st.accounts.get(holder.AccountID); !ok {
+			return ErrNotFound
+		}
+		if _, ok := st.customers.get(holder.CustomerID); !ok {
+			return ErrNotFound
+		}
+		row := *holder
+		row.Customer = models.Customer{}
+		row.Account = models.SavingsAccount{}
+		st.holders.insert(&row)
+		holder.ID, holder.CreatedAt = row.ID, row.CreatedAt
+		return nil
+	})
+}
+
+func (r *memoryAccountRepository) FindHolder(accountID, customerID uint) (*models.CustomerAccount, error) {
+	var holder models.CustomerAccount
+	err := r.s.with(func(st *memoryState) error {
+		row, ok := st.holders.first(func(h models.CustomerAccount) bool {
+			return h.AccountID == accountID && h.CustomerID == customerID
+		})
+		if !ok {
+			return ErrNotFound
+		}
+		holder = row
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &holder, nil
+}
+
+func (r *memoryAccountRepository) ListHolders(accountID uint) ([]models.CustomerAccount, error) {
+	var holders []models.CustomerAccount
+	err := r.s.with(func(st *memoryState) error {
+		holders = holdersOf(st, accountID)
+		return nil
+	})
+	return holders, err
+}
+
+func (r *memoryAccountRepository) AddStatusChange(change *models.AccountStatusChange) error {
+	return r.s.with(func(st *memoryState) error {
+		if _, ok := st.accounts.get(change.AccountID); !ok {
+			return ErrNotFound
+		}
+		st.statusChanges.insert(change)
+		return nil
+	})
+}
+
+func (r *memoryAccountRepository) StatusHistory(accountID uint) ([]models.AccountStatusChange, error) {
+	var changes []models.AccountStatusChange
+	err := r.s.with(func(st *memoryState) error {
+		changes = st.statusChanges.find(func(c models.AccountStatusChange) bool { return c.AccountID == accountID })
+		return nil
+	})
+	return changes, err
+}
+
+func holdersOf(st *memoryState, accountID uint) []models.CustomerAccount {
+	holders := st.holders.find(func(h models.CustomerAccount) bool { return h.AccountID == accountID })
+	for i := range holders {
+		holders[i].Customer, _ = st.customers.get(holders[i].CustomerID)
+	}
+	return holders
+}
+
+func stripAccount(account models.SavingsAccount) models.SavingsAccount {
+	account.CustomerAccounts = nil
+	account.Transactions = nil
+	return account
+}
+
+type memoryTransactionRepository struct{ s *MemoryStore }
+
+func (r *memoryTransactionRepository) Create(transaction *models.Transaction) error {
+	return r.s.with(func(st *memoryState) error {
+		if _, ok := st.accounts.get(transaction.AccountID); !ok {
+			return ErrNotFound
+		}
+		row := *transaction
+		row.Account = models.SavingsAccount{}
+		st.transactions.insert(&row)
+		transaction.ID, transaction.CreatedAt = row.ID, row.CreatedAt
+		return nil
+	})
+}
+
+func matchesTransaction(t models.Transaction, filter TransactionFilter) bool {
+	if t.AccountID != filter.AccountID {
+		return false
+	}
+	if len(filter.Types) > 0 {
+		found := false
+		for _, typ := range filter.Types {
+			found = found || t.Type == typ
+		}
+		if !found {
+			return false
+		}
+	}
+	switch {
+	case filter.MinAmount != nil && t.Amount < *filter.MinAmount,
+		filter.MaxAmount != nil && t.Amount > *filter.MaxAmount,
+		filter.From != nil && t.CreatedAt.Before(*filter.From),
+		filter.To != nil && !t.CreatedAt.Before(*filter.To):
+		return false
+	}
+	return true
+}
+
+// newerThan orders transactions newest first by (created_at, id).
+func newerThan(a models.Transaction, createdAt time.Time, id uint) bool {
+	if !a.CreatedAt.Equal(createdAt) {
+		return a.CreatedAt.After(createdAt)
+	}
+	return a.ID > id
+}
+
+func (r *memoryTransactionRepository) Find(filter TransactionFilter) ([]models.Transaction, error) {
+	var transactions []models.Transaction
+	err := r.s.with(func(st *memoryState) error {
+		transactions = st.transactions.find(func(t models.Transaction) bool {
+			if filter.After != nil && !newerThan(models.Transaction{CreatedAt: filter.After.CreatedAt, ID: filter.After.ID}, t.CreatedAt, t.ID) {
+				return false
+			}
+			return matchesTransaction(t, filter)
+		})
+		sort.SliceStable(transactions, func(i, j int) bool {
+			return newerThan(transactions[i], transactions[j].CreatedAt, transactions[j].ID)
+		})
+		if filter.Limit > 0 && len(transactions) > filter.Limit {
+			transactions = transactions[:filter.Limit]
+		}
+		return nil
+	})
+	return transactions, err
+}
+
+func (r *memoryTransactionRepository) Count(filter TransactionFilter) (int64, error) {
+	var count int64
+	err := r.s.with(func(st *memoryState) error {
+		count = int64(len(st.transactions.find(func(t models.Transaction) bool { return matchesTransaction(t, filter) })))
+		return nil
+	})
+	return count, err
+}
+
+func (r *memoryTransactionRepository) ListByTransfer(transferID string) ([]models.Transaction, error) {
+	var transactions []models.Transaction
+	err := r.s.with(func(st *memoryState) error {
+		transactions = st.transactions.find(func(t models.Transaction) bool { return t.TransferID == transferID })
+		return nil
+	})
+	return transactions, err
+}
+
+type memoryLoanRepository struct{ s *MemoryStore }
+
+func (r *memoryLoanRepository) Create(loan *models.Loan) error {
+	return r.s.with(func(st *memoryState) error {
+		if _, ok := st.customers.get(loan.CustomerID); !ok {
+			return ErrNotFound
+		}
+		row := stripLoan(*loan)
+		st.loans.insert(&row)
+		loan.ID, loan.CreatedAt = row.ID, row.CreatedAt
+		return nil
+	})
+}
+
+func (r *memoryLoanRepository) Get(id uint) (*models.Loan, error) {
+	var loan models.Loan
+	err := r.s.with(func(st *memoryState) error {
+		row, ok := st.loans.get(id)
+		if !ok {
+			return ErrNotFound
+		}
+		row.Customer, _ = st.customers.get(row.CustomerID)
+		row.LoanPayments = paymentsOf(st, row.ID)
+		loan = row
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &loan, nil
+}
+
+func (r *memoryLoanRepository) GetForUpdate(id uint) (*models.Loan, error) {
+	var loan models.Loan
+	err := r.s.with(func(st *memoryState) error {
+		row, ok := st.loans.get(id)
+		if !ok {
+			return ErrNotFound
+		}
+		loan = row
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &loan, nil
+}
+
+func (r *memoryLoanRepository) Update(loan *models.Loan) error {
+	return r.s.with(func(st *memoryState) error {
+		row := stripLoan(*loan)
+		if !st.loans.update(&row) {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+func (r *memoryLoanRepository) List() ([]models.Loan, error) {
+	var loans []models.Loan
+	err := r.s.with(func(st *memoryState) error {
+		loans = st.loans.find(nil)
+		return nil
+	})
+	return loans, err
+}
+
+func matchesLoan(st *memoryState, l models.Loan, filter LoanFilter) bool {
+	return idMatches(l.CustomerID, filter.CustomerID) &&
+		(filter.Status == "" || l.Status == filter.Status) &&
+		(filter.LoanType == "" || l.LoanType == filter.LoanType) &&
+		(filter.Bucket == "" || l.DelinquencyBucket == filter.Bucket) &&
+		(filter.Restructured == nil || l.Restructured == *filter.Restructured) &&
+		(filter.ResetThrough == nil || l.NextResetDate != nil && !l.NextResetDate.After(*filter.ResetThrough)) &&
+		customerInScope(st, l.CustomerID, filter.BranchID, filter.BankID)
+}
+
+func (r *memoryLoanRepository) Find(filter LoanFilter) ([]models.Loan, error) {
+	var loans []models.Loan
+	err := r.s.with(func(st *memoryState) error {
+		loans = listed(st.loans.find(func(l models.Loan) bool { return matchesLoan(st, l, filter) }), filter.ListOptions)
+		return nil
+	})
+	return loans, err
+}
+
+func (r *memoryLoanRepository) Count(filter LoanFilter) (int64, error) {
+	var count int64
+	err := r.s.with(func(st *memoryState) error {
+		count = int64(len(st.loans.find(func(l models.Loan) bool { return matchesLoan(st, l, filter) })))
+		return nil
+	})
+	return count, err
+}
+
+func (r *memoryLoanRepository) ListByCustomer(customerID uint) ([]models.Loan, error) {
+	var loans []models.Loan
+	err := r.s.with(func(st *memoryState) error {
+		loans = st.loans.find(func(l models.Loan) bool { return l.CustomerID == customerID })
+		for i := range loans {
+			loans[i].LoanPayments = paymentsOf(st, loans[i].ID)
+		}
+		return nil
+	})
+	return loans, err
+}
+
+func (r *memoryLoanRepository) CreatePayment(payment *models.LoanPayment) error {
+	return r.s.with(func(st *memoryState) error {
+		if _, ok := st.loans.get(payment.LoanID); !ok {
+			return ErrNotFound
+		}
+		row := *payment
+		row.Loan = models.Loan{}
+		st.payments.insert(&row)
+		payment.ID, payment.CreatedAt = row.ID, row.CreatedAt
+		return nil
+	})
+}
+
+func (r *memoryLoanRepository) ListPayments(loanID uint) ([]models.LoanPayment, error) {
+	var payments []models.LoanPayment
+	err := r.s.with(func(st *memoryState) error {
+		payments = paymentsOf(st, loanID)
+		sort.SliceStable(payments, func(i, j int) bool {
+			a, b := payments[i], payments[j]
+			if !a.PaymentDate.Equal(b.PaymentDate) {
+				return a.PaymentDate.After(b.PaymentDate)
+			}
+			return a.ID > b.ID
+		})
+		return nil
+	})
+	return payments, err
+}
+
+func (r *memoryLoanRepository) CreateInstallment(installment *models.LoanInstallment) error {
+	return r.s.with(func(st *memoryState) error {
+		if _, ok := st.loans.get(installment.LoanID); !ok {
+			return ErrNotFound
+		}
+		if _, taken := st.installments.first(func(i models.LoanInstallment) bool {
+			return i.LoanID == installment.LoanID && i.Number == installment.Number
+		}); taken {
+			return ErrDuplicate
+		}
+		st.installments.insert(installment)
+		return nil
+	})
+}
+
+func (r *memoryLoanRepository) ListInstallments(loanID uint) ([]models.LoanInstallment, error) {
+	var installments []models.LoanInstallment
+	err := r.s.with(func(st *memoryState) error {
+		installments = st.installments.find(func(i models.LoanInstallment) bool { return i.LoanID == loanID })
+		sort.SliceStable(installments, func(i, j int) bool { return installments[i].Number < installments[j].Number })
+		return nil
+	})
+	return installments, err
+}
+
+func (r *memoryLoanRepository) UpdateInstallment(installment *models.LoanInstallment) error {
+	return r.s.with(func(st *memoryState) error {
+		if !st.installments.update(installment) {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+func (r *memoryLoanRepository) DeleteInstallment(id uint) error {
+	return r.s.with(func(st *memoryState) error {
+		if !st.installments.remove(id) {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+func (r *memoryLoanRepository) CreateAllocation(allocation *models.LoanPaymentAllocation) error {
+	return r.s.with(func(st *memoryState) error {
+		if _, ok := st.payments.get(allocation.PaymentID); !ok {
+			return ErrNotFound
+		}
+		if _, ok := st.installments.get(allocation.InstallmentID); !ok {
+			return ErrNotFound
+		}
+		st.allocations.insert(allocation)
+		return nil
+	})
+}
+
+func (r *memoryLoanRepository) AddStatusChange(change *models.LoanStatusChange) error {
+	return r.s.with(func(st *memoryState) error {
+		if _, ok := st.loans.get(change.LoanID); !ok {
+			return ErrNotFound
+		}
+		st.loanChanges.insert(change)
+		return nil
+	})
+}
+
+func (r *memoryLoanRepository) CreateCollection(collection *models.LoanCollection) (bool, error) {
+	created := false
+	err := r.s.with(func(st *memoryState) error {
+		if _, ok := st.installments.get(collection.InstallmentID); !ok {
+			return ErrNotFound
+		}
+		if _, taken := st.collections.first(func(c models.LoanCollection) bool {
+			return c.InstallmentID == collection.InstallmentID && c.BusinessDate.Equal(collection.BusinessDate)
+		}); taken {
+			return nil
+		}
+		st.collections.insert(collection)
+		created = true
+		return nil
+	})
+	return created, err
+}
+
+func (r *memoryLoanRepository) ListCollections(loanID uint) ([]models.LoanCollection, error) {
+	var collections []models.LoanCollection
+	err := r.s.with(func(st *memoryState) error {
+		collections = st.collections.find(func(c models.LoanCollection) bool { return c.LoanID == loanID })
+		return nil
+	})
+	return collections, err
+}
+
+func (r *memoryLoanRepository) AddClassification(classification *models.LoanClassification) error {
+	return r.s.with(func(st *memoryState) error {
+		if _, ok := st.loans.get(classification.LoanID); !ok {
+			return ErrNotFound
+		}
+		st.classifications.insert(classification)
+		return nil
+	})
+}
+
+func (r *memoryLoanRepository) ClassificationHistory(loanID uint) ([]models.LoanClassification, error) {
+	var classifications []models.LoanClassification
+	err := r.s.with(func(st *memoryState) error {
+		classifications = st.classifications.find(func(c models.LoanClassification) bool { return c.LoanID == loanID })
+		return nil
+	})
+	return classifications, err
+}
+
+func (r *memoryLoanRepository) AddRateChange(change *models.LoanRateChange) error {
+	return r.s.with(func(st *memoryState) error {
+		if _, ok := st.loans.get(change.LoanID); !ok {
+			return ErrNotFound
+		}
+		st.rateChanges.insert(change)
+		return nil
+	})
+}
+
+func (r *memoryLoanRepository) RateHistory(loanID uint) ([]models.LoanRateChange, error) {
+	var changes []models.LoanRateChange
+	err := r.s.with(func(st *memoryState) error {
+		changes = st.rateChanges.find(func(c models.LoanRateChange) bool { return c.LoanID == loanID })
+		return nil
+	})
+	return changes, err
+}
+
+func (r *memoryLoanRepository) AddRestructuring(restructuring *models.LoanRestructuring) error {
+	return r.s.with(func(st *memoryState) error {
+		if _, ok := st.loans.get(restructuring.LoanID); !ok {
+			return ErrNotFound
+		}
+		st.restructurings.insert(restructuring)
+		return nil
+	})
+}
+
+func (r *memoryLoanRepository) ListRestructurings(loanID uint) ([]models.LoanRestructuring, error) {
+	var restructurings []models.LoanRestructuring
+	err := r.s.with(func(st *memoryState) error {
+		restructurings = st.restructurings.find(func(r models.LoanRestructuring) bool { return r.LoanID == loanID })
+		return nil
+	})
+	return restructurings, err
+}
+
+func (r *memoryLoanRepository) ArchiveSchedule(snapshots []models.LoanScheduleSnapshot) error {
+	return r.s.with(func(st *memoryState) error {
+		for i := range snapshots {
+			if _, ok := st.loans.get(snapshots[i].LoanID); !ok {
+				return ErrNotFound
+			}
+			if _, taken := st.snapshots.first(func(s models.LoanScheduleSnapshot) bool {
+				return s.LoanID == snapshots[i].LoanID && s.Version == snapshots[i].Version && s.Number == snapshots[i].Number
+			}); taken {
+				return ErrDuplicate
+			}
+			st.snapshots.insert(&snapshots[i])
+		}
+		return nil
+	})
+}
+
+func (r *memoryLoanRepository) ListSnapshots(loanID uint) ([]models.LoanScheduleSnapshot, error) {
+	var snapshots []models.LoanScheduleSnapshot
+	err := r.s.with(func(st *memoryState) error {
+		snapshots = st.snapshots.find(func(s models.LoanScheduleSnapshot) bool { return s.LoanID == loanID })
+		sort.SliceStable(snapshots, func(i, j int) bool {
+			if snapshots[i].Version != snapshots[j].Version {
+				return snapshots[i].Version < snapshots[j].Version
+			}
+			return snapshots[i].Number < snapshots[j].Number
+		})
+		return nil
+	})
+	return snapshots, err
+}
+
+func (r *memoryLoanRepository) CreateWriteOff(writeOff *models.LoanWriteOff) error {
+	return r.s.with(func(st *memoryState) error {
+		if _, ok := st.loans.get(writeOff.LoanID); !ok {
+			return ErrNotFound
+		}
+		if _, taken := st.writeOffs.first(func(w models.LoanWriteOff) bool { return w.LoanID == writeOff.LoanID }); taken {
+			return ErrDuplicate
+		}
+		st.writeOffs.insert(writeOff)
+		return nil
+	})
+}
+
+func (r *memoryLoanRepository) GetWriteOff(loanID uint) (*models.LoanWriteOff, error) {
+	var writeOff models.LoanWriteOff
+	err := r.s.with(func(st *memoryState) error {
+		row, ok := st.writeOffs.first(func(w models.LoanWriteOff) bool { return w.LoanID == loanID })
+		if !ok {
+			return ErrNotFound
+		}
+		writeOff = row
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &writeOff, nil
+}
+
+func (r *memoryLoanRepository) UpdateWriteOff(writeOff *models.LoanWriteOff) error {
+	return r.s.with(func(st *memoryState) error {
+		if !st.writeOffs.update(writeOff) {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+func (r *memoryLoanRepository) FindWriteOffs(filter LoanFilter) ([]models.LoanWriteOff, error) {
+	var writeOffs []models.LoanWriteOff
+	err := r.s.with(func(st *memoryState) error {
+		writeOffs = st.writeOffs.find(func(w models.LoanWriteOff) bool {
+			loan, ok := st.loans.get(w.LoanID)
+			return ok && matchesLoan(st, loan, filter)
+		})
+		return nil
+	})
+	return writeOffs, err
+}
+
+func (r *memoryLoanRepository) AddRecovery(recovery *models.LoanRecovery) error {
+	return r.s.with(func(st *memoryState) error {
+		if _, ok := st.writeOffs.get(recovery.WriteOffID); !ok {
+			return ErrNotFound
+		}
+		st.recoveries.insert(recovery)
+		return nil
+	})
+}
+
+func (r *memoryLoanRepository) ListRecoveries(loanID uint) ([]models.LoanRecovery, error) {
+	var recoveries []models.LoanRecovery
+	err := r.s.with(func(st *memoryState) error {
+		recoveries = st.recoveries.find(func(r models.LoanRecovery) bool { return r.LoanID == loanID })
+		return nil
+	})
+	return recoveries, err
+}
+
+func (r *memoryLoanRepository) StatusHistory(loanID uint) ([]models.LoanStatusChange, error) {
+	var changes []models.LoanStatusChange
+	err := r.s.with(func(st *memoryState) error {
+		changes = st.loanChanges.find(func(c models.LoanStatusChange) bool { return c.LoanID == loanID })
+		return nil
+	})
+	return changes, err
+}
+
+func paymentsOf(st *memoryState, loanID uint) []models.LoanPayment {
+	return st.payments.find(func(p models.LoanPayment) bool { return p.LoanID == loanID })
+}
+
+func stripLoan(loan models.Loan) models.Loan {
+	loan.Customer = models.Customer{}
+	loan.LoanPayments = nil
+	return loan
+}
+
+type memoryLoanProductRepository struct{ s *MemoryStore }
+
+func productCodeTaken(st *memoryState, product *models.LoanProduct) bool {
+	_, taken := st.loanProducts.first(func(p models.LoanProduct) bool {
+		return p.BankID == product.BankID && p.Code == product.Code && p.ID != product.ID
+	})
+	return taken
+}
+
+func (r *memoryLoanProductRepository) Create(product *models.LoanProduct) error {
+	return r.s.with(func(st *memoryState) error {
+		if _, ok := st.banks.get(product.BankID); !ok {
+			return ErrNotFound
+		}
+		if productCodeTaken(st, product) {
+			return ErrDuplicate
+		}
+		st.loanProducts.insert(product)
+		return nil
+	})
+}
+
+func (r *memoryLoanProductRepository) Get(id uint) (*models.LoanProduct, error) {
+	var product models.LoanProduct
+	err := r.s.with(func(st *memoryState) error {
+		row, ok := st.loanProducts.get(id)
+		if !ok {
+			return ErrNotFound
+		}
+		product = row
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+func (r *memoryLoanProductRepository) Update(product *models.LoanProduct) error {
+	return r.s.with(func(st *memoryState) error {
+		if productCodeTaken(st, product) {
+			return ErrDuplicate
+		}
+		if !st.loanProducts.update(product) {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+func matchesLoanProduct(p models.LoanProduct, filter LoanProductFilter) bool {
+	return idMatches(p.BankID, filter.BankID) &&
+		(filter.LoanType == "" || p.LoanType == filter.LoanType) &&
+		(filter.Active == nil || p.Active == *filter.Active)
+}
+
+func (r *memoryLoanProductRepository) Find(filter LoanProductFilter) ([]models.LoanProduct, error) {
+	var products []models.LoanProduct
+	err := r.s.with(func(st *memoryState) error {
+		products = listed(st.loanProducts.find(func(p models.LoanProduct) bool { return matchesLoanProduct(p, filter) }), filter.ListOptions)
+		return nil
+	})
+	return products, err
+}
+
+func (r *memoryLoanProductRepository) Count(filter LoanProductFilter) (int64, error) {
+	var count int64
+	err := r.s.with(func(st *memoryState) error {
+		count = int64(len(st.loanProducts.find(func(p models.LoanProduct) bool { return matchesLoanProduct(p, filter) })))
+		return nil
+	})
+	return count, err
+}
+
+type memoryBenchmarkRepository struct{ s *MemoryStore }
+
+func (r *memoryBenchmarkRepository) Create(benchmark *models.Benchmark) error {
+	return r.s.with(func(st *memoryState) error {
+		if _, ok := st.banks.get(benchmark.BankID); !ok {
+			return ErrNotFound
+		}
+		if _, taken := st.benchmarks.first(func(b models.Benchmark) bool {
+			return b.BankID == benchmark.BankID && b.Code == benchmark.Code
+		}); taken {
+			return ErrDuplicate
+		}
+		st.benchmarks.insert(benchmark)
+		return nil
+	})
+}
+
+func (r *memoryBenchmarkRepository) Get(id uint) (*models.Benchmark, error) {
+	var benchmark models.Benchmark
+	err := r.s.with(func(st *memoryState) error {
+		row, ok := st.benchmarks.get(id)
+		if !ok {
+			return ErrNotFound
+		}
+		benchmark = row
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &benchmark, nil
+}
+
+func (r *memoryBenchmarkRepository) ListByBank(bankID uint) ([]models.Benchmark, error) {
+	var benchmarks []models.Benchmark
+	err := r.s.with(func(st *memoryState) error {
+		benchmarks = st.benchmarks.find(func(b models.Benchmark) bool { return b.BankID == bankID })
+		sort.SliceStable(benchmarks, func(i, j int) bool { return benchmarks[i].Code < benchmarks[j].Code })
+		return nil
+	})
+	return benchmarks, err
+}
+
+func (r *memoryBenchmarkRepository) AddRate(rate *models.BenchmarkRate) (bool, error) {
+	created := false
+	err := r.s.with(func(st *memoryState) error {
+		if _, ok := st.benchmarks.get(rate.BenchmarkID); !ok {
+			return ErrNotFound
+		}
+		if _, taken := st.benchmarkRates.first(func(b models.BenchmarkRate) bool {
+			return b.BenchmarkID == rate.BenchmarkID && b.EffectiveDate.Equal(rate.EffectiveDate)
+		}); taken {
+			return nil
+		}
+		st.benchmarkRates.insert(rate)
+		created = true
+		return nil
+	})
+	return created, err
+}
+
+func (r *memoryBenchmarkRepository) ListRates(benchmarkID uint) ([]models.BenchmarkRate, error) {
+	var rates []models.BenchmarkRate
+	err := r.s.with(func(st *memoryState) error {
+		rates = st.benchmarkRates.find(func(b models.BenchmarkRate) bool { return b.BenchmarkID == benchmarkID })
+		sort.SliceStable(rates, func(i, j int) bool { return rates[i].EffectiveDate.Before(rates[j].EffectiveDate) })
+		return nil
+	})
+	return rates, err
+}
+
+func (r *memoryBenchmarkRepository) RateAsOf(benchmarkID uint, date time.Time) (*models.BenchmarkRate, error) {
+	rates, err := r.ListRates(benchmarkID)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(rates) - 1; i >= 0; i-- {
+		if !rates[i].EffectiveDate.After(date) {
+			return &rates[i], nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+type memoryLedgerRepository struct{ s *MemoryStore }
+
+func (r *memoryLedgerRepository) EnsureAccount(account models.LedgerAccount) (*models.LedgerAccount, error) {
+	err := r.s.with(func(st *memoryState) error {
+		if existing, ok := st.ledgerAccounts.first(func(a models.LedgerAccount) bool { return a.Code == account.Code }); ok {
+			account = existing
+			return nil
+		}
+		st.ledgerAccounts.insert(&account)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (r *memoryLedgerRepository) GetAccountByCode(code string) (*models.LedgerAccount, error) {
+	var account models.LedgerAccount
+	err := r.s.with(func(st *memoryState) error {
+		row, ok := st.ledgerAccounts.first(func(a models.LedgerAccount) bool { return a.Code == code })
+		if !ok {
+			return ErrNotFound
+		}
+		account = row
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (r *memoryLedgerRepository) CreateEntry(entry *models.JournalEntry) error {
+	return r.s.with(func(st *memoryState) error {
+		for _, p := range entry.Postings {
+			if _, ok := st.ledgerAccounts.get(p.LedgerAccountID); !ok {
+				return ErrNotFound
+			}
+		}
+		row := *entry
+		row.Postings = nil
+		st.entries.insert(&row)
+		entry.ID, entry.CreatedAt = row.ID, row.CreatedAt
+		for i := range entry.Postings {
+			posting := entry.Postings[i]
+			posting.JournalEntryID = row.ID
+			posting.LedgerAccount = models.LedgerAccount{}
+			st.postings.insert(&posting)
+			entry.Postings[i].ID = posting.ID
+			entry.Postings[i].JournalEntryID = row.ID
+			entry.Postings[i].CreatedAt = posting.CreatedAt
+		}
+		return nil
+	})
+}
+
+func (r *memoryLedgerRepository) GetEntry(id uint) (*models.JournalEntry, error) {
+	var entry models.JournalEntry
+	err := r.s.with(func(st *memoryState) error {
+		row, ok := st.entries.get(id)
+		if !ok {
+			return ErrNotFound
+		}
+		row.Postings = st.postings.find(func(p models.Posting) bool { return p.JournalEntryID == row.ID })
+		for i := range row.Postings {
+			row.Postings[i].LedgerAccount, _ = st.ledgerAccounts.get(row.Postings[i].LedgerAccountID)
+		}
+		entry = row
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (r *memoryLedgerRepository) Balance(ledgerAccountID uint) (money.Money, error) {
+	var total money.Money
+	err := r.s.with(func(st *memoryState) error {
+		for _, p := range st.postings.find(func(p models.Posting) bool { return p.LedgerAccountID == ledgerAccountID }) {
+			total += p.Amount
+		}
+		return nil
+	})
+	return total, err
+}
+
+func (r *memoryLedgerRepository) BalanceAsOf(ledgerAccountID uint, at time.Time) (money.Money, error) {
+	var total money.Money
+	err := r.s.with(func(st *memoryState) error {
+		for _, p := range st.postings.find(func(p models.Posting) bool {
+			return p.LedgerAccountID == ledgerAccountID && p.CreatedAt.Before(at)
+		}) {
+			total += p.Amount
+		}
+		return nil
+	})
+	return total, err
+}
+
+type memoryInterestRepository struct{ s *MemoryStore }
+
+func (r *memoryInterestRepository) CreateAccrual(accrual *models.InterestAccrual) (bool, error) {
+	created := false
+	err := r.s.with(func(st *memoryState) error {
+		if _, ok := st.accounts.get(accrual.AccountID); !ok {
+			return ErrNotFound
+		}
+		if _, taken := st.accruals.first(func(a models.InterestAccrual) bool {
+			return a.AccountID == accrual.AccountID && a.BusinessDate.Equal(accrual.BusinessDate)
+		}); taken {
+			return nil
+		}
+		st.accruals.insert(accrual)
+		created = true
+		return nil
+	})
+	return created, err
+}
+
+func (r *memoryInterestRepository) FindAccruals(filter AccrualFilter) ([]models.InterestAccrual, error) {
+	var accruals []models.InterestAccrual
+	err := r.s.with(func(st *memoryState) error {
+		accruals = st.accruals.find(func(a models.InterestAccrual) bool {
+			return idMatches(a.AccountID, filter.AccountID) &&
+				(!filter.Unposted || a.PostedAt == nil) &&
+				(filter.Through == nil || !a.BusinessDate.After(*filter.Through))
+		})
+		return nil
+	})
+	sort.SliceStable(accruals, func(i, j int) bool {
+		if accruals[i].AccountID != accruals[j].AccountID {
+			return accruals[i].AccountID < accruals[j].AccountID
+		}
+		return accruals[i].BusinessDate.Before(accruals[j].BusinessDate)
+	})
+	return accruals, err
+}
+
+func (r *memoryInterestRepository) MarkPosted(accrualIDs []uint, transactionID *uint, postedAt time.Time) error {
+	return r.s.with(func(st *memoryState) error {
+		for _, id := range accrualIDs {
+			row, ok := st.accruals.get(id)
+			if !ok {
+				return ErrNotFound
+			}
+			row.TransactionID = transactionID
+			row.PostedAt = &postedAt
+			st.accruals.update(&row)
+		}
+		return nil
+	})
+}
+
+type memoryIdempotencyRepository struct{ s *MemoryStore }
+
+func (r *memoryIdempotencyRepository) Reserve(record *models.IdempotencyRecord) (bool, error) {
+	reserved := false
+	err := r.s.with(func(st *memoryState) error {
+		if _, taken := st.idempotency.get(record.Key); taken {
+			return nil
+		}
+		record.CreatedAt = time.Now()
+		record.UpdatedAt = record.CreatedAt
+		st.idempotency.set(record.Key, *record)
+		reserved = true
+		return nil
+	})
+	return reserved, err
+}
+
+func (r *memoryIdempotencyRepository) Get(key string) (*models.IdempotencyRecord, error) {
+	var record models.IdempotencyRecord
+	err := r.s.with(func(st *memoryState) error {
+		row, ok := st.idempotency.get(key)
+		if !ok {
+			return ErrNotFound
+		}
+		record = row
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (r *memoryIdempotencyRepository) Complete(key string, statusCode int, responseBody []byte) error {
+	return r.s.with(func(st *memoryState) error {
+		record, ok := st.idempotency.get(key)
+		if !ok {
+			return ErrNotFound
+		}
+		record.StatusCode = statusCode
+		record.ResponseBody = append([]byte(nil), responseBody...)
+		record.UpdatedAt = time.Now()
+		st.idempotency.set(key, record)
+		return nil
+	})
+}
+
+func (r *memoryIdempotencyRepository) Release(key string) error {
+	return r.s.with(func(st *memoryState) error {
+		st.idempotency.remove(key)
+		return nil
+	})
+}
+
+type memoryAPIKeyRepository struct{ s *MemoryStore }
+
+func (r *memoryAPIKeyRepository) Create(key *models.APIKey) error {
+	return r.s.with(func(st *memoryState) error {
+		if _, taken := st.apiKeys.first(func(k models.APIKey) bool { return k.KeyHash == key.KeyHash }); taken {
+			return ErrDuplicate
+		}
+		st.apiKeys.insert(key)
+		return nil
+	})
+}
+
+func (r *memoryAPIKeyRepository) Get(id uint) (*models.APIKey, error) {
+	var key models.APIKey
+	err := r.s.with(func(st *memoryState) error {
+		row, ok := st.apiKeys.get(id)
+		if !ok {
+			return ErrNotFound
+		}
+		key = row
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *memoryAPIKeyRepository) GetByHash(keyHash string) (*models.APIKey, error) {
+	var key models.APIKey
+	err := r.s.with(func(st *memoryState) error {
+		row, ok := st.apiKeys.first(func(k models.APIKey) bool { return k.KeyHash == keyHash })
+		if !ok {
+			return ErrNotFound
+		}
+		key = row
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *memoryAPIKeyRepository) Update(key *models.APIKey) error {
+	return r.s.with(func(st *memoryState) error {
+		if !st.apiKeys.update(key) {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+func (r *memoryAPIKeyRepository) List() ([]models.APIKey, error) {
+	var keys []models.APIKey
+	err := r.s.with(func(st *memoryState) error {
+		keys = st.apiKeys.find(nil)
+		return nil
+	})
+	return keys, err
+}
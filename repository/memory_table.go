@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"cmp"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm/schema"
+)
+
+var naming = schema.NamingStrategy{}
+
+// layer holds rows by key. A transaction works on a layer forked from the
+// committed one: it keeps only the rows the transaction wrote or removed and
+// reads everything else through to its base, so a transaction costs what it
+// touches rather than a copy of every table.
+type layer[K comparable, V any] struct {
+	rows    map[K]V
+	removed map[K]bool
+	base    *layer[K, V]
+}
+
+func newLayer[K comparable, V any]() *layer[K, V] {
+	return &layer[K, V]{rows: map[K]V{}}
+}
+
+func (l *layer[K, V]) fork() *layer[K, V] {
+	return &layer[K, V]{rows: map[K]V{}, removed: map[K]bool{}, base: l}
+}
+
+func (l *layer[K, V]) get(key K) (V, bool) {
+	if row, ok := l.rows[key]; ok {
+		return row, true
+	}
+	if l.base == nil || l.removed[key] {
+		var zero V
+		return zero, false
+	}
+	return l.base.get(key)
+}
+
+func (l *layer[K, V]) set(key K, row V) {
+	l.rows[key] = row
+	delete(l.removed, key)
+}
+
+func (l *layer[K, V]) remove(key K) {
+	delete(l.rows, key)
+	if l.base != nil {
+		l.removed[key] = true
+	}
+}
+
+// each calls fn for every row, in no particular order.
+func (l *layer[K, V]) each(fn func(key K, row V)) {
+	if l.base != nil {
+		l.base.each(func(key K, row V) {
+			if _, written := l.rows[key]; !written && !l.removed[key] {
+				fn(key, row)
+			}
+		})
+	}
+	for key, row := range l.rows {
+		fn(key, row)
+	}
+}
+
+// commit applies a forked layer's writes to its base.
+func (l *layer[K, V]) commit() {
+	for key, row := range l.rows {
+		l.base.set(key, row)
+	}
+	for key := range l.removed {
+		l.base.remove(key)
+	}
+}
+
+// table is an in-memory stand-in for one database table. Rows are stored by
+// value, so callers never share memory with the table.
+type table[T any] struct {
+	*layer[uint, T]
+	next uint
+}
+
+func newTable[T any]() *table[T] {
+	return &table[T]{layer: newLayer[uint, T]()}
+}
+
+func (t *table[T]) fork() *table[T] {
+	return &table[T]{layer: t.layer.fork(), next: t.next}
+}
+
+func (t *table[T]) commit(base *table[T]) {
+	t.layer.commit()
+	base.next = t.next
+}
+
+// insert assigns the next id and fills CreatedAt/UpdatedAt the way gorm does,
+// writing them back into row.
+func (t *table[T]) insert(row *T) {
+	t.next++
+	v := reflect.ValueOf(row).Elem()
+	v.FieldByName("ID").SetUint(uint64(t.next))
+	now := time.Now()
+	for _, name := range []string{"CreatedAt", "UpdatedAt"} {
+		if f := v.FieldByName(name); f.IsValid() && f.Interface().(time.Time).IsZero() {
+			f.Set(reflect.ValueOf(now))
+		}
+	}
+	t.set(t.next, *row)
+}
+
+// update replaces an existing row and reports whether it existed.
+func (t *table[T]) update(row *T) bool {
+	v := reflect.ValueOf(row).Elem()
+	id := uint(v.FieldByName("ID").Uint())
+	if _, ok := t.get(id); !ok {
+		return false
+	}
+	if f := v.FieldByName("UpdatedAt"); f.IsValid() {
+		f.Set(reflect.ValueOf(time.Now()))
+	}
+	t.set(id, *row)
+	return true
+}
+
+func (t *table[T]) remove(id uint) bool {
+	if _, ok := t.get(id); !ok {
+		return false
+	}
+	t.layer.remove(id)
+	return true
+}
+
+// find returns the matching rows in id order.
+func (t *table[T]) find(match func(T) bool) []T {
+	var ids []uint
+	found := map[uint]T{}
+	t.each(func(id uint, row T) {
+		if match == nil || match(row) {
+			ids = append(ids, id)
+			found[id] = row
+		}
+	})
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	rows := make([]T, 0, len(ids))
+	for _, id := range ids {
+		rows = append(rows, found[id])
+	}
+	return rows
+}
+
+func (t *table[T]) first(match func(T) bool) (T, bool) {
+	rows := t.find(match)
+	if len(rows) == 0 {
+		var zero T
+		return zero, false
+	}
+	return rows[0], true
+}
+
+// sortRows orders rows by the field backing column, then by id, the way
+// ORDER BY column, id does. Unknown columns leave the id order alone.
+func sortRows[T any](rows []T, column string, desc bool) {
+	field := fieldForColumn[T](column)
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := reflect.ValueOf(rows[i]), reflect.ValueOf(rows[j])
+		c := 0
+		if field != "" {
+			c = compareValues(a.FieldByName(field), b.FieldByName(field))
+		}
+		if c == 0 {
+			c = compareValues(a.FieldByName("ID"), b.FieldByName("ID"))
+		}
+		if desc {
+			return c > 0
+		}
+		return c < 0
+	})
+}
+
+func fieldForColumn[T any](column string) string {
+	var zero T
+	t := reflect.TypeOf(zero)
+	for i := 0; i < t.NumField(); i++ {
+		if naming.ColumnName("", t.Field(i).Name) == column {
+			return t.Field(i).Name
+		}
+	}
+	return ""
+}
+
+func compareValues(a, b reflect.Value) int {
+	if ta, ok := a.Interface().(time.Time); ok {
+		return ta.Compare(b.Interface().(time.Time))
+	}
+	switch a.Kind() {
+	case reflect.String:
+		return strings.Compare(a.String(), b.String())
+	case reflect.Int, reflect.Int64:
+		return cmp.Compare(a.Int(), b.Int())
+	case reflect.Uint, reflect.Uint64:
+		return cmp.Compare(a.Uint(), b.Uint())
+	case reflect.Float64:
+		return cmp.Compare(a.Float(), b.Float())
+	}
+	return 0
+}
+
+// pageRows applies OFFSET and LIMIT; a zero limit means no limit.
+func pageRows[T any](rows []T, offset, limit int) []T {
+	if offset >= len(rows) {
+		return []T{}
+	}
+	rows = rows[offset:]
+	if limit > 0 && len(rows) > limit {
+		rows = rows[:limit]
+	}
+	return rows
+}
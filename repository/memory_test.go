@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"banking-system/models"
+	"errors"
+	"testing"
+)
+
+var errRollback = errors.New("rollback")
+
+func TestAtomicCommitsOnSuccess(t *testing.T) {
+	s := NewMemoryStore()
+	bank := models.Bank{Name: "Before"}
+	if err := s.Banks().Create(&bank); err != nil {
+		t.Fatal(err)
+	}
+
+	err := s.Atomic(func(tx Store) error {
+		renamed := bank
+		renamed.Name = "After"
+		if err := tx.Banks().Update(&renamed); err != nil {
+			return err
+		}
+		if got, _ := tx.Banks().Get(bank.ID); got.Name != "After" {
+			t.Errorf("inside the transaction the bank is %q, want its own write", got.Name)
+		}
+		return tx.Banks().Create(&models.Bank{Name: "New"})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	banks, err := s.Banks().Find(BankFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(banks) != 2 || banks[0].Name != "After" || banks[1].Name != "New" || banks[1].ID != 2 {
+		t.Fatalf("banks = %+v, want After and New", banks)
+	}
+}
+
+func TestAtomicRollsBackOnError(t *testing.T) {
+	s := NewMemoryStore()
+	keep := models.Bank{Name: "Keep"}
+	if err := s.Banks().Create(&keep); err != nil {
+		t.Fatal(err)
+	}
+
+	err := s.Atomic(func(tx Store) error {
+		if err := tx.Banks().Create(&models.Bank{Name: "Discard"}); err != nil {
+			return err
+		}
+		renamed := keep
+		renamed.Name = "Changed"
+		if err := tx.Banks().Update(&renamed); err != nil {
+			return err
+		}
+		if _, err := tx.Idempotency().Reserve(&models.IdempotencyRecord{Key: "k"}); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("err = %v, want errRollback", err)
+	}
+
+	banks, err := s.Banks().Find(BankFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(banks) != 1 || banks[0].Name != "Keep" {
+		t.Fatalf("banks = %+v, want only Keep unchanged", banks)
+	}
+	if _, err := s.Idempotency().Get("k"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("idempotency record survived the rollback: %v", err)
+	}
+	next := models.Bank{Name: "Next"}
+	if err := s.Banks().Create(&next); err != nil {
+		t.Fatal(err)
+	}
+	if next.ID != 2 {
+		t.Errorf("next id = %d, want 2 after the rolled-back insert", next.ID)
+	}
+}
+
+func TestNestedAtomic(t *testing.T) {
+	s := NewMemoryStore()
+	err := s.Atomic(func(tx Store) error {
+		if err := tx.Banks().Create(&models.Bank{Name: "Outer"}); err != nil {
+			return err
+		}
+		if err := tx.Atomic(func(inner Store) error {
+			return inner.Banks().Create(&models.Bank{Name: "Inner"})
+		}); err != nil {
+			return err
+		}
+		err := tx.Atomic(func(inner Store) error {
+			if err := inner.Banks().Create(&models.Bank{Name: "Failed"}); err != nil {
+				return err
+			}
+			return errRollback
+		})
+		if !errors.Is(err, errRollback) {
+			t.Errorf("inner err = %v, want errRollback", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	banks, err := s.Banks().Find(BankFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(banks) != 2 || banks[0].Name != "Outer" || banks[1].Name != "Inner" {
+		t.Fatalf("banks = %+v, want Outer and Inner", banks)
+	}
+}
+
+func TestRemoveInsideAtomic(t *testing.T) {
+	s := NewMemoryStore()
+	bank := models.Bank{Name: "Bank"}
+	if err := s.Banks().Create(&bank); err != nil {
+		t.Fatal(err)
+	}
+	branch := models.Branch{BankID: bank.ID, Name: "Branch"}
+	if err := s.Branches().Create(&branch); err != nil {
+		t.Fatal(err)
+	}
+	customer := models.Customer{BranchID: branch.ID, Name: "Customer", Email: "c@example.com"}
+	if err := s.Customers().Create(&customer); err != nil {
+		t.Fatal(err)
+	}
+	loan := models.Loan{CustomerID: customer.ID}
+	if err := s.Loans().Create(&loan); err != nil {
+		t.Fatal(err)
+	}
+	installment := models.LoanInstallment{LoanID: loan.ID, Number: 1}
+	if err := s.Loans().CreateInstallment(&installment); err != nil {
+		t.Fatal(err)
+	}
+
+	err := s.Atomic(func(tx Store) error {
+		if err := tx.Loans().DeleteInstallment(installment.ID); err != nil {
+			return err
+		}
+		if rows, _ := tx.Loans().ListInstallments(loan.ID); len(rows) != 0 {
+			t.Errorf("deleted installment still listed inside the transaction")
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatal(err)
+	}
+	if rows, _ := s.Loans().ListInstallments(loan.ID); len(rows) != 1 {
+		t.Fatalf("installments after rollback = %d, want 1", len(rows))
+	}
+
+	if err := s.Atomic(func(tx Store) error { return tx.Loans().DeleteInstallment(installment.ID) }); err != nil {
+		t.Fatal(err)
+	}
+	if rows, _ := s.Loans().ListInstallments(loan.ID); len(rows) != 0 {
+		t.Fatalf("installments after commit = %d, want 0", len(rows))
+	}
+}
@@ -0,0 +1,307 @@
+// Package repository defines the persistence interfaces used by the services
+// together with a GORM/Postgres implementation and an in-memory
+// implementation for tests.
+package repository
+
+import (
+	"banking-system/models"
+	"banking-system/money"
+	"errors"
+	"time"
+)
+
+var (
+	ErrNotFound  = errors.New("record not found")
+	ErrDuplicate = errors.New("duplicate record")
+)
+
+// Store gives access to every repository. Inside Atomic, fn receives a Store
+// bound to a single transaction: its writes are visible to fn straight away
+// and to everyone else only once fn returns nil. Rows read with a ForUpdate
+// method stay locked until the transaction ends.
+type Store interface {
+	Banks() BankRepository
+	Branches() BranchRepository
+	Customers() CustomerRepository
+	Accounts() AccountRepository
+	Transactions() TransactionRepository
+	Loans() LoanRepository
+	LoanProducts() LoanProductRepository
+	Ledger() LedgerRepository
+	Interest() InterestRepository
+	Benchmarks() BenchmarkRepository
+	Idempotency() IdempotencyRepository
+	APIKeys() APIKeyRepository
+	Atomic(fn func(tx Store) error) error
+}
+
+// ListOptions orders and pages a collection query. Sort is a column name
+// the caller has already checked; rows with equal sort values are ordered by
+// id in the same direction. A zero Limit means no limit.
+type ListOptions struct {
+	Sort   string
+	Desc   bool
+	Offset int
+	Limit  int
+}
+
+// The filters below select rows for Find and Count. Nil and empty fields do
+// not filter, Name matches a case-insensitive substring, and ListOptions
+// only applies to Find.
+
+type BankFilter struct {
+	ID   *uint
+	Name string
+	ListOptions
+}
+
+type BranchFilter struct {
+	ID     *uint
+	BankID *uint
+	Name   string
+	ListOptions
+}
+
+type CustomerFilter struct {
+	ID       *uint
+	BranchID *uint
+	BankID   *uint
+	Name     string
+	Email    string
+	ListOptions
+}
+
+// AccountFilter matches accounts through their holders, so a joint account
+// is found from the branch or bank of any holder.
+type AccountFilter struct {
+	CustomerID *uint
+	BranchID   *uint
+	BankID     *uint
+	MinBalance *money.Money
+	MaxBalance *money.Money
+	Status     string
+	ListOptions
+}
+
+// LoanFilter's ResetThrough matches loans with a rate reset due on or
+// before it.
+type LoanFilter struct {
+	CustomerID   *uint
+	BranchID     *uint
+	BankID       *uint
+	Status       string
+	LoanType     string
+	Bucket       string
+	Restructured *bool
+	ResetThrough *time.Time
+	ListOptions
+}
+
+type LoanProductFilter struct {
+	BankID   *uint
+	LoanType string
+	Active   *bool
+	ListOptions
+}
+
+type BankRepository interface {
+	Create(bank *models.Bank) error
+	// Get returns the bank with its branches.
+	Get(id uint) (*models.Bank, error)
+	Find(filter BankFilter) ([]models.Bank, error)
+	Count(filter BankFilter) (int64, error)
+	Update(bank *models.Bank) error
+}
+
+type BranchRepository interface {
+	Create(branch *models.Branch) error
+	// Get returns the branch with its bank and customers.
+	Get(id uint) (*models.Branch, error)
+	Find(filter BranchFilter) ([]models.Branch, error)
+	Count(filter BranchFilter) (int64, error)
+	Update(branch *models.Branch) error
+}
+
+type CustomerRepository interface {
+	Create(customer *models.Customer) error
+	// Get returns the customer with its branch, accounts and loans.
+	Get(id uint) (*models.Customer, error)
+	Find(filter CustomerFilter) ([]models.Customer, error)
+	Count(filter CustomerFilter) (int64, error)
+	Update(customer *models.Customer) error
+}
+
+type AccountRepository interface {
+	Create(account *models.SavingsAccount) error
+	// Get returns the account with its holders.
+	Get(id uint) (*models.SavingsAccount, error)
+	GetForUpdate(id uint) (*models.SavingsAccount, error)
+	Update(account *models.SavingsAccount) error
+	List() ([]models.SavingsAccount, error)
+	Find(filter AccountFilter) ([]models.SavingsAccount, error)
+	Count(filter AccountFilter) (int64, error)
+	AddHolder(holder *models.CustomerAccount) error
+	FindHolder(accountID, customerID uint) (*models.CustomerAccount, error)
+	// ListHolders returns the account's holders with their customers.
+	ListHolders(accountID uint) ([]models.CustomerAccount, error)
+	AddStatusChange(change *models.AccountStatusChange) error
+	// StatusHistory returns the account's status changes, oldest first.
+	StatusHistory(accountID uint) ([]models.AccountStatusChange, error)
+}
+
+// TransactionCursor is the (created_at, id) position of the last transaction
+// on a page.
+type TransactionCursor struct {
+	CreatedAt time.Time
+	ID        uint
+}
+
+// TransactionFilter selects an account's transactions. Nil and empty fields
+// do not filter; From is inclusive and To exclusive. After and Limit only
+// apply to Find.
+type TransactionFilter struct {
+	AccountID uint
+	Types     []string
+	MinAmount *money.Money
+	MaxAmount *money.Money
+	From      *time.Time
+	To        *time.Time
+	After     *TransactionCursor
+	Limit     int
+}
+
+type TransactionRepository interface {
+	Create(transaction *models.Transaction) error
+	// Find returns the matching transactions newest first, starting after
+	// filter.After.
+	Find(filter TransactionFilter) ([]models.Transaction, error)
+	Count(filter TransactionFilter) (int64, error)
+	ListByTransfer(transferID string) ([]models.Transaction, error)
+}
+
+type LoanRepository interface {
+	Create(loan *models.Loan) error
+	// Get returns the loan with its customer and payments.
+	Get(id uint) (*models.Loan, error)
+	GetForUpdate(id uint) (*models.Loan, error)
+	Update(loan *models.Loan) error
+	List() ([]models.Loan, error)
+	Find(filter LoanFilter) ([]models.Loan, error)
+	Count(filter LoanFilter) (int64, error)
+	ListByCustomer(customerID uint) ([]models.Loan, error)
+	CreatePayment(payment *models.LoanPayment) error
+	// ListPayments returns the newest payments first.
+	ListPayments(loanID uint) ([]models.LoanPayment, error)
+	CreateInstallment(installment *models.LoanInstallment) error
+	// ListInstallments returns the loan's schedule in installment order.
+	ListInstallments(loanID uint) ([]models.LoanInstallment, error)
+	UpdateInstallment(installment *models.LoanInstallment) error
+	// DeleteInstallment removes an unpaid installment cut from a schedule.
+	DeleteInstallment(id uint) error
+	CreateAllocation(allocation *models.LoanPaymentAllocation) error
+	AddStatusChange(change *models.LoanStatusChange) error
+	StatusHistory(loanID uint) ([]models.LoanStatusChange, error)
+	// CreateCollection reports false, writing nothing, when the installment
+	// already has an attempt on the same business date.
+	CreateCollection(collection *models.LoanCollection) (bool, error)
+	// ListCollections returns the loan's collection attempts, oldest first.
+	ListCollections(loanID uint) ([]models.LoanCollection, error)
+	AddClassification(classification *models.LoanClassification) error
+	// ClassificationHistory returns the loan's bucket changes, oldest first.
+	ClassificationHistory(loanID uint) ([]models.LoanClassification, error)
+	AddRateChange(change *models.LoanRateChange) error
+	// RateHistory returns the loan's rate changes, oldest first.
+	RateHistory(loanID uint) ([]models.LoanRateChange, error)
+	AddRestructuring(restructuring *models.LoanRestructuring) error
+	// ListRestructurings returns the loan's restructurings, oldest first.
+	ListRestructurings(loanID uint) ([]models.LoanRestructuring, error)
+	// ArchiveSchedule stores the installments of a replaced schedule version.
+	ArchiveSchedule(snapshots []models.LoanScheduleSnapshot) error
+	// ListSnapshots returns the loan's replaced schedules ordered by version
+	// and installment number.
+	ListSnapshots(loanID uint) ([]models.LoanScheduleSnapshot, error)
+	// CreateWriteOff fails with ErrDuplicate when the loan has already been
+	// written off.
+	CreateWriteOff(writeOff *models.LoanWriteOff) error
+	GetWriteOff(loanID uint) (*models.LoanWriteOff, error)
+	UpdateWriteOff(writeOff *models.LoanWriteOff) error
+	// FindWriteOffs returns the write-offs of the loans matching filter; its
+	// ListOptions are ignored.
+	FindWriteOffs(filter LoanFilter) ([]models.LoanWriteOff, error)
+	AddRecovery(recovery *models.LoanRecovery) error
+	// ListRecoveries returns the loan's recoveries, oldest first.
+	ListRecoveries(loanID uint) ([]models.LoanRecovery, error)
+}
+
+type LoanProductRepository interface {
+	Create(product *models.LoanProduct) error
+	Get(id uint) (*models.LoanProduct, error)
+	Update(product *models.LoanProduct) error
+	Find(filter LoanProductFilter) ([]models.LoanProduct, error)
+	Count(filter LoanProductFilter) (int64, error)
+}
+
+type LedgerRepository interface {
+	// EnsureAccount returns the ledger account with account.Code, creating
+	// it from account if it does not exist yet.
+	EnsureAccount(account models.LedgerAccount) (*models.LedgerAccount, error)
+	GetAccountByCode(code string) (*models.LedgerAccount, error)
+	// CreateEntry stores the entry together with its postings.
+	CreateEntry(entry *models.JournalEntry) error
+	// GetEntry returns the entry with its postings and their ledger accounts.
+	GetEntry(id uint) (*models.JournalEntry, error)
+	Balance(ledgerAccountID uint) (money.Money, error)
+	// BalanceAsOf sums the postings made before at.
+	BalanceAsOf(ledgerAccountID uint, at time.Time) (money.Money, error)
+}
+
+// AccrualFilter selects interest accruals. Through is inclusive.
+type AccrualFilter struct {
+	AccountID *uint
+	Unposted  bool
+	Through   *time.Time
+}
+
+type InterestRepository interface {
+	// CreateAccrual stores accrual unless the account already has one for
+	// its business date, reporting whether it did.
+	CreateAccrual(accrual *models.InterestAccrual) (bool, error)
+	// FindAccruals returns the matching accruals ordered by account and
+	// business date.
+	FindAccruals(filter AccrualFilter) ([]models.InterestAccrual, error)
+	// MarkPosted records that the accruals were posted. transactionID is nil
+	// when they added up to nothing.
+	MarkPosted(accrualIDs []uint, transactionID *uint, postedAt time.Time) error
+}
+
+type BenchmarkRepository interface {
+	Create(benchmark *models.Benchmark) error
+	Get(id uint) (*models.Benchmark, error)
+	// ListByBank returns the bank's benchmarks ordered by code.
+	ListByBank(bankID uint) ([]models.Benchmark, error)
+	// AddRate stores rate unless the benchmark already has one effective on
+	// the same date, reporting whether it did.
+	AddRate(rate *models.BenchmarkRate) (bool, error)
+	// ListRates returns the benchmark's rates, earliest effective first.
+	ListRates(benchmarkID uint) ([]models.BenchmarkRate, error)
+	// RateAsOf returns the rate in effect on date: the latest one effective
+	// on or before it.
+	RateAsOf(benchmarkID uint, date time.Time) (*models.BenchmarkRate, error)
+}
+
+type IdempotencyRepository interface {
+	// Reserve stores record unless its key is taken, reporting whether it
+	// did.
+	Reserve(record *models.IdempotencyRecord) (bool, error)
+	Get(key string) (*models.IdempotencyRecord, error)
+	Complete(key string, statusCode int, responseBody []byte) error
+	Release(key string) error
+}
+
+type APIKeyRepository interface {
+	Create(key *models.APIKey) error
+	Get(id uint) (*models.APIKey, error)
+	GetByHash(keyHash string) (*models.APIKey, error)
+	Update(key *models.APIKey) error
+	List() ([]models.APIKey, error)
+}
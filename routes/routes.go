@@ -1,30 +1,126 @@
 package routes
 
 import (
+	"banking-system/auth"
 	"banking-system/controllers"
+	"banking-system/middleware"
+	"banking-system/repository"
+	"banking-system/services"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRoutes(router *gin.Engine) {
+func SetupRoutes(router *gin.Engine, store repository.Store, tokens *auth.Tokens) {
+	authService := services.NewAuthService(store, tokens)
+	policy := services.NewAccessService(store)
+	idempotent := middleware.Idempotency(store.Idempotency())
+	serviceClient := middleware.RequireAPIKey()
 
-	router.POST("/banks", controllers.CreateBank)
-	router.GET("/banks/:id", controllers.GetBank)
-	router.PUT("/banks/:id", controllers.UpdateBank)
+	banks := controllers.NewBankController(services.NewBankService(store), policy)
+	branches := controllers.NewBranchController(services.NewBranchService(store), policy)
+	customers := controllers.NewCustomerController(services.NewCustomerService(store), policy)
+	accounts := controllers.NewAccountController(services.NewAccountService(store), policy)
+	transfers := controllers.NewTransferController(services.NewTransferService(store), policy)
+	loans := controllers.NewLoanController(services.NewLoanService(store), policy)
+	products := controllers.NewLoanProductController(services.NewLoanProductService(store), policy)
+	benchmarks := controllers.NewBenchmarkController(services.NewBenchmarkService(store), policy)
+	interest := controllers.NewInterestController(services.NewInterestService(store), policy)
+	collections := controllers.NewCollectionController(services.NewCollectionService(store), policy)
+	ledger := controllers.NewLedgerController(services.NewLedgerService(store), policy)
+	authentication := controllers.NewAuthController(authService, policy)
 
-	router.POST("/branches", controllers.CreateBranch)
-	router.GET("/branches/:id", controllers.GetBranch)
-	router.PUT("/branches/:id", controllers.UpdateBranch)
+	api := router.Group("/", middleware.Authenticate(authService))
 
-	router.POST("/customers", controllers.CreateCustomer)
-	router.GET("/customers/:id", controllers.GetCustomer)
-	router.PUT("/customers/:id", controllers.UpdateCustomer)
+	api.GET("/auth/me", authentication.GetPrincipal)
+	api.POST("/auth/api-keys", serviceClient, authentication.IssueAPIKey)
+	api.GET("/auth/api-keys", serviceClient, authentication.ListAPIKeys)
+	api.DELETE("/auth/api-keys/:id", serviceClient, authentication.RevokeAPIKey)
+	api.POST("/auth/tokens", serviceClient, authentication.IssueToken)
 
-	router.POST("/accounts", controllers.OpenSavingsAccount)
-	router.GET("/accounts/:id", controllers.GetAccount)
-	router.PUT("/accounts/:id", controllers.UpdateAccount)
+	api.POST("/banks", banks.CreateBank)
+	api.GET("/banks", banks.GetAllBanks)
+	api.GET("/banks/:id", banks.GetBank)
+	api.GET("/banks/:id/branches", branches.ListBankBranches)
+	api.GET("/banks/:id/benchmarks", benchmarks.ListBenchmarks)
+	api.PUT("/banks/:id", banks.UpdateBank)
 
-	router.POST("/loans", controllers.TakeLoan)
-	router.GET("/loans/:id", controllers.GetLoan)
-	router.PUT("/loans/:id", controllers.UpdateLoan)
+	api.POST("/branches", branches.CreateBranch)
+	api.GET("/branches", branches.ListBranches)
+	api.GET("/branches/:id", branches.GetBranch)
+	api.GET("/branches/:id/customers", customers.ListBranchCustomers)
+	api.PUT("/branches/:id", branches.UpdateBranch)
+
+	api.POST("/customers", customers.CreateCustomer)
+	api.GET("/customers", customers.ListCustomers)
+	api.GET("/customers/:id", customers.GetCustomer)
+	api.GET("/customers/:id/accounts", accounts.ListCustomerAccounts)
+	api.GET("/customers/:id/loans", loans.GetCustomerLoans)
+	api.PUT("/customers/:id", customers.UpdateCustomer)
+
+	api.POST("/accounts", accounts.OpenSavingsAccount)
+	api.GET("/accounts", accounts.ListAccounts)
+	api.GET("/accounts/:id", accounts.GetAccount)
+	api.GET("/accounts/:id/holders", accounts.GetAccountHolders)
+	api.GET("/accounts/:id/transactions", accounts.ListTransactions)
+	api.GET("/accounts/:id/status-history", accounts.GetStatusHistory)
+	api.GET("/accounts/:id/interest-accruals", accounts.GetInterestAccruals)
+	api.PUT("/accounts/:id/interest", accounts.UpdateInterestSettings)
+	api.PUT("/accounts/:id", idempotent, accounts.UpdateAccount)
+	api.POST("/accounts/:id/freeze", accounts.FreezeAccount)
+	api.POST("/accounts/:id/unfreeze", accounts.UnfreezeAccount)
+	api.POST("/accounts/:id/dormant", accounts.MarkAccountDormant)
+	api.POST("/accounts/:id/reactivate", accounts.ReactivateAccount)
+	api.POST("/accounts/:id/close", idempotent, accounts.CloseAccount)
+
+	api.POST("/transfers", idempotent, transfers.CreateTransfer)
+	api.GET("/transfers/:id", transfers.GetTransfer)
+
+	api.POST("/loan-products", products.CreateProduct)
+	api.GET("/loan-products", products.ListProducts)
+	api.GET("/loan-products/:id", products.GetProduct)
+	api.PUT("/loan-products/:id", products.UpdateProduct)
+	api.POST("/benchmarks", benchmarks.CreateBenchmark)
+	api.GET("/benchmarks/:id", benchmarks.GetBenchmark)
+	api.POST("/benchmarks/:id/rates", benchmarks.PublishRate)
+	api.GET("/benchmarks/:id/rates", benchmarks.ListRates)
+
+	api.POST("/loans", idempotent, loans.TakeLoan)
+	api.GET("/loans", loans.ListLoans)
+	api.GET("/loans/:id", loans.GetLoan)
+	api.GET("/loans/:id/payments", loans.GetLoanPayments)
+	api.GET("/loans/:id/schedule", loans.GetLoanSchedule)
+	api.GET("/loans/:id/schedule-versions", loans.GetScheduleVersions)
+	api.GET("/loans/:id/interest", loans.GetLoanInterest)
+	api.GET("/loans/:id/status-history", loans.GetLoanStatusHistory)
+	api.GET("/loans/:id/collections", loans.GetLoanCollections)
+	api.GET("/loans/:id/classifications", loans.GetLoanClassifications)
+	api.GET("/loans/:id/rate-history", loans.GetLoanRateHistory)
+	api.PUT("/loans/:id/repayment-account", loans.SetRepaymentAccount)
+	api.POST("/loans/:id/prepay", idempotent, loans.PrepayLoan)
+	api.GET("/loans/:id/foreclosure-quote", loans.GetForeclosureQuote)
+	api.POST("/loans/:id/foreclose", idempotent, loans.ForecloseLoan)
+	api.POST("/loans/:id/moratorium", idempotent, loans.GrantMoratorium)
+	api.POST("/loans/:id/restructure", idempotent, loans.RestructureLoan)
+	api.GET("/loans/:id/restructurings", loans.GetLoanRestructurings)
+	api.POST("/loans/:id/write-off", idempotent, loans.WriteOffLoan)
+	api.GET("/loans/:id/write-off", loans.GetLoanWriteOff)
+	api.POST("/loans/:id/recoveries", idempotent, loans.RecordRecovery)
+	api.GET("/loans/:id/recoveries", loans.GetLoanRecoveries)
+	api.POST("/loans/:id/review", loans.ReviewLoan)
+	api.POST("/loans/:id/approve", loans.ApproveLoan)
+	api.POST("/loans/:id/reject", loans.RejectLoan)
+	api.POST("/loans/:id/disburse", idempotent, loans.DisburseLoan)
+	api.PUT("/loans/:id", idempotent, loans.UpdateLoan)
+
+	api.POST("/interest/accruals", interest.RunAccrual)
+	api.POST("/interest/postings", interest.RunPosting)
+	api.POST("/collections", collections.RunCollections)
+	api.POST("/collections/classifications", collections.RunClassification)
+	api.POST("/collections/rate-resets", collections.RunRateResets)
+
+	api.GET("/reports/loan-portfolio", loans.GetLoanPortfolio)
+
+	api.GET("/ledger/entries/:id", ledger.GetJournalEntry)
+	api.GET("/ledger/accounts/:code", ledger.GetLedgerAccount)
+	api.GET("/ledger/reconciliation", ledger.ReconcileLedger)
 }
@@ -0,0 +1,437 @@
+package services
+
+import (
+	"banking-system/auth"
+	"banking-system/repository"
+	"fmt"
+)
+
+// Permission is an action a role may perform on a resource.
+type Permission string
+
+const (
+	PermBankCreate        Permission = "bank:create"
+	PermBankRead          Permission = "bank:read"
+	PermBankUpdate        Permission = "bank:update"
+	PermBranchCreate      Permission = "branch:create"
+	PermBranchRead        Permission = "branch:read"
+	PermBranchUpdate      Permission = "branch:update"
+	PermCustomerCreate    Permission = "customer:create"
+	PermCustomerRead      Permission = "customer:read"
+	PermCustomerUpdate    Permission = "customer:update"
+	PermAccountOpen       Permission = "account:open"
+	PermAccountRead       Permission = "account:read"
+	PermAccountTransact   Permission = "account:transact"
+	PermAccountManage     Permission = "account:manage"
+	PermTransferCreate    Permission = "transfer:create"
+	PermTransferRead      Permission = "transfer:read"
+	PermLoanCreate        Permission = "loan:create"
+	PermLoanRead          Permission = "loan:read"
+	PermLoanRepay         Permission = "loan:repay"
+	PermLoanApprove       Permission = "loan:approve"
+	PermLoanDisburse      Permission = "loan:disburse"
+	PermLoanWriteOff      Permission = "loan:write_off"
+	PermLoanRecover       Permission = "loan:recover"
+	PermProductManage     Permission = "product:manage"
+	PermProductRead       Permission = "product:read"
+	PermLedgerRead        Permission = "ledger:read"
+	PermInterestRun       Permission = "interest:run"
+	PermCollectionRun     Permission = "collection:run"
+	PermCredentialsManage Permission = "credentials:manage"
+)
+
+func permissions(perms ...Permission) map[Permission]bool {
+	set := make(map[Permission]bool, len(perms))
+	for _, p := range perms {
+		set[p] = true
+	}
+	return set
+}
+
+var readPermissions = []Permission{
+	PermBankRead, PermBranchRead, PermCustomerRead, PermAccountRead,
+	PermTransferRead, PermLoanRead, PermProductRead, PermLedgerRead,
+}
+
+// rolePermissions says what each role may do. Where it may do it is decided
+// by the principal's scope.
+var rolePermissions = map[string]map[Permission]bool{
+	auth.RoleSystemAdmin: permissions(
+		PermBankCreate, PermBankRead, PermBankUpdate,
+		PermBranchCreate, PermBranchRead, PermBranchUpdate,
+		PermCustomerCreate, PermCustomerRead, PermCustomerUpdate,
+		PermAccountOpen, PermAccountRead, PermAccountTransact, PermAccountManage,
+		PermTransferCreate, PermTransferRead,
+		PermLoanCreate, PermLoanRead, PermLoanRepay, PermLoanApprove, PermLoanDisburse,
+		PermLoanWriteOff, PermLoanRecover,
+		PermProductManage, PermProductRead,
+		PermLedgerRead, PermInterestRun, PermCollectionRun, PermCredentialsManage,
+	),
+	auth.RoleBankAdmin: permissions(
+		PermBankRead, PermBankUpdate,
+		PermBranchCreate, PermBranchRead, PermBranchUpdate,
+		PermCustomerCreate, PermCustomerRead, PermCustomerUpdate,
+		PermAccountOpen, PermAccountRead, PermAccountTransact, PermAccountManage,
+		PermTransferCreate, PermTransferRead,
+		PermLoanCreate, PermLoanRead, PermLoanRepay, PermLoanApprove, PermLoanDisburse,
+		PermLoanWriteOff, PermLoanRecover,
+		PermProductManage, PermProductRead,
+		PermCredentialsManage,
+	),
+	auth.RoleBranchManager: permissions(
+		PermBankRead, PermBranchRead, PermBranchUpdate,
+		PermCustomerCreate, PermCustomerRead, PermCustomerUpdate,
+		PermAccountOpen, PermAccountRead, PermAccountTransact, PermAccountManage,
+		PermTransferCreate, PermTransferRead,
+		PermLoanCreate, PermLoanRead, PermLoanRepay, PermLoanApprove, PermLoanDisburse,
+		PermLoanRecover,
+		PermProductRead,
+	),
+	auth.RoleTeller: permissions(
+		PermBankRead, PermBranchRead,
+		PermCustomerCreate, PermCustomerRead, PermCustomerUpdate,
+		PermAccountOpen, PermAccountRead, PermAccountTransact,
+		PermTransferCreate, PermTransferRead,
+		PermLoanCreate, PermLoanRead, PermLoanRepay, PermLoanDisburse,
+		PermProductRead,
+	),
+	auth.RoleAuditor: permissions(readPermissions...),
+	auth.RoleCustomer: permissions(
+		PermCustomerRead, PermAccountRead,
+		PermTransferCreate, PermTransferRead,
+		PermLoanRead, PermLoanRepay,
+	),
+}
+
+const (
+	resourceGlobal    = "global"
+	resourceBank      = "bank"
+	resourceBranch    = "branch"
+	resourceCustomer  = "customer"
+	resourceAccount   = "account"
+	resourceLoan      = "loan"
+	resourceTransfer  = "transfer"
+	resourceProduct   = "product"
+	resourceBenchmark = "benchmark"
+)
+
+// Resource identifies what a request acts on, for Authorize.
+type Resource struct {
+	kind       string
+	id         uint
+	transferID string
+}
+
+// GlobalResource is for actions that are not tied to one bank, such as
+// creating a bank or reading the general ledger.
+func GlobalResource() Resource            { return Resource{kind: resourceGlobal} }
+func BankResource(id uint) Resource       { return Resource{kind: resourceBank, id: id} }
+func BranchResource(id uint) Resource     { return Resource{kind: resourceBranch, id: id} }
+func CustomerResource(id uint) Resource   { return Resource{kind: resourceCustomer, id: id} }
+func AccountResource(id uint) Resource    { return Resource{kind: resourceAccount, id: id} }
+func LoanResource(id uint) Resource       { return Resource{kind: resourceLoan, id: id} }
+func TransferResource(id string) Resource { return Resource{kind: resourceTransfer, transferID: id} }
+func ProductResource(id uint) Resource    { return Resource{kind: resourceProduct, id: id} }
+func BenchmarkResource(id uint) Resource  { return Resource{kind: resourceBenchmark, id: id} }
+
+// Grant is the role and scope given to an API key or user token.
+type Grant struct {
+	Role       string `json:"role"`
+	BankID     *uint  `json:"bank_id,omitempty"`
+	BranchID   *uint  `json:"branch_id,omitempty"`
+	CustomerID *uint  `json:"customer_id,omitempty"`
+}
+
+// scope lists the banks, branches and customers a resource belongs to. A
+// joint account belongs to the branches of all its holders.
+type scope struct {
+	banks     []uint
+	branches  []uint
+	customers []uint
+}
+
+func (s *scope) addCustomer(customerID, branchID, bankID uint) {
+	s.customers = appendUnique(s.customers, customerID)
+	s.branches = appendUnique(s.branches, branchID)
+	s.banks = appendUnique(s.banks, bankID)
+}
+
+func (s *scope) merge(other scope) {
+	for _, id := range other.customers {
+		s.customers = appendUnique(s.customers, id)
+	}
+	for _, id := range other.branches {
+		s.branches = appendUnique(s.branches, id)
+	}
+	for _, id := range other.banks {
+		s.banks = appendUnique(s.banks, id)
+	}
+}
+
+// allows reports whether principal's scope covers s. Unscoped staff (system
+// admins and global auditors) cover everything; branch staff cover their
+// branch and, for bank-level resources, their bank; customers cover only
+// what they are linked to.
+func (s scope) allows(p *auth.Principal) bool {
+	if p.Role == auth.RoleCustomer {
+		return p.CustomerID != nil && contains(s.customers, *p.CustomerID)
+	}
+	switch {
+	case p.BranchID != nil:
+		if len(s.branches) > 0 {
+			return contains(s.branches, *p.BranchID)
+		}
+		return p.BankID != nil && contains(s.banks, *p.BankID)
+	case p.BankID != nil:
+		return contains(s.banks, *p.BankID)
+	}
+	return true
+}
+
+func appendUnique(ids []uint, id uint) []uint {
+	if contains(ids, id) {
+		return ids
+	}
+	return append(ids, id)
+}
+
+func contains(ids []uint, id uint) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// AccessService is the policy layer: it decides whether a principal may
+// perform an action on a resource.
+type AccessService struct {
+	store repository.Store
+}
+
+func NewAccessService(store repository.Store) *AccessService {
+	return &AccessService{store: store}
+}
+
+// Authorize returns ErrForbidden unless principal's role grants permission
+// and its scope covers resource. A missing resource is reported as not
+// found.
+func (as *AccessService) Authorize(principal *auth.Principal, permission Permission, resource Resource) error {
+	if principal == nil {
+		return ErrUnauthenticated
+	}
+	if !rolePermissions[principal.Role][permission] {
+		return fmt.Errorf("%w: role %q may not %s", ErrForbidden, principal.Role, permission)
+	}
+	s, err := as.resolve(resource)
+	if err != nil {
+		return err
+	}
+	if !s.allows(principal) {
+		return fmt.Errorf("%w: %s is outside your scope", ErrForbidden, resource.kind)
+	}
+	return nil
+}
+
+// ListScope restricts a collection query to what a principal may see. Nil
+// fields do not restrict.
+type ListScope struct {
+	BankID     *uint
+	BranchID   *uint
+	CustomerID *uint
+}
+
+// AuthorizeList checks that principal's role grants permission and returns
+// the scope its collection queries are limited to.
+func (as *AccessService) AuthorizeList(principal *auth.Principal, permission Permission) (ListScope, error) {
+	if principal == nil {
+		return ListScope{}, ErrUnauthenticated
+	}
+	if !rolePermissions[principal.Role][permission] {
+		return ListScope{}, fmt.Errorf("%w: role %q may not %s", ErrForbidden, principal.Role, permission)
+	}
+	if principal.Role == auth.RoleCustomer {
+		return ListScope{CustomerID: principal.CustomerID}, nil
+	}
+	return ListScope{BankID: principal.BankID, BranchID: principal.BranchID}, nil
+}
+
+// narrow combines a filter the caller asked for with the one its scope
+// imposes. Asking for something outside the scope is forbidden rather than
+// silently empty.
+func narrow(requested, scoped *uint) (*uint, error) {
+	switch {
+	case scoped == nil:
+		return requested, nil
+	case requested == nil || *requested == *scoped:
+		return scoped, nil
+	}
+	return nil, fmt.Errorf("%w: filter is outside your scope", ErrForbidden)
+}
+
+// AuthorizeGrant checks that principal may hand out grant and returns it
+// with its scope filled in (a branch implies its bank, a customer its branch
+// and bank). Principals can only grant within their own scope.
+func (as *AccessService) AuthorizeGrant(principal *auth.Principal, grant Grant) (*Grant, error) {
+	normalized, err := as.NormalizeGrant(grant)
+	if err != nil {
+		return nil, err
+	}
+	if !rolePermissions[principal.Role][PermCredentialsManage] {
+		return nil, fmt.Errorf("%w: role %q may not manage credentials", ErrForbidden, principal.Role)
+	}
+	if principal.BankID != nil && (normalized.BankID == nil || *normalized.BankID != *principal.BankID) {
+		return nil, fmt.Errorf("%w: grant is outside your scope", ErrForbidden)
+	}
+	return normalized, nil
+}
+
+// NormalizeGrant validates that grant's scope suits its role and fills in
+// the enclosing branch and bank.
+func (as *AccessService) NormalizeGrant(grant Grant) (*Grant, error) {
+	if _, ok := rolePermissions[grant.Role]; !ok {
+		return nil, fmt.Errorf("%w: unknown role %q", ErrInvalidArgument, grant.Role)
+	}
+	normalized := Grant{Role: grant.Role}
+
+	switch {
+	case grant.CustomerID != nil:
+		customer, err := as.store.Customers().Get(*grant.CustomerID)
+		if err != nil {
+			return nil, lookupError("customer", err)
+		}
+		branch, err := as.store.Branches().Get(customer.BranchID)
+		if err != nil {
+			return nil, lookupError("branch", err)
+		}
+		normalized.CustomerID = &customer.ID
+		normalized.BranchID = &branch.ID
+		normalized.BankID = &branch.BankID
+	case grant.BranchID != nil:
+		branch, err := as.store.Branches().Get(*grant.BranchID)
+		if err != nil {
+			return nil, lookupError("branch", err)
+		}
+		normalized.BranchID = &branch.ID
+		normalized.BankID = &branch.BankID
+	case grant.BankID != nil:
+		bank, err := as.store.Banks().Get(*grant.BankID)
+		if err != nil {
+			return nil, lookupError("bank", err)
+		}
+		normalized.BankID = &bank.ID
+	}
+	if grant.BankID != nil && *grant.BankID != *normalized.BankID {
+		return nil, fmt.Errorf("%w: bank_id does not match the branch or customer", ErrInvalidArgument)
+	}
+	if grant.BranchID != nil && *grant.BranchID != *normalized.BranchID {
+		return nil, fmt.Errorf("%w: branch_id does not match the customer", ErrInvalidArgument)
+	}
+
+	var valid bool
+	switch grant.Role {
+	case auth.RoleSystemAdmin:
+		valid = normalized.BankID == nil
+	case auth.RoleBankAdmin:
+		valid = normalized.BankID != nil && normalized.BranchID == nil
+	case auth.RoleBranchManager, auth.RoleTeller:
+		valid = normalized.BranchID != nil && normalized.CustomerID == nil
+	case auth.RoleAuditor:
+		valid = normalized.CustomerID == nil
+	case auth.RoleCustomer:
+		valid = normalized.CustomerID != nil
+	}
+	if !valid {
+		return nil, fmt.Errorf("%w: scope does not suit role %q", ErrInvalidArgument, grant.Role)
+	}
+	return &normalized, nil
+}
+
+func (as *AccessService) resolve(resource Resource) (scope, error) {
+	switch resource.kind {
+	case resourceGlobal:
+		return scope{}, nil
+	case resourceBank:
+		bank, err := as.store.Banks().Get(resource.id)
+		if err != nil {
+			return scope{}, lookupError("bank", err)
+		}
+		return scope{banks: []uint{bank.ID}}, nil
+	case resourceBranch:
+		branch, err := as.store.Branches().Get(resource.id)
+		if err != nil {
+			return scope{}, lookupError("branch", err)
+		}
+		return scope{banks: []uint{branch.BankID}, branches: []uint{branch.ID}}, nil
+	case resourceCustomer:
+		return as.customerScope(resource.id)
+	case resourceAccount:
+		return as.accountScope(resource.id)
+	case resourceLoan:
+		loan, err := as.store.Loans().Get(resource.id)
+		if err != nil {
+			return scope{}, lookupError("loan", err)
+		}
+		return as.customerScope(loan.CustomerID)
+	case resourceProduct:
+		product, err := as.store.LoanProducts().Get(resource.id)
+		if err != nil {
+			return scope{}, lookupError("loan product", err)
+		}
+		return scope{banks: []uint{product.BankID}}, nil
+	case resourceBenchmark:
+		benchmark, err := as.store.Benchmarks().Get(resource.id)
+		if err != nil {
+			return scope{}, lookupError("benchmark", err)
+		}
+		return scope{banks: []uint{benchmark.BankID}}, nil
+	case resourceTransfer:
+		transactions, err := as.store.Transactions().ListByTransfer(resource.transferID)
+		if err != nil {
+			return scope{}, err
+		}
+		if len(transactions) == 0 {
+			return scope{}, notFound("transfer")
+		}
+		var s scope
+		for _, t := range transactions {
+			accountScope, err := as.accountScope(t.AccountID)
+			if err != nil {
+				return scope{}, err
+			}
+			s.merge(accountScope)
+		}
+		return s, nil
+	}
+	return scope{}, fmt.Errorf("unknown resource kind %q", resource.kind)
+}
+
+func (as *AccessService) customerScope(customerID uint) (scope, error) {
+	customer, err := as.store.Customers().Get(customerID)
+	if err != nil {
+		return scope{}, lookupError("customer", err)
+	}
+	var s scope
+	s.addCustomer(customer.ID, customer.BranchID, customer.Branch.BankID)
+	return s, nil
+}
+
+func (as *AccessService) accountScope(accountID uint) (scope, error) {
+	if _, err := as.store.Accounts().Get(accountID); err != nil {
+		return scope{}, lookupError("account", err)
+	}
+	holders, err := as.store.Accounts().ListHolders(accountID)
+	if err != nil {
+		return scope{}, err
+	}
+	var s scope
+	for _, holder := range holders {
+		customerScope, err := as.customerScope(holder.CustomerID)
+		if err != nil {
+			return scope{}, err
+		}
+		s.merge(customerScope)
+	}
+	return s, nil
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"banking-system/auth"
+	"banking-system/models"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// accessFixture adds to fixture a second branch of the same bank and a
+// second bank, each with a customer and an account, plus an account bob
+// holds jointly with alice.
+type accessFixture struct {
+	*fixture
+	otherBranch models.Branch
+	otherBank   models.Bank
+	bob, carol  models.Customer
+
+	aliceAccount, bobAccount, jointAccount, carolAccount models.SavingsAccount
+}
+
+func newAccessFixture(t *testing.T) *accessFixture {
+	t.Helper()
+	f := &accessFixture{fixture: newFixture(t)}
+	f.otherBranch = models.Branch{BankID: f.bank.ID, Name: "North"}
+	f.must(NewBranchService(f.store).CreateBranch(&f.otherBranch))
+	f.otherBank = models.Bank{Name: "Other Bank"}
+	f.must(NewBankService(f.store).CreateBank(&f.otherBank))
+	otherBankBranch := models.Branch{BankID: f.otherBank.ID, Name: "Main"}
+	f.must(NewBranchService(f.store).CreateBranch(&otherBankBranch))
+
+	f.bob = f.newCustomer(f.otherBranch.ID, "bob@example.com")
+	f.carol = f.newCustomer(otherBankBranch.ID, "carol@example.com")
+	f.aliceAccount = f.openAccount(f.customer.ID, 0)
+	f.bobAccount = f.openAccount(f.bob.ID, 0)
+	f.jointAccount = f.openAccount(f.bob.ID, 0)
+	_, err := NewAccountService(f.store).AddAccountHolder(f.jointAccount.ID, f.customer.ID, "joint_holder")
+	f.must(err)
+	f.carolAccount = f.openAccount(f.carol.ID, 0)
+	return f
+}
+
+func (f *accessFixture) principal(role string) *auth.Principal {
+	p := &auth.Principal{Type: auth.PrincipalUser, Subject: role, Role: role}
+	switch role {
+	case auth.RoleBankAdmin, auth.RoleAuditor:
+		p.BankID = &f.bank.ID
+	case auth.RoleBranchManager, auth.RoleTeller:
+		p.BankID, p.BranchID = &f.bank.ID, &f.branch.ID
+	case auth.RoleCustomer:
+		p.BankID, p.BranchID, p.CustomerID = &f.bank.ID, &f.branch.ID, &f.customer.ID
+	}
+	return p
+}
+
+func TestAuthorize(t *testing.T) {
+	f := newAccessFixture(t)
+	access := NewAccessService(f.store)
+
+	tests := []struct {
+		name       string
+		role       string
+		permission Permission
+		resource   Resource
+		want       error
+	}{
+		{"system admin creates a bank", auth.RoleSystemAdmin, PermBankCreate, GlobalResource(), nil},
+		{"system admin updates a customer of any bank", auth.RoleSystemAdmin, PermCustomerUpdate, CustomerResource(f.carol.ID), nil},
+		{"system admin on a missing account", auth.RoleSystemAdmin, PermAccountRead, AccountResource(9999), ErrNotFound},
+
+		{"bank admin updates a customer at another branch of its bank", auth.RoleBankAdmin, PermCustomerUpdate, CustomerResource(f.bob.ID), nil},
+		{"bank admin updates a customer of another bank", auth.RoleBankAdmin, PermCustomerUpdate, CustomerResource(f.carol.ID), ErrForbidden},
+		{"bank admin creates a bank", auth.RoleBankAdmin, PermBankCreate, GlobalResource(), ErrForbidden},
+
+		{"branch manager manages an account at its branch", auth.RoleBranchManager, PermAccountManage, AccountResource(f.aliceAccount.ID), nil},
+		{"branch manager reads its bank", auth.RoleBranchManager, PermBankRead, BankResource(f.bank.ID), nil},
+		{"branch manager updates another branch", auth.RoleBranchManager, PermBranchUpdate, BranchResource(f.otherBranch.ID), ErrForbidden},
+		{"branch manager manages an account at another branch", auth.RoleBranchManager, PermAccountManage, AccountResource(f.bobAccount.ID), ErrForbidden},
+
+		{"teller updates a customer at its branch", auth.RoleTeller, PermCustomerUpdate, CustomerResource(f.customer.ID), nil},
+		{"teller reads a joint account with a holder at its branch", auth.RoleTeller, PermAccountRead, AccountResource(f.jointAccount.ID), nil},
+		{"teller updates a customer at another branch", auth.RoleTeller, PermCustomerUpdate, CustomerResource(f.bob.ID), ErrForbidden},
+		{"teller manages an account", auth.RoleTeller, PermAccountManage, AccountResource(f.aliceAccount.ID), ErrForbidden},
+
+		{"auditor reads an account anywhere in its bank", auth.RoleAuditor, PermAccountRead, AccountResource(f.bobAccount.ID), nil},
+		{"auditor reads an account of another bank", auth.RoleAuditor, PermAccountRead, AccountResource(f.carolAccount.ID), ErrForbidden},
+		{"auditor updates a customer", auth.RoleAuditor, PermCustomerUpdate, CustomerResource(f.customer.ID), ErrForbidden},
+
+		{"customer reads its own account", auth.RoleCustomer, PermAccountRead, AccountResource(f.aliceAccount.ID), nil},
+		{"customer reads a joint account it holds", auth.RoleCustomer, PermAccountRead, AccountResource(f.jointAccount.ID), nil},
+		{"customer reads someone else's account", auth.RoleCustomer, PermAccountRead, AccountResource(f.bobAccount.ID), ErrForbidden},
+		{"customer updates its own record", auth.RoleCustomer, PermCustomerUpdate, CustomerResource(f.customer.ID), ErrForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := access.Authorize(f.principal(tt.role), tt.permission, tt.resource)
+			if tt.want == nil && err != nil || tt.want != nil && !errors.Is(err, tt.want) {
+				t.Fatalf("Authorize = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	if err := access.Authorize(nil, PermBankRead, BankResource(f.bank.ID)); !errors.Is(err, ErrUnauthenticated) {
+		t.Fatalf("Authorize without a principal = %v, want %v", err, ErrUnauthenticated)
+	}
+}
+
+func TestAuthorizeList(t *testing.T) {
+	f := newAccessFixture(t)
+	access := NewAccessService(f.store)
+
+	tests := []struct {
+		role       string
+		permission Permission
+		want       ListScope
+		err        error
+	}{
+		{auth.RoleSystemAdmin, PermLedgerRead, ListScope{}, nil},
+		{auth.RoleBankAdmin, PermAccountRead, ListScope{BankID: &f.bank.ID}, nil},
+		{auth.RoleBankAdmin, PermLedgerRead, ListScope{}, ErrForbidden},
+		{auth.RoleBranchManager, PermLoanRead, ListScope{BankID: &f.bank.ID, BranchID: &f.branch.ID}, nil},
+		{auth.RoleBranchManager, PermLedgerRead, ListScope{}, ErrForbidden},
+		{auth.RoleTeller, PermCustomerRead, ListScope{BankID: &f.bank.ID, BranchID: &f.branch.ID}, nil},
+		{auth.RoleTeller, PermLedgerRead, ListScope{}, ErrForbidden},
+		{auth.RoleAuditor, PermLedgerRead, ListScope{BankID: &f.bank.ID}, nil},
+		{auth.RoleAuditor, PermInterestRun, ListScope{}, ErrForbidden},
+		{auth.RoleCustomer, PermAccountRead, ListScope{CustomerID: &f.customer.ID}, nil},
+		{auth.RoleCustomer, PermBankRead, ListScope{}, ErrForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.role+" "+string(tt.permission), func(t *testing.T) {
+			got, err := access.AuthorizeList(f.principal(tt.role), tt.permission)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("AuthorizeList = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("AuthorizeList = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCustomerListsOnlyLinkedAccounts(t *testing.T) {
+	f := newAccessFixture(t)
+	access := NewAccessService(f.store)
+	accounts := NewAccountService(f.store)
+
+	scope, err := access.AuthorizeList(f.principal(auth.RoleCustomer), PermAccountRead)
+	f.must(err)
+	page, err := accounts.ListAccounts(AccountQuery{Scope: scope})
+	f.must(err)
+	var got []uint
+	for _, account := range page.Items {
+		got = append(got, account.ID)
+	}
+	slices.Sort(got)
+	if want := []uint{f.aliceAccount.ID, f.jointAccount.ID}; !slices.Equal(got, want) {
+		t.Fatalf("customer sees accounts %v, want %v", got, want)
+	}
+
+	if _, err := accounts.ListAccounts(AccountQuery{CustomerID: &f.bob.ID, Scope: scope}); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("listing another customer's accounts = %v, want %v", err, ErrForbidden)
+	}
+}
@@ -0,0 +1,279 @@
+package services
+
+import (
+	"banking-system/models"
+	"banking-system/repository"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	AccountStatusActive  = "ACTIVE"
+	AccountStatusFrozen  = "FROZEN"
+	AccountStatusDormant = "DORMANT"
+	AccountStatusClosed  = "CLOSED"
+)
+
+const TransactionClosure = "closure_settlement"
+
+// accountTransitions lists the statuses each status may move to. CLOSED is
+// final.
+var accountTransitions = map[string][]string{
+	AccountStatusActive:  {AccountStatusFrozen, AccountStatusDormant, AccountStatusClosed},
+	AccountStatusFrozen:  {AccountStatusActive},
+	AccountStatusDormant: {AccountStatusActive, AccountStatusFrozen, AccountStatusClosed},
+}
+
+// accountStatusReasons lists the reason codes accepted for moving an account
+// into each status.
+var accountStatusReasons = map[string][]string{
+	AccountStatusActive:  {"customer_request", "customer_activity", "investigation_cleared", "kyc_completed"},
+	AccountStatusFrozen:  {"court_order", "fraud_suspected", "kyc_pending", "customer_request"},
+	AccountStatusDormant: {"inactivity"},
+	AccountStatusClosed:  {"customer_request", "bank_initiated", "deceased", "inactivity"},
+}
+
+// StatusChange says why an account is moving to a new status and who moved
+// it.
+type StatusChange struct {
+	Reason    string
+	Note      string
+	ChangedBy string
+}
+
+// AccountClosure is the result of closing an account. Settlement is the
+// transaction that emptied it, if it held a balance.
+type AccountClosure struct {
+	Account      models.SavingsAccount      `json:"account"`
+	Settlement   *models.Transaction        `json:"settlement,omitempty"`
+	StatusChange models.AccountStatusChange `json:"status_change"`
+}
+
+// canDebit reports whether money may leave the account. Only active accounts
+// can be debited.
+func canDebit(account *models.SavingsAccount) error {
+	if account.Status != AccountStatusActive {
+		return accountInactive(account)
+	}
+	return nil
+}
+
+// canCredit reports whether money may enter the account. Frozen and dormant
+// accounts still accept credits; closed accounts accept nothing.
+func canCredit(account *models.SavingsAccount) error {
+	if account.Status == AccountStatusClosed {
+		return accountInactive(account)
+	}
+	return nil
+}
+
+func accountInactive(account *models.SavingsAccount) error {
+	return fmt.Errorf("%w: account %d is %s", ErrAccountInactive, account.ID, account.Status)
+}
+
+func checkTransition(account *models.SavingsAccount, status, reason string) error {
+	if !slices.Contains(accountTransitions[account.Status], status) {
+		return fmt.Errorf("%w: account %d cannot move from %s to %s", ErrConflict, account.ID, account.Status, status)
+	}
+	reasons := accountStatusReasons[status]
+	if !slices.Contains(reasons, reason) {
+		return fmt.Errorf("%w: reason for %s must be one of %s", ErrInvalidArgument, status, strings.Join(reasons, ", "))
+	}
+	return nil
+}
+
+// Freeze blocks debits on an active or dormant account.
+func (as *AccountService) Freeze(accountID uint, change StatusChange) (*models.SavingsAccount, error) {
+	return as.changeStatus(accountID, "", AccountStatusFrozen, change)
+}
+
+func (as *AccountService) Unfreeze(accountID uint, change StatusChange) (*models.SavingsAccount, error) {
+	return as.changeStatus(accountID, AccountStatusFrozen, AccountStatusActive, change)
+}
+
+func (as *AccountService) MarkDormant(accountID uint, change StatusChange) (*models.SavingsAccount, error) {
+	return as.changeStatus(accountID, "", AccountStatusDormant, change)
+}
+
+func (as *AccountService) Reactivate(accountID uint, change StatusChange) (*models.SavingsAccount, error) {
+	return as.changeStatus(accountID, AccountStatusDormant, AccountStatusActive, change)
+}
+
+// changeStatus moves the account to status. When from is set the account
+// must currently be in it, so that unfreezing cannot wake a dormant account.
+func (as *AccountService) changeStatus(accountID uint, from, status string, change StatusChange) (*models.SavingsAccount, error) {
+	var account *models.SavingsAccount
+	err := as.store.Atomic(func(tx repository.Store) error {
+		var err error
+		account, err = tx.Accounts().GetForUpdate(accountID)
+		if err != nil {
+			return lookupError("account", err)
+		}
+		if from != "" && account.Status != from {
+			return fmt.Errorf("%w: account %d is %s, not %s", ErrConflict, account.ID, account.Status, from)
+		}
+		if err := checkTransition(account, status, change.Reason); err != nil {
+			return err
+		}
+		_, err = as.recordStatus(tx, account, status, change, nil)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// CloseAccount closes an active or dormant account. Accrued interest is
+// posted and the remaining balance settled first, by transfer to
+// settlementAccountID when it is set and as a cash payout otherwise, so the
+// account always closes at zero.
+func (as *AccountService) CloseAccount(accountID uint, change StatusChange, settlementAccountID *uint) (*AccountClosure, error) {
+	if settlementAccountID != nil && *settlementAccountID == accountID {
+		return nil, fmt.Errorf("%w: cannot settle an account into itself", ErrInvalidArgument)
+	}
+
+	var closure AccountClosure
+	err := as.store.Atomic(func(tx repository.Store) error {
+		account, target, err := lockForClosure(tx, accountID, settlementAccountID)
+		if err != nil {
+			return err
+		}
+		if err := checkTransition(account, AccountStatusClosed, change.Reason); err != nil {
+			return err
+		}
+		if err := as.postPendingInterest(tx, account); err != nil {
+			return err
+		}
+
+		var entryID *uint
+		if account.Balance.IsPositive() {
+			settlement, err := as.settle(tx, account, target)
+			if err != nil {
+				return err
+			}
+			closure.Settlement = settlement
+			entryID = settlement.JournalEntryID
+		}
+
+		now := time.Now()
+		account.ClosedAt = &now
+		statusChange, err := as.recordStatus(tx, account, AccountStatusClosed, change, entryID)
+		if err != nil {
+			return err
+		}
+		closure.Account = *account
+		closure.StatusChange = *statusChange
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &closure, nil
+}
+
+// lockForClosure locks the closing account and the settlement account, if
+// any, in ascending id order like Transfer does.
+func lockForClosure(tx repository.Store, accountID uint, settlementAccountID *uint) (*models.SavingsAccount, *models.SavingsAccount, error) {
+	ids := []uint{accountID}
+	if settlementAccountID != nil {
+		ids = append(ids, *settlementAccountID)
+		slices.Sort(ids)
+	}
+	locked := map[uint]*models.SavingsAccount{}
+	for _, id := range ids {
+		account, err := tx.Accounts().GetForUpdate(id)
+		if err != nil {
+			return nil, nil, lookupError("account", err)
+		}
+		locked[id] = account
+	}
+	if settlementAccountID == nil {
+		return locked[accountID], nil, nil
+	}
+	return locked[accountID], locked[*settlementAccountID], nil
+}
+
+// settle empties account, moving its balance to target or paying it out in
+// cash, and returns the debit on account.
+func (as *AccountService) settle(tx repository.Store, account, target *models.SavingsAccount) (*models.Transaction, error) {
+	amount := account.Balance
+	account.Balance = 0
+
+	debit := models.Transaction{AccountID: account.ID, Type: TransactionClosure, Amount: amount}
+	if target == nil {
+		entry, err := as.ledger.RecordClosureSettlement(tx, account.ID, amount)
+		if err != nil {
+			return nil, err
+		}
+		debit.JournalEntryID = &entry.ID
+		if err := tx.Transactions().Create(&debit); err != nil {
+			return nil, err
+		}
+		return &debit, nil
+	}
+
+	if err := canCredit(target); err != nil {
+		return nil, err
+	}
+	transferID, err := newTransferID()
+	if err != nil {
+		return nil, err
+	}
+	target.Balance += amount
+	if err := tx.Accounts().Update(target); err != nil {
+		return nil, err
+	}
+	entry, err := as.ledger.RecordTransfer(tx, account.ID, target.ID, amount)
+	if err != nil {
+		return nil, err
+	}
+	debit.Type = TransactionTransferOut
+	debit.TransferID = transferID
+	debit.JournalEntryID = &entry.ID
+	credit := models.Transaction{
+		AccountID:      target.ID,
+		Type:           TransactionTransferIn,
+		Amount:         amount,
+		TransferID:     transferID,
+		JournalEntryID: &entry.ID,
+	}
+	if err := tx.Transactions().Create(&debit); err != nil {
+		return nil, err
+	}
+	if err := tx.Transactions().Create(&credit); err != nil {
+		return nil, err
+	}
+	return &debit, nil
+}
+
+// recordStatus moves the locked account to status, saves it and appends the
+// change to its history.
+func (as *AccountService) recordStatus(tx repository.Store, account *models.SavingsAccount, status string, change StatusChange, entryID *uint) (*models.AccountStatusChange, error) {
+	statusChange := models.AccountStatusChange{
+		AccountID:      account.ID,
+		FromStatus:     account.Status,
+		ToStatus:       status,
+		Reason:         change.Reason,
+		Note:           change.Note,
+		ChangedBy:      change.ChangedBy,
+		JournalEntryID: entryID,
+	}
+	account.Status = status
+	if err := tx.Accounts().Update(account); err != nil {
+		return nil, err
+	}
+	if err := tx.Accounts().AddStatusChange(&statusChange); err != nil {
+		return nil, err
+	}
+	return &statusChange, nil
+}
+
+func (as *AccountService) GetStatusHistory(accountID uint) ([]models.AccountStatusChange, error) {
+	if _, err := as.store.Accounts().Get(accountID); err != nil {
+		return nil, lookupError("account", err)
+	}
+	return as.store.Accounts().StatusHistory(accountID)
+}
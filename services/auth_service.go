@@ -0,0 +1,182 @@
+package services
+
+import (
+	"banking-system/auth"
+	"banking-system/models"
+	"banking-system/repository"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// APIKeyPrefix starts every issued key so that keys are easy to spot in
+// logs and secret scanners.
+const APIKeyPrefix = "bk_"
+
+const apiKeyDisplayLength = len(APIKeyPrefix) + 8
+
+type AuthService struct {
+	store  repository.Store
+	tokens *auth.Tokens
+}
+
+func NewAuthService(store repository.Store, tokens *auth.Tokens) *AuthService {
+	return &AuthService{store: store, tokens: tokens}
+}
+
+// IssuedToken is a signed JWT together with the claims it carries.
+type IssuedToken struct {
+	Token   string `json:"token"`
+	Subject string `json:"subject"`
+	Grant
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// IssueAPIKey creates a key with grant's role and scope and returns its
+// record and the plaintext key. The plaintext is not stored and cannot be
+// recovered later. grant must already be normalized and authorized.
+func (as *AuthService) IssueAPIKey(name, createdBy string, grant Grant) (*models.APIKey, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", fmt.Errorf("%w: name is required", ErrInvalidArgument)
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", err
+	}
+	plaintext := APIKeyPrefix + hex.EncodeToString(secret)
+
+	key := models.APIKey{
+		Name:       name,
+		Prefix:     plaintext[:apiKeyDisplayLength],
+		KeyHash:    hashAPIKey(plaintext),
+		Role:       grant.Role,
+		BankID:     grant.BankID,
+		BranchID:   grant.BranchID,
+		CustomerID: grant.CustomerID,
+		CreatedBy:  createdBy,
+	}
+	if err := as.store.APIKeys().Create(&key); err != nil {
+		return nil, "", storeError(err)
+	}
+	return &key, plaintext, nil
+}
+
+// ListAPIKeys returns every key, or only the keys scoped to bankID when it
+// is set.
+func (as *AuthService) ListAPIKeys(bankID *uint) ([]models.APIKey, error) {
+	keys, err := as.store.APIKeys().List()
+	if err != nil || bankID == nil {
+		return keys, err
+	}
+	scoped := []models.APIKey{}
+	for _, key := range keys {
+		if key.BankID != nil && *key.BankID == *bankID {
+			scoped = append(scoped, key)
+		}
+	}
+	return scoped, nil
+}
+
+func (as *AuthService) GetAPIKey(id uint) (*models.APIKey, error) {
+	key, err := as.store.APIKeys().Get(id)
+	if err != nil {
+		return nil, lookupError("api key", err)
+	}
+	return key, nil
+}
+
+// RevokeAPIKey stops a key from authenticating. Revoking twice is harmless.
+func (as *AuthService) RevokeAPIKey(id uint) (*models.APIKey, error) {
+	key, err := as.store.APIKeys().Get(id)
+	if err != nil {
+		return nil, lookupError("api key", err)
+	}
+	if key.RevokedAt == nil {
+		now := time.Now()
+		key.RevokedAt = &now
+		if err := as.store.APIKeys().Update(key); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+func (as *AuthService) AuthenticateAPIKey(plaintext string) (*auth.Principal, error) {
+	if !strings.HasPrefix(plaintext, APIKeyPrefix) {
+		return nil, ErrUnauthenticated
+	}
+	key, err := as.store.APIKeys().GetByHash(hashAPIKey(plaintext))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrUnauthenticated
+	}
+	if err != nil {
+		return nil, err
+	}
+	if key.RevokedAt != nil {
+		return nil, fmt.Errorf("%w: api key has been revoked", ErrUnauthenticated)
+	}
+	return &auth.Principal{
+		Type:       auth.PrincipalAPIKey,
+		Subject:    fmt.Sprintf("api_key:%d", key.ID),
+		Name:       key.Name,
+		APIKeyID:   key.ID,
+		Role:       key.Role,
+		BankID:     key.BankID,
+		BranchID:   key.BranchID,
+		CustomerID: key.CustomerID,
+	}, nil
+}
+
+// IssueToken signs a user token carrying grant. Tokens are minted by trusted
+// service clients (for example a login frontend) on behalf of the user they
+// authenticated; grant must already be normalized and authorized.
+func (as *AuthService) IssueToken(subject, name string, grant Grant) (*IssuedToken, error) {
+	subject = strings.TrimSpace(subject)
+	if subject == "" {
+		return nil, fmt.Errorf("%w: subject is required", ErrInvalidArgument)
+	}
+	token, claims, err := as.tokens.Issue(auth.Claims{
+		Subject:    subject,
+		Name:       name,
+		Role:       grant.Role,
+		BankID:     grant.BankID,
+		BranchID:   grant.BranchID,
+		CustomerID: grant.CustomerID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &IssuedToken{
+		Token:     token,
+		Subject:   claims.Subject,
+		Grant:     grant,
+		ExpiresAt: time.Unix(claims.ExpiresAt, 0).UTC(),
+	}, nil
+}
+
+func (as *AuthService) AuthenticateToken(token string) (*auth.Principal, error) {
+	claims, err := as.tokens.Verify(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnauthenticated, err)
+	}
+	return &auth.Principal{
+		Type:       auth.PrincipalUser,
+		Subject:    claims.Subject,
+		Name:       claims.Name,
+		Role:       claims.Role,
+		BankID:     claims.BankID,
+		BranchID:   claims.BranchID,
+		CustomerID: claims.CustomerID,
+	}, nil
+}
+
+func hashAPIKey(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:])
+}
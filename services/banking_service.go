@@ -1,283 +1,757 @@
 package services
 
 import (
-	"banking-system/config"
+	"banking-system/interest"
 	"banking-system/models"
+	"banking-system/money"
+	"banking-system/repository"
 	"errors"
+	"fmt"
+	"math/big"
 	"time"
 )
 
-type BankService struct{}
+const (
+	TransactionDeposit       = "deposit"
+	TransactionWithdraw      = "withdraw"
+	TransactionLoanRepayment = "loan_repayment"
+)
+
+const (
+	LoanStatusActive     = "ACTIVE"
+	LoanStatusClosed     = "CLOSED"
+	LoanStatusWrittenOff = "WRITTEN_OFF"
+)
+
+const DefaultHolderRole = "primary_holder"
 
-func NewBankService() *BankService {
-	return &BankService{}
+type BankService struct {
+	store repository.Store
 }
 
-func (bs *BankService) CreateBank(name string) (*models.Bank, error) {
-	bank := models.Bank{
-		Name: name,
-	}
+func NewBankService(store repository.Store) *BankService {
+	return &BankService{store: store}
+}
+
+func (bs *BankService) CreateBank(bank *models.Bank) error {
+	return storeError(bs.store.Banks().Create(bank))
+}
 
-	if result := config.GetDB().Create(&bank); result.Error != nil {
-		return nil, result.Error
+func (bs *BankService) GetBankByID(id uint) (*models.Bank, error) {
+	bank, err := bs.store.Banks().Get(id)
+	if err != nil {
+		return nil, lookupError("bank", err)
 	}
+	return bank, nil
+}
 
-	return &bank, nil
+type BankQuery struct {
+	Name  string
+	Scope ListScope
+	ListQuery
 }
 
-func (bs *BankService) GetBankByID(id uint) (*models.Bank, error) {
-	var bank models.Bank
-	result := config.GetDB().Preload("Branches").First(&bank, id)
-	if result.Error != nil {
-		return nil, result.Error
+func (bs *BankService) GetAllBanks(query BankQuery) (*Page[models.Bank], error) {
+	opts, err := query.options("name", "created_at")
+	if err != nil {
+		return nil, err
+	}
+	filter := repository.BankFilter{ID: query.Scope.BankID, Name: query.Name, ListOptions: opts}
+	banks, err := bs.store.Banks().Find(filter)
+	if err != nil {
+		return nil, err
+	}
+	return newPage(banks, opts, query.ListQuery, func() (int64, error) { return bs.store.Banks().Count(filter) })
+}
+
+func (bs *BankService) UpdateBank(id uint, updates models.Bank) (*models.Bank, error) {
+	bank, err := bs.store.Banks().Get(id)
+	if err != nil {
+		return nil, lookupError("bank", err)
+	}
+	if updates.Name != "" {
+		bank.Name = updates.Name
 	}
-	return &bank, nil
+	if err := bs.store.Banks().Update(bank); err != nil {
+		return nil, storeError(err)
+	}
+	return bank, nil
 }
 
-type BranchService struct{}
+type BranchService struct {
+	store repository.Store
+}
 
-func NewBranchService() *BranchService {
-	return &BranchService{}
+func NewBranchService(store repository.Store) *BranchService {
+	return &BranchService{store: store}
 }
 
-func (bs *BranchService) CreateBranch(bankID uint, name, address string) (*models.Branch, error) {
-	var bank models.Bank
-	if result := config.GetDB().First(&bank, bankID); result.Error != nil {
-		return nil, errors.New("bank not found")
+func (bs *BranchService) CreateBranch(branch *models.Branch) error {
+	if _, err := bs.store.Banks().Get(branch.BankID); err != nil {
+		return lookupError("bank", err)
 	}
+	return storeError(bs.store.Branches().Create(branch))
+}
 
-	branch := models.Branch{
-		BankID:  bankID,
-		Name:    name,
-		Address: address,
+func (bs *BranchService) GetBranchByID(id uint) (*models.Branch, error) {
+	branch, err := bs.store.Branches().Get(id)
+	if err != nil {
+		return nil, lookupError("branch", err)
 	}
+	return branch, nil
+}
 
-	if result := config.GetDB().Create(&branch); result.Error != nil {
-		return nil, result.Error
+type BranchQuery struct {
+	BankID *uint
+	Name   string
+	Scope  ListScope
+	ListQuery
+}
+
+func (bs *BranchService) ListBranches(query BranchQuery) (*Page[models.Branch], error) {
+	opts, err := query.options("name", "created_at")
+	if err != nil {
+		return nil, err
+	}
+	bankID, err := narrow(query.BankID, query.Scope.BankID)
+	if err != nil {
+		return nil, err
+	}
+	filter := repository.BranchFilter{ID: query.Scope.BranchID, BankID: bankID, Name: query.Name, ListOptions: opts}
+	branches, err := bs.store.Branches().Find(filter)
+	if err != nil {
+		return nil, err
 	}
+	return newPage(branches, opts, query.ListQuery, func() (int64, error) { return bs.store.Branches().Count(filter) })
+}
 
-	return &branch, nil
+func (bs *BranchService) UpdateBranch(id uint, updates models.Branch) (*models.Branch, error) {
+	branch, err := bs.store.Branches().Get(id)
+	if err != nil {
+		return nil, lookupError("branch", err)
+	}
+	if updates.BankID != 0 {
+		if _, err := bs.store.Banks().Get(updates.BankID); err != nil {
+			return nil, lookupError("bank", err)
+		}
+		branch.BankID = updates.BankID
+	}
+	if updates.Name != "" {
+		branch.Name = updates.Name
+	}
+	if updates.Address != "" {
+		branch.Address = updates.Address
+	}
+	if err := bs.store.Branches().Update(branch); err != nil {
+		return nil, storeError(err)
+	}
+	return branch, nil
 }
 
-type CustomerService struct{}
+type CustomerService struct {
+	store repository.Store
+}
 
-func NewCustomerService() *CustomerService {
-	return &CustomerService{}
+func NewCustomerService(store repository.Store) *CustomerService {
+	return &CustomerService{store: store}
 }
 
-func (cs *CustomerService) RegisterCustomer(branchID uint, name, email, phone string) (*models.Customer, error) {
-	var branch models.Branch
-	if result := config.GetDB().First(&branch, branchID); result.Error != nil {
-		return nil, errors.New("branch not found")
+func (cs *CustomerService) RegisterCustomer(customer *models.Customer) error {
+	if _, err := cs.store.Branches().Get(customer.BranchID); err != nil {
+		return lookupError("branch", err)
 	}
+	return storeError(cs.store.Customers().Create(customer))
+}
 
-	customer := models.Customer{
-		BranchID: branchID,
-		Name:     name,
-		Email:    email,
-		Phone:    phone,
+func (cs *CustomerService) GetCustomerByID(id uint) (*models.Customer, error) {
+	customer, err := cs.store.Customers().Get(id)
+	if err != nil {
+		return nil, lookupError("customer", err)
 	}
+	return customer, nil
+}
+
+type CustomerQuery struct {
+	BranchID *uint
+	BankID   *uint
+	Name     string
+	Email    string
+	Scope    ListScope
+	ListQuery
+}
 
-	if result := config.GetDB().Create(&customer); result.Error != nil {
-		return nil, result.Error
+func (cs *CustomerService) ListCustomers(query CustomerQuery) (*Page[models.Customer], error) {
+	opts, err := query.options("name", "email", "created_at")
+	if err != nil {
+		return nil, err
+	}
+	branchID, err := narrow(query.BranchID, query.Scope.BranchID)
+	if err != nil {
+		return nil, err
 	}
+	bankID, err := narrow(query.BankID, query.Scope.BankID)
+	if err != nil {
+		return nil, err
+	}
+	filter := repository.CustomerFilter{
+		ID:          query.Scope.CustomerID,
+		BranchID:    branchID,
+		BankID:      bankID,
+		Name:        query.Name,
+		Email:       query.Email,
+		ListOptions: opts,
+	}
+	customers, err := cs.store.Customers().Find(filter)
+	if err != nil {
+		return nil, err
+	}
+	return newPage(customers, opts, query.ListQuery, func() (int64, error) { return cs.store.Customers().Count(filter) })
+}
 
-	return &customer, nil
+func (cs *CustomerService) UpdateCustomer(id uint, updates models.Customer) (*models.Customer, error) {
+	customer, err := cs.store.Customers().Get(id)
+	if err != nil {
+		return nil, lookupError("customer", err)
+	}
+	if updates.BranchID != 0 {
+		if _, err := cs.store.Branches().Get(updates.BranchID); err != nil {
+			return nil, lookupError("branch", err)
+		}
+		customer.BranchID = updates.BranchID
+	}
+	if updates.Name != "" {
+		customer.Name = updates.Name
+	}
+	if updates.Email != "" {
+		customer.Email = updates.Email
+	}
+	if updates.Phone != "" {
+		customer.Phone = updates.Phone
+	}
+	if err := cs.store.Customers().Update(customer); err != nil {
+		return nil, storeError(err)
+	}
+	return customer, nil
 }
 
-type AccountService struct{}
+type AccountService struct {
+	store  repository.Store
+	ledger *LedgerService
+}
 
-func NewAccountService() *AccountService {
-	return &AccountService{}
+func NewAccountService(store repository.Store) *AccountService {
+	return &AccountService{store: store, ledger: NewLedgerService(store)}
 }
 
-func (as *AccountService) OpenSavingsAccount(customerID uint, holderRole string) (*models.SavingsAccount, error) {
-	var customer models.Customer
-	if result := config.GetDB().First(&customer, customerID); result.Error != nil {
-		return nil, errors.New("customer not found")
+func (as *AccountService) OpenSavingsAccount(customerID uint, holderRole string) (*models.SavingsAccount, *models.CustomerAccount, error) {
+	if _, err := as.store.Customers().Get(customerID); err != nil {
+		return nil, nil, lookupError("customer", err)
 	}
 	if holderRole == "" {
-		holderRole = "primary_holder"
-	}
-	tx := config.GetDB().Begin()
-	account := models.SavingsAccount{
-		Balance: 0,
+		holderRole = DefaultHolderRole
 	}
-	if result := tx.Create(&account); result.Error != nil {
-		tx.Rollback()
-		return nil, result.Error
-	}
-	customerAccount := models.CustomerAccount{
-		CustomerID: customerID,
-		AccountID:  account.ID,
-		HolderRole: holderRole,
-	}
-	if result := tx.Create(&customerAccount); result.Error != nil {
-		tx.Rollback()
-		return nil, result.Error
+
+	var account models.SavingsAccount
+	var customerAccount models.CustomerAccount
+	err := as.store.Atomic(func(tx repository.Store) error {
+		account = models.SavingsAccount{
+			Balance:         0,
+			Status:          AccountStatusActive,
+			InterestRate:    DefaultSavingsInterestRate,
+			InterestPosting: InterestPostingMonthly,
+		}
+		if err := tx.Accounts().Create(&account); err != nil {
+			return err
+		}
+		customerAccount = models.CustomerAccount{
+			CustomerID: customerID,
+			AccountID:  account.ID,
+			HolderRole: holderRole,
+		}
+		return tx.Accounts().AddHolder(&customerAccount)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &account, &customerAccount, nil
+}
+
+func (as *AccountService) GetAccount(accountID uint) (*models.SavingsAccount, error) {
+	account, err := as.store.Accounts().Get(accountID)
+	if err != nil {
+		return nil, lookupError("account", err)
 	}
-	tx.Commit()
-	return &account, nil
+	return account, nil
+}
+
+func (as *AccountService) Deposit(accountID uint, amount money.Money) (*models.SavingsAccount, error) {
+	return as.applyTransaction(accountID, TransactionDeposit, amount)
+}
+
+func (as *AccountService) Withdraw(accountID uint, amount money.Money) (*models.SavingsAccount, error) {
+	return as.applyTransaction(accountID, TransactionWithdraw, amount)
+}
+
+func (as *AccountService) applyTransaction(accountID uint, transactionType string, amount money.Money) (*models.SavingsAccount, error) {
+	if !amount.IsPositive() {
+		return nil, ErrInvalidArgument
+	}
+
+	var account *models.SavingsAccount
+	err := as.store.Atomic(func(tx repository.Store) error {
+		var err error
+		account, err = tx.Accounts().GetForUpdate(accountID)
+		if err != nil {
+			return lookupError("account", err)
+		}
+
+		var entry *models.JournalEntry
+		if transactionType == TransactionDeposit {
+			if err := canCredit(account); err != nil {
+				return err
+			}
+			account.Balance += amount
+			entry, err = as.ledger.RecordDeposit(tx, account.ID, amount)
+		} else {
+			if err := canDebit(account); err != nil {
+				return err
+			}
+			if account.Balance < amount {
+				return ErrInsufficientFunds
+			}
+			account.Balance -= amount
+			entry, err = as.ledger.RecordWithdrawal(tx, account.ID, amount)
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Accounts().Update(account); err != nil {
+			return err
+		}
+
+		transaction := models.Transaction{
+			AccountID:      account.ID,
+			Type:           transactionType,
+			Amount:         amount,
+			JournalEntryID: &entry.ID,
+		}
+		return tx.Transactions().Create(&transaction)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return account, nil
 }
+
 func (as *AccountService) AddAccountHolder(accountID, customerID uint, holderRole string) (*models.CustomerAccount, error) {
 
-	var account models.SavingsAccount
-	if result := config.GetDB().First(&account, accountID); result.Error != nil {
-		return nil, errors.New("account not found")
+	account, err := as.store.Accounts().Get(accountID)
+	if err != nil {
+		return nil, lookupError("account", err)
+	}
+	if account.Status == AccountStatusClosed {
+		return nil, accountInactive(account)
+	}
+	if _, err := as.store.Customers().Get(customerID); err != nil {
+		return nil, lookupError("customer", err)
+	}
+	_, err = as.store.Accounts().FindHolder(accountID, customerID)
+	if err == nil {
+		return nil, ErrAlreadyLinked
 	}
-	var customer models.Customer
-	if result := config.GetDB().First(&customer, customerID); result.Error != nil {
-		return nil, errors.New("customer not found")
+	if !errors.Is(err, repository.ErrNotFound) {
+		return nil, err
 	}
-	var existingLink models.CustomerAccount
-	if result := config.GetDB().Where("customer_id = ? AND account_id = ?", customerID, accountID).First(&existingLink); result.RowsAffected > 0 {
-		return nil, errors.New("customer is already linked to this account")
+	if holderRole == "" {
+		holderRole = DefaultHolderRole
 	}
 	customerAccount := models.CustomerAccount{
 		CustomerID: customerID,
 		AccountID:  accountID,
 		HolderRole: holderRole,
 	}
-	if result := config.GetDB().Create(&customerAccount); result.Error != nil {
-		return nil, result.Error
+	if err := as.store.Accounts().AddHolder(&customerAccount); err != nil {
+		return nil, storeError(err)
 	}
 	return &customerAccount, nil
 }
-func (as *AccountService) GetAccountBalance(accountID uint) (float64, error) {
-	var account models.SavingsAccount
-	if result := config.GetDB().First(&account, accountID); result.Error != nil {
-		return 0, result.Error
+func (as *AccountService) GetAccountBalance(accountID uint) (money.Money, error) {
+	account, err := as.store.Accounts().Get(accountID)
+	if err != nil {
+		return 0, lookupError("account", err)
 	}
 	return account.Balance, nil
 }
 
-func (as *AccountService) GetTransactionHistory(accountID uint) ([]models.Transaction, error) {
-	var transactions []models.Transaction
-	result := config.GetDB().Where("account_id = ?", accountID).Order("created_at DESC").Find(&transactions)
-	if result.Error != nil {
-		return nil, result.Error
-	}
-	return transactions, nil
+// TransactionQuery filters and pages an account's transaction history. From
+// is inclusive and To exclusive; Cursor is the NextCursor of the previous
+// page.
+type TransactionQuery struct {
+	Types        []string
+	MinAmount    *money.Money
+	MaxAmount    *money.Money
+	From         *time.Time
+	To           *time.Time
+	Cursor       string
+	Limit        int
+	IncludeTotal bool
 }
 
-func (as *AccountService) GetAccountHolders(accountID uint) ([]models.CustomerAccount, error) {
-	var holders []models.CustomerAccount
-	result := config.GetDB().Where("account_id = ?", accountID).Preload("Customer").Find(&holders)
-	if result.Error != nil {
-		return nil, result.Error
-	}
-	return holders, nil
+type TransactionPage struct {
+	Transactions []models.Transaction `json:"transactions"`
+	NextCursor   string               `json:"next_cursor,omitempty"`
+	Total        *int64               `json:"total,omitempty"`
 }
 
-type LoanService struct{}
-
-func NewLoanService() *LoanService {
-	return &LoanService{}
+type transactionCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        uint      `json:"id"`
 }
 
-func (ls *LoanService) CreateLoan(customerID uint, loanType string, principalAmount float64) (*models.Loan, error) {
+// GetTransactionHistory returns one page of the account's transactions,
+// newest first. Pages are keyed on (created_at, id) so they stay stable while
+// new transactions arrive.
+func (as *AccountService) GetTransactionHistory(accountID uint, query TransactionQuery) (*TransactionPage, error) {
+	limit, err := pageSize(query.Limit)
+	if err != nil {
+		return nil, err
+	}
+	if query.MinAmount != nil && query.MaxAmount != nil && *query.MinAmount > *query.MaxAmount {
+		return nil, fmt.Errorf("%w: min_amount is greater than max_amount", ErrInvalidArgument)
+	}
+	if query.From != nil && query.To != nil && !query.From.Before(*query.To) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidArgument)
+	}
+	if _, err := as.store.Accounts().Get(accountID); err != nil {
+		return nil, lookupError("account", err)
+	}
+
+	filter := repository.TransactionFilter{
+		AccountID: accountID,
+		Types:     query.Types,
+		MinAmount: query.MinAmount,
+		MaxAmount: query.MaxAmount,
+		From:      query.From,
+		To:        query.To,
+		Limit:     limit + 1,
+	}
+	if query.Cursor != "" {
+		var cursor transactionCursor
+		if err := decodeCursor(query.Cursor, &cursor); err != nil {
+			return nil, err
+		}
+		filter.After = &repository.TransactionCursor{CreatedAt: cursor.CreatedAt, ID: cursor.ID}
+	}
 
-	var customer models.Customer
-	if result := config.GetDB().First(&customer, customerID); result.Error != nil {
-		return nil, errors.New("customer not found")
+	transactions, err := as.store.Transactions().Find(filter)
+	if err != nil {
+		return nil, err
+	}
+	page := TransactionPage{Transactions: transactions}
+	if len(transactions) > limit {
+		page.Transactions = transactions[:limit]
+		last := page.Transactions[limit-1]
+		page.NextCursor = encodeCursor(transactionCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	if page.Transactions == nil {
+		page.Transactions = []models.Transaction{}
 	}
-	interestRate := 12.0
-	totalPayableAmount := principalAmount + (principalAmount * interestRate / 100.0)
 
-	loan := models.Loan{
-		CustomerID:         customerID,
-		LoanType:           loanType,
-		PrincipalAmount:    principalAmount,
-		InterestRate:       interestRate,
-		TotalPayableAmount: totalPayableAmount,
-		PendingAmount:      totalPayableAmount,
-		StartDate:          time.Now(),
-		Status:             "ACTIVE",
+	if query.IncludeTotal {
+		total, err := as.store.Transactions().Count(filter)
+		if err != nil {
+			return nil, err
+		}
+		page.Total = &total
 	}
+	return &page, nil
+}
 
-	if result := config.GetDB().Create(&loan); result.Error != nil {
-		return nil, result.Error
+type AccountQuery struct {
+	CustomerID *uint
+	BranchID   *uint
+	BankID     *uint
+	MinBalance *money.Money
+	MaxBalance *money.Money
+	Status     string
+	Scope      ListScope
+	ListQuery
+}
+
+func (as *AccountService) ListAccounts(query AccountQuery) (*Page[models.SavingsAccount], error) {
+	opts, err := query.options("balance", "status", "created_at")
+	if err != nil {
+		return nil, err
+	}
+	filter := repository.AccountFilter{
+		MinBalance:  query.MinBalance,
+		MaxBalance:  query.MaxBalance,
+		Status:      query.Status,
+		ListOptions: opts,
+	}
+	if filter.CustomerID, err = narrow(query.CustomerID, query.Scope.CustomerID); err != nil {
+		return nil, err
 	}
+	if filter.BranchID, err = narrow(query.BranchID, query.Scope.BranchID); err != nil {
+		return nil, err
+	}
+	if filter.BankID, err = narrow(query.BankID, query.Scope.BankID); err != nil {
+		return nil, err
+	}
+	accounts, err := as.store.Accounts().Find(filter)
+	if err != nil {
+		return nil, err
+	}
+	return newPage(accounts, opts, query.ListQuery, func() (int64, error) { return as.store.Accounts().Count(filter) })
+}
 
-	return &loan, nil
+func (as *AccountService) GetAccountHolders(accountID uint) ([]models.CustomerAccount, error) {
+	return as.store.Accounts().ListHolders(accountID)
+}
+
+type LoanService struct {
+	store  repository.Store
+	ledger *LedgerService
+}
+
+func NewLoanService(store repository.Store) *LoanService {
+	return &LoanService{store: store, ledger: NewLedgerService(store)}
 }
 
 func (ls *LoanService) GetLoanByID(loanID uint) (*models.Loan, error) {
-	var loan models.Loan
-	result := config.GetDB().Preload("LoanPayments").First(&loan, loanID)
-	if result.Error != nil {
-		return nil, result.Error
+	loan, err := ls.store.Loans().Get(loanID)
+	if err != nil {
+		return nil, lookupError("loan", err)
 	}
-	return &loan, nil
+	return loan, nil
 }
 
 func (ls *LoanService) GetCustomerLoans(customerID uint) ([]models.Loan, error) {
-	var loans []models.Loan
-	result := config.GetDB().Where("customer_id = ?", customerID).Preload("LoanPayments").Find(&loans)
-	if result.Error != nil {
-		return nil, result.Error
-	}
-	return loans, nil
+	return ls.store.Loans().ListByCustomer(customerID)
 }
 
-func (ls *LoanService) RepayLoan(loanID uint, amount float64) error {
-	tx := config.GetDB().Begin()
+type LoanQuery struct {
+	CustomerID   *uint
+	BranchID     *uint
+	BankID       *uint
+	Status       string
+	LoanType     string
+	Bucket       string
+	Restructured *bool
+	Scope        ListScope
+	ListQuery
+}
 
-	var loan models.Loan
-	if result := tx.First(&loan, loanID); result.Error != nil {
-		tx.Rollback()
-		return errors.New("loan not found")
+func (ls *LoanService) ListLoans(query LoanQuery) (*Page[models.Loan], error) {
+	opts, err := query.options("created_at", "start_date", "principal_amount", "pending_amount", "status", "loan_type", "days_past_due")
+	if err != nil {
+		return nil, err
 	}
-
-	if loan.Status == "CLOSED" {
-		tx.Rollback()
-		return errors.New("loan is already closed")
+	filter := repository.LoanFilter{Status: query.Status, LoanType: query.LoanType, Bucket: query.Bucket, Restructured: query.Restructured, ListOptions: opts}
+	if filter.CustomerID, err = narrow(query.CustomerID, query.Scope.CustomerID); err != nil {
+		return nil, err
 	}
+	if filter.BranchID, err = narrow(query.BranchID, query.Scope.BranchID); err != nil {
+		return nil, err
+	}
+	if filter.BankID, err = narrow(query.BankID, query.Scope.BankID); err != nil {
+		return nil, err
+	}
+	loans, err := ls.store.Loans().Find(filter)
+	if err != nil {
+		return nil, err
+	}
+	return newPage(loans, opts, query.ListQuery, func() (int64, error) { return ls.store.Loans().Count(filter) })
+}
 
+// RepayLoan repays amount of the loan out of a savings account the borrower
+// holds. The account debit, its transaction, the loan's reduction and the
+// payment commit together.
+func (ls *LoanService) RepayLoan(loanID, accountID uint, amount money.Money) (*models.Loan, error) {
+	if !amount.IsPositive() {
+		return nil, ErrInvalidArgument
+	}
+
+	var loan *models.Loan
+	err := ls.store.Atomic(func(tx repository.Store) error {
+		var err error
+		loan, err = tx.Loans().GetForUpdate(loanID)
+		if err != nil {
+			return lookupError("loan", err)
+		}
+		if err := checkRepayable(loan, amount); err != nil {
+			return err
+		}
+		account, err := debitable(tx, loan, accountID, amount)
+		if err != nil {
+			return err
+		}
+		_, err = ls.repay(tx, loan, account, amount)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return loan, nil
+}
+
+func checkRepayable(loan *models.Loan, amount money.Money) error {
+	if loan.Status == LoanStatusClosed {
+		return ErrLoanClosed
+	}
+	if loan.Status != LoanStatusActive {
+		return fmt.Errorf("%w: loan %d is %s", ErrConflict, loan.ID, loan.Status)
+	}
 	if amount > loan.PendingAmount {
-		tx.Rollback()
-		return errors.New("repayment amount exceeds pending amount")
+		return ErrExceedsPending
 	}
+	return nil
+}
 
-	loan.PendingAmount -= amount
-	if loan.PendingAmount == 0 {
-		loan.Status = "CLOSED"
+// repay moves amount from the locked account to the locked loan, which the
+// caller has checked, and records the payment against the schedule.
+func (ls *LoanService) repay(tx repository.Store, loan *models.Loan, account *models.SavingsAccount, amount money.Money) (*models.LoanPayment, error) {
+	allocation, err := allocateRepayment(tx, loan, amount)
+	if err != nil {
+		return nil, err
 	}
+	return ls.pay(tx, loan, account, amount, allocation, "repaid")
+}
 
-	if result := tx.Save(&loan); result.Error != nil {
-		tx.Rollback()
-		return result.Error
+// pay debits amount, split as allocation says, from the locked account to
+// the locked loan, closing the loan with closeReason once nothing is left.
+func (ls *LoanService) pay(tx repository.Store, loan *models.Loan, account *models.SavingsAccount, amount money.Money, allocation *repaymentAllocation, closeReason string) (*models.LoanPayment, error) {
+	loan.PendingAmount -= amount
+	loan.ChargesDue -= allocation.charges
+	if loan.PendingAmount.IsZero() {
+		loan.Status = LoanStatusClosed
 	}
-
-	payment := models.LoanPayment{
-		LoanID:      loanID,
-		Amount:      amount,
-		PaymentDate: time.Now(),
+	if err := tx.Loans().Update(loan); err != nil {
+		return nil, err
+	}
+	if loan.Status == LoanStatusClosed {
+		if err := recordLoanStatus(tx, loan, LoanStatusActive, StatusChange{Reason: closeReason}); err != nil {
+			return nil, err
+		}
 	}
 
-	if result := tx.Create(&payment); result.Error != nil {
-		tx.Rollback()
-		return result.Error
+	account.Balance -= amount
+	if err := tx.Accounts().Update(account); err != nil {
+		return nil, err
+	}
+	entry, err := ls.ledger.RecordLoanRepayment(tx, loan, account.ID, amount, allocation.interest)
+	if err != nil {
+		return nil, err
+	}
+	debit := models.Transaction{
+		AccountID:      account.ID,
+		Type:           TransactionLoanRepayment,
+		Amount:         amount,
+		JournalEntryID: &entry.ID,
+	}
+	if err := tx.Transactions().Create(&debit); err != nil {
+		return nil, err
 	}
 
-	tx.Commit()
-	return nil
+	payment := models.LoanPayment{
+		LoanID:         loan.ID,
+		Amount:         amount,
+		PrincipalPaid:  allocation.principal,
+		InterestPaid:   allocation.interest,
+		ChargesPaid:    allocation.charges,
+		PaymentDate:    time.Now(),
+		JournalEntryID: &entry.ID,
+		TransactionID:  &debit.ID,
+	}
+	if err := tx.Loans().CreatePayment(&payment); err != nil {
+		return nil, err
+	}
+	if err := allocation.save(tx, payment.ID); err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// LoanInterest is the interest a loan accrues over a period.
+type LoanInterest struct {
+	LoanID             uint        `json:"loan_id"`
+	From               time.Time   `json:"from"`
+	To                 time.Time   `json:"to"`
+	DayCount           string      `json:"day_count"`
+	CompoundingPerYear int         `json:"compounding_per_year"`
+	Interest           money.Money `json:"interest"`
 }
 
-func (ls *LoanService) CalculateYearlyInterest(loanID uint) (float64, error) {
-	var loan models.Loan
-	if result := config.GetDB().First(&loan, loanID); result.Error != nil {
-		return 0, result.Error
+// loanInterest is the interest principal accrues at the loan's rate from
+// from to to, under its day count and compounding.
+func loanInterest(loan *models.Loan, principal money.Money, from, to time.Time) (*big.Rat, error) {
+	dayCount := interest.DayCount(loan.DayCount)
+	if loan.CompoundingPerYear == 0 {
+		return interest.Simple(principal, loan.InterestRate, dayCount, from, to), nil
+	}
+	accrued, err := interest.Compound(principal, loan.InterestRate, dayCount, loan.CompoundingPerYear, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("%w: loan %d: %w", ErrConflict, loan.ID, err)
 	}
-	interest := (loan.PendingAmount * loan.InterestRate) / 100.0
-	return interest, nil
+	return accrued, nil
+}
+
+// CalculateInterest works out the interest the loan accrues from from to to
+// under its day count and compounding, on the principal its schedule leaves
+// outstanding on each day. Loans from before schedules existed accrue on
+// their whole principal.
+func (ls *LoanService) CalculateInterest(loanID uint, from, to time.Time) (*LoanInterest, error) {
+	from, to = BusinessDate(from), BusinessDate(to)
+	if to.Before(from) {
+		return nil, fmt.Errorf("%w: to must not be before from", ErrInvalidArgument)
+	}
+	loan, err := ls.store.Loans().Get(loanID)
+	if err != nil {
+		return nil, lookupError("loan", err)
+	}
+	installments, err := ls.store.Loans().ListInstallments(loan.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	outstanding := loan.PrincipalAmount
+	start := from
+	accrued := new(big.Rat)
+	for _, installment := range installments {
+		if !installment.DueDate.After(start) {
+			outstanding = installment.OutstandingPrincipal
+			continue
+		}
+		if !installment.DueDate.Before(to) {
+			break
+		}
+		period, err := loanInterest(loan, outstanding, start, installment.DueDate)
+		if err != nil {
+			return nil, err
+		}
+		accrued.Add(accrued, period)
+		start, outstanding = installment.DueDate, installment.OutstandingPrincipal
+	}
+	period, err := loanInterest(loan, outstanding, start, to)
+	if err != nil {
+		return nil, err
+	}
+	accrued.Add(accrued, period)
+	amount, err := money.FromRat(accrued)
+	if err != nil {
+		return nil, err
+	}
+	return &LoanInterest{
+		LoanID:             loan.ID,
+		From:               from,
+		To:                 to,
+		DayCount:           loan.DayCount,
+		CompoundingPerYear: loan.CompoundingPerYear,
+		Interest:           amount,
+	}, nil
 }
 
 func (ls *LoanService) GetLoanPaymentHistory(loanID uint) ([]models.LoanPayment, error) {
-	var payments []models.LoanPayment
-	result := config.GetDB().Where("loan_id = ?", loanID).Order("payment_date DESC").Find(&payments)
-	if result.Error != nil {
-		return nil, result.Error
-	}
-	return payments, nil
+	return ls.store.Loans().ListPayments(loanID)
 }
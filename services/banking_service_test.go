@@ -0,0 +1,128 @@
+package services
+
+import (
+	"banking-system/interest"
+	"banking-system/money"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestConcurrentDebitsOnOneAccount hammers one account with withdrawals and
+// loan repayments from many goroutines. More is asked for than the account
+// holds, so some debits must fail; none may overdraw it or get lost.
+func TestConcurrentDebitsOnOneAccount(t *testing.T) {
+	const workers = 50
+	f := newFixture(t)
+	account := f.openAccount(f.customer.ID, money.MustParse("500"))
+	loan := f.disburseLoan(account.ID, money.MustParse("1200"), 12)
+	start := f.account(account.ID).Balance
+	accounts := NewAccountService(f.store)
+	loans := NewLoanService(f.store)
+	amount := money.MustParse("40")
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		debited  money.Money
+		failures []error
+	)
+	done := make(chan struct{})
+	negative := make(chan money.Money, 1)
+	go func() {
+		for {
+			select {
+			case <-done:
+				close(negative)
+				return
+			default:
+			}
+			if balance := f.account(account.ID).Balance; balance.IsNegative() {
+				negative <- balance
+				close(negative)
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var err error
+			if i%2 == 0 {
+				_, err = accounts.Withdraw(account.ID, amount)
+			} else {
+				_, err = loans.RepayLoan(loan.ID, account.ID, amount)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				debited += amount
+			case !errors.Is(err, ErrInsufficientFunds):
+				failures = append(failures, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(done)
+
+	if balance, ok := <-negative; ok {
+		t.Errorf("balance went negative: %s", balance)
+	}
+	for _, err := range failures {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if debited > start {
+		t.Fatalf("debited %s from an account holding %s", debited, start)
+	}
+	if got, want := f.account(account.ID).Balance, start-debited; got != want {
+		t.Errorf("final balance = %s, want %s (%s less %s debited)", got, want, start, debited)
+	}
+	f.assertReconciled()
+}
+
+func TestCalculateInterestCompoundsAsTheProductSays(t *testing.T) {
+	f := newFixture(t)
+	account := f.openAccount(f.customer.ID, 0)
+	simple := f.disburseLoan(account.ID, money.MustParse("1200"), 12)
+
+	products := NewLoanProductService(f.store)
+	daily := f.product
+	daily.ID, daily.Code, daily.CompoundingPerYear = 0, "DAILY", 366
+	if err := products.CreateProduct(&daily); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatalf("compounding 366 times a year: err = %v, want ErrInvalidArgument", err)
+	}
+	daily.CompoundingPerYear = 365
+	f.must(products.CreateProduct(&daily))
+	f.product = daily
+	compounded := f.disburseLoan(account.ID, money.MustParse("1200"), 12)
+	if compounded.CompoundingPerYear != 365 {
+		t.Fatalf("loan compounds %d times a year, want the product's 365", compounded.CompoundingPerYear)
+	}
+
+	loans := NewLoanService(f.store)
+	from := BusinessDate(time.Now())
+	to := from.AddDate(0, 0, 20)
+	got, err := loans.CalculateInterest(compounded.ID, from, to)
+	f.must(err)
+	exact, err := interest.Compound(compounded.PrincipalAmount, 12, interest.Actual365, 365, from, to)
+	f.must(err)
+	want, err := money.FromRat(exact)
+	f.must(err)
+	if got.Interest != want {
+		t.Errorf("compounded interest = %s, want %s", got.Interest, want)
+	}
+	flat, err := loans.CalculateInterest(simple.ID, from, to)
+	f.must(err)
+	want, err = money.FromRat(interest.Simple(simple.PrincipalAmount, 12, interest.Actual365, from, to))
+	f.must(err)
+	if flat.Interest != want {
+		t.Errorf("simple interest = %s, want %s", flat.Interest, want)
+	}
+	if got.Interest <= flat.Interest {
+		t.Errorf("compounded interest %s is not above simple interest %s", got.Interest, flat.Interest)
+	}
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"banking-system/models"
+	"banking-system/repository"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Ways a loan's interest rate is set.
+const (
+	RateFixed    = "FIXED"
+	RateFloating = "FLOATING"
+)
+
+type BenchmarkService struct {
+	store repository.Store
+	now   func() time.Time
+}
+
+func NewBenchmarkService(store repository.Store) *BenchmarkService {
+	return &BenchmarkService{store: store, now: time.Now}
+}
+
+// CreateBenchmark adds a reference rate to its bank. It has no value until
+// a rate is published for it.
+func (bs *BenchmarkService) CreateBenchmark(benchmark *models.Benchmark) error {
+	benchmark.Code = strings.ToUpper(strings.TrimSpace(benchmark.Code))
+	if benchmark.Code == "" || benchmark.Name == "" {
+		return fmt.Errorf("%w: code and name are required", ErrInvalidArgument)
+	}
+	if _, err := bs.store.Banks().Get(benchmark.BankID); err != nil {
+		return lookupError("bank", err)
+	}
+	return storeError(bs.store.Benchmarks().Create(benchmark))
+}
+
+func (bs *BenchmarkService) GetBenchmark(id uint) (*models.Benchmark, error) {
+	benchmark, err := bs.store.Benchmarks().Get(id)
+	if err != nil {
+		return nil, lookupError("benchmark", err)
+	}
+	return benchmark, nil
+}
+
+func (bs *BenchmarkService) ListBenchmarks(bankID uint) ([]models.Benchmark, error) {
+	if _, err := bs.store.Banks().Get(bankID); err != nil {
+		return nil, lookupError("bank", err)
+	}
+	return bs.store.Benchmarks().ListByBank(bankID)
+}
+
+// PublishRate sets the benchmark's rate from effective on. Rates cannot be
+// backdated, since loans may already have been priced off the old one, and
+// each date has at most one rate.
+func (bs *BenchmarkService) PublishRate(benchmarkID uint, effective time.Time, rate float64, createdBy string) (*models.BenchmarkRate, error) {
+	effective = BusinessDate(effective)
+	if effective.Before(BusinessDate(bs.now())) {
+		return nil, fmt.Errorf("%w: effective_date must not be in the past", ErrInvalidArgument)
+	}
+	if rate < 0 || rate > 100 {
+		return nil, fmt.Errorf("%w: rate must be between 0 and 100", ErrInvalidArgument)
+	}
+	if _, err := bs.store.Benchmarks().Get(benchmarkID); err != nil {
+		return nil, lookupError("benchmark", err)
+	}
+	published := models.BenchmarkRate{BenchmarkID: benchmarkID, EffectiveDate: effective, Rate: rate, CreatedBy: createdBy}
+	created, err := bs.store.Benchmarks().AddRate(&published)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, fmt.Errorf("%w: benchmark %d already has a rate effective %s", ErrConflict, benchmarkID, effective.Format(time.DateOnly))
+	}
+	return &published, nil
+}
+
+func (bs *BenchmarkService) ListRates(benchmarkID uint) ([]models.BenchmarkRate, error) {
+	if _, err := bs.store.Benchmarks().Get(benchmarkID); err != nil {
+		return nil, lookupError("benchmark", err)
+	}
+	return bs.store.Benchmarks().ListRates(benchmarkID)
+}
+
+// floatingRate prices a floating-rate loan on date: the benchmark's rate in
+// effect then plus spread, never below zero. It returns the benchmark's
+// rate too.
+func floatingRate(tx repository.Store, benchmarkID uint, spread float64, date time.Time) (float64, float64, error) {
+	benchmark, err := tx.Benchmarks().RateAsOf(benchmarkID, date)
+	if errors.Is(err, repository.ErrNotFound) {
+		return 0, 0, fmt.Errorf("%w: benchmark %d has no rate in effect on %s", ErrConflict, benchmarkID, date.Format(time.DateOnly))
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	rate := math.Round((benchmark.Rate+spread)*1e6) / 1e6
+	return max(rate, 0), benchmark.Rate, nil
+}
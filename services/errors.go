@@ -0,0 +1,59 @@
+package services
+
+import (
+	"banking-system/repository"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrNotFound          = errors.New("not found")
+	ErrInvalidArgument   = errors.New("invalid argument")
+	ErrConflict          = errors.New("conflict")
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	ErrLoanClosed        = errors.New("loan is already closed")
+	ErrExceedsPending    = errors.New("repayment amount exceeds pending amount")
+	ErrUnauthenticated   = errors.New("authentication required")
+	ErrForbidden         = errors.New("forbidden")
+	ErrAccountInactive   = errors.New("account is not active")
+	ErrNotEligible       = errors.New("customer is not eligible for this loan product")
+)
+
+var (
+	ErrSameAccount   = fmt.Errorf("%w: cannot transfer to the same account", ErrInvalidArgument)
+	ErrAlreadyLinked = fmt.Errorf("%w: customer is already linked to this account", ErrConflict)
+)
+
+// NotFoundError reports a missing resource and matches ErrNotFound.
+type NotFoundError struct {
+	Resource string
+}
+
+func (e *NotFoundError) Error() string {
+	return e.Resource + " not found"
+}
+
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+func notFound(resource string) error {
+	return &NotFoundError{Resource: resource}
+}
+
+// lookupError turns the repository's missing-row error into a NotFoundError
+// for the given resource and passes every other error through.
+func lookupError(resource string, err error) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return notFound(resource)
+	}
+	return err
+}
+
+// storeError reports unique-constraint violations as conflicts.
+func storeError(err error) error {
+	if errors.Is(err, repository.ErrDuplicate) {
+		return fmt.Errorf("%w: %v", ErrConflict, err)
+	}
+	return err
+}
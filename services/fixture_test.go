@@ -0,0 +1,185 @@
+package services
+
+import (
+	"banking-system/models"
+	"banking-system/money"
+	"banking-system/repository"
+	"errors"
+	"testing"
+)
+
+// fixture is a bank with one branch, customer and loan product in a fresh
+// memory store.
+type fixture struct {
+	t        *testing.T
+	store    *repository.MemoryStore
+	bank     models.Bank
+	branch   models.Branch
+	customer models.Customer
+	product  models.LoanProduct
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	f := &fixture{t: t, store: repository.NewMemoryStore()}
+	f.bank = models.Bank{Name: "Test Bank"}
+	f.must(NewBankService(f.store).CreateBank(&f.bank))
+	f.branch = models.Branch{BankID: f.bank.ID, Name: "Main"}
+	f.must(NewBranchService(f.store).CreateBranch(&f.branch))
+	f.customer = f.newCustomer(f.branch.ID, "alice@example.com")
+	f.product = models.LoanProduct{
+		BankID:          f.bank.ID,
+		Code:            "PERSONAL",
+		Name:            "Personal loan",
+		LoanType:        "personal",
+		InterestRate:    12,
+		MinPrincipal:    money.MustParse("100"),
+		MaxPrincipal:    money.MustParse("1000000"),
+		MinTenureMonths: 1,
+		MaxTenureMonths: 60,
+	}
+	f.must(NewLoanProductService(f.store).CreateProduct(&f.product))
+	return f
+}
+
+// useProduct switches the fixture to a copy of its product under code, as
+// configure changes it.
+func (f *fixture) useProduct(code string, configure func(product *models.LoanProduct)) {
+	f.t.Helper()
+	product := f.product
+	product.ID, product.Code = 0, code
+	configure(&product)
+	f.must(NewLoanProductService(f.store).CreateProduct(&product))
+	f.product = product
+}
+
+func (f *fixture) must(err error) {
+	f.t.Helper()
+	if err != nil {
+		f.t.Fatal(err)
+	}
+}
+
+func (f *fixture) newCustomer(branchID uint, email string) models.Customer {
+	f.t.Helper()
+	customer := models.Customer{BranchID: branchID, Name: email, Email: email}
+	f.must(NewCustomerService(f.store).RegisterCustomer(&customer))
+	return customer
+}
+
+// openAccount opens a savings account for customerID and deposits balance
+// into it.
+func (f *fixture) openAccount(customerID uint, balance money.Money) models.SavingsAccount {
+	f.t.Helper()
+	accounts := NewAccountService(f.store)
+	account, _, err := accounts.OpenSavingsAccount(customerID, "")
+	f.must(err)
+	if balance.IsPositive() {
+		account, err = accounts.Deposit(account.ID, balance)
+		f.must(err)
+	}
+	return *account
+}
+
+// disburseLoan takes a 12% loan of principal over tenure months through
+// application, review, approval and disbursement into accountID.
+func (f *fixture) disburseLoan(accountID uint, principal money.Money, tenure int) models.Loan {
+	f.t.Helper()
+	loans := NewLoanService(f.store)
+	loan, err := loans.Apply(LoanApplication{
+		CustomerID:            f.customer.ID,
+		ProductID:             f.product.ID,
+		PrincipalAmount:       principal,
+		Terms:                 LoanTerms{TenureMonths: tenure},
+		DisbursementAccountID: accountID,
+		SubmittedBy:           "teller",
+	})
+	f.must(err)
+	checker := StatusChange{ChangedBy: "manager"}
+	for _, step := range []func(uint, StatusChange) (*models.Loan, error){loans.Review, loans.Approve, loans.Disburse} {
+		loan, err = step(loan.ID, checker)
+		f.must(err)
+	}
+	return *loan
+}
+
+func (f *fixture) account(id uint) models.SavingsAccount {
+	f.t.Helper()
+	account, err := f.store.Accounts().Get(id)
+	f.must(err)
+	return *account
+}
+
+// assertReconciled fails the test unless every savings balance and loan
+// agrees with the ledger.
+func (f *fixture) assertReconciled() {
+	f.t.Helper()
+	mismatches, err := NewLedgerService(f.store).Reconcile()
+	f.must(err)
+	if len(mismatches) != 0 {
+		f.t.Fatalf("ledger does not reconcile: %+v", mismatches)
+	}
+}
+
+// ledgerBalance is the balance of the ledger account with code, debits
+// positive; a ledger account that was never posted to has none.
+func (f *fixture) ledgerBalance(code string) money.Money {
+	f.t.Helper()
+	account, err := f.store.Ledger().GetAccountByCode(code)
+	if errors.Is(err, repository.ErrNotFound) {
+		return 0
+	}
+	f.must(err)
+	balance, err := f.store.Ledger().Balance(account.ID)
+	f.must(err)
+	return balance
+}
+
+var errInjected = errors.New("injected failure")
+
+// failingStore fails every attempt to lock the account accountID or the loan
+// loanID, inside transactions too, so batch jobs can be tested against one
+// bad item.
+type failingStore struct {
+	repository.Store
+	accountID uint
+	loanID    uint
+}
+
+func (s failingStore) Atomic(fn func(tx repository.Store) error) error {
+	return s.Store.Atomic(func(tx repository.Store) error {
+		return fn(failingStore{Store: tx, accountID: s.accountID, loanID: s.loanID})
+	})
+}
+
+func (s failingStore) Accounts() repository.AccountRepository {
+	return failingAccounts{AccountRepository: s.Store.Accounts(), id: s.accountID}
+}
+
+type failingAccounts struct {
+	repository.AccountRepository
+	id uint
+}
+
+func (a failingAccounts) GetForUpdate(id uint) (*models.SavingsAccount, error) {
+	if id == a.id {
+		return nil, errInjected
+	}
+	return a.AccountRepository.GetForUpdate(id)
+}
+
+func (s failingStore) Loans() repository.LoanRepository {
+	return failingLoans{LoanRepository: s.Store.Loans(), id: s.loanID}
+}
+
+type failingLoans struct {
+	repository.LoanRepository
+	id uint
+}
+
+func (l failingLoans) GetForUpdate(id uint) (*models.Loan, error) {
+	if id == l.id {
+		return nil, errInjected
+	}
+	return l.LoanRepository.GetForUpdate(id)
+}
@@ -0,0 +1,363 @@
+package services
+
+import (
+	"banking-system/interest"
+	"banking-system/models"
+	"banking-system/money"
+	"banking-system/repository"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+)
+
+const DefaultSavingsInterestRate = 3.5
+
+const (
+	InterestPostingMonthly   = "MONTHLY"
+	InterestPostingQuarterly = "QUARTERLY"
+)
+
+const TransactionInterest = "interest"
+
+// savingsDayCount is the convention savings interest accrues under.
+const savingsDayCount = interest.Actual365
+
+var errAlreadyAccrued = errors.New("interest already accrued")
+
+type InterestSettings struct {
+	InterestRate    *float64
+	InterestPosting string
+}
+
+// AccrualRun summarises one accrual job. Skipped counts accounts that had
+// already accrued for the business date; Failed counts those that could not
+// be accrued, which a rerun retries.
+type AccrualRun struct {
+	BusinessDate     time.Time   `json:"business_date"`
+	Accrued          int         `json:"accrued"`
+	Skipped          int         `json:"skipped"`
+	Failed           int         `json:"failed"`
+	FailedAccountIDs []uint      `json:"failed_account_ids,omitempty"`
+	Amount           money.Money `json:"amount"`
+}
+
+type PostingRun struct {
+	BusinessDate     time.Time   `json:"business_date"`
+	Posted           int         `json:"posted"`
+	Failed           int         `json:"failed"`
+	FailedAccountIDs []uint      `json:"failed_account_ids,omitempty"`
+	Amount           money.Money `json:"amount"`
+}
+
+type InterestService struct {
+	store  repository.Store
+	ledger *LedgerService
+	now    func() time.Time
+}
+
+func NewInterestService(store repository.Store) *InterestService {
+	return &InterestService{store: store, ledger: NewLedgerService(store), now: time.Now}
+}
+
+// BusinessDate truncates t to its UTC calendar date.
+func BusinessDate(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// lastPostingDate is the end of the latest posting period that finished on
+// or before date.
+func lastPostingDate(date time.Time, frequency string) time.Time {
+	months := 1
+	if frequency == InterestPostingQuarterly {
+		months = 3
+	}
+	start := time.Date(date.Year(), date.Month()-time.Month((int(date.Month())-1)%months), 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, months, -1)
+	if date.Equal(end) {
+		return end
+	}
+	return start.AddDate(0, 0, -1)
+}
+
+// checkEnded rejects business dates whose day has not finished, since their
+// end-of-day balances are not known yet.
+func (is *InterestService) checkEnded(date time.Time) error {
+	if date.AddDate(0, 0, 1).After(is.now()) {
+		return fmt.Errorf("%w: business date %s has not ended", ErrInvalidArgument, date.Format(time.DateOnly))
+	}
+	return nil
+}
+
+// Accrue records a day of interest for every open account with a positive
+// end-of-day balance. Each account accrues at most once per business date,
+// so the job can be rerun safely. An account that fails is logged and
+// counted in the run rather than stopping it. Daily amounts are rounded so
+// that the accruals awaiting posting always add up to the exact interest
+// earned.
+func (is *InterestService) Accrue(date time.Time) (*AccrualRun, error) {
+	date = BusinessDate(date)
+	if err := is.checkEnded(date); err != nil {
+		return nil, err
+	}
+	accounts, err := is.store.Accounts().List()
+	if err != nil {
+		return nil, err
+	}
+
+	run := AccrualRun{BusinessDate: date}
+	for _, account := range accounts {
+		if account.Status == AccountStatusClosed || account.InterestRate <= 0 {
+			continue
+		}
+		var accrual *models.InterestAccrual
+		err := is.store.Atomic(func(tx repository.Store) error {
+			var err error
+			accrual, err = is.accrue(tx, account.ID, date)
+			return err
+		})
+		if errors.Is(err, errAlreadyAccrued) {
+			run.Skipped++
+			continue
+		}
+		if err != nil {
+			log.Printf("interest: accruing account %d for %s: %v", account.ID, date.Format(time.DateOnly), err)
+			run.Failed++
+			run.FailedAccountIDs = append(run.FailedAccountIDs, account.ID)
+			continue
+		}
+		if accrual != nil {
+			run.Accrued++
+			run.Amount += accrual.Amount
+		}
+	}
+	return &run, nil
+}
+
+func (is *InterestService) accrue(tx repository.Store, accountID uint, date time.Time) (*models.InterestAccrual, error) {
+	account, err := tx.Accounts().GetForUpdate(accountID)
+	if err != nil {
+		return nil, lookupError("account", err)
+	}
+	balance, err := is.endOfDayBalance(tx, account.ID, date)
+	if err != nil {
+		return nil, err
+	}
+	if !balance.IsPositive() {
+		return nil, nil
+	}
+
+	dayBefore := date.AddDate(0, 0, -1)
+	pending, err := tx.Interest().FindAccruals(repository.AccrualFilter{AccountID: &account.ID, Unposted: true, Through: &dayBefore})
+	if err != nil {
+		return nil, err
+	}
+	exact := dailyInterest(balance, account.InterestRate, date)
+	var rounded money.Money
+	for _, a := range pending {
+		exact.Add(exact, dailyInterest(a.Balance, a.InterestRate, a.BusinessDate))
+		rounded += a.Amount
+	}
+	total, err := money.FromRat(exact)
+	if err != nil {
+		return nil, err
+	}
+
+	accrual := models.InterestAccrual{
+		AccountID:    account.ID,
+		BusinessDate: date,
+		Balance:      balance,
+		InterestRate: account.InterestRate,
+		Amount:       money.Max(total-rounded, 0),
+	}
+	if accrual.Amount.IsPositive() {
+		entry, err := is.ledger.RecordInterestAccrual(tx, account.ID, accrual.Amount)
+		if err != nil {
+			return nil, err
+		}
+		accrual.JournalEntryID = &entry.ID
+	}
+	created, err := tx.Interest().CreateAccrual(&accrual)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, errAlreadyAccrued
+	}
+	return &accrual, nil
+}
+
+// endOfDayBalance reads the savings balance at the end of date from the
+// ledger.
+func (is *InterestService) endOfDayBalance(tx repository.Store, accountID uint, date time.Time) (money.Money, error) {
+	ledgerAccount, err := tx.Ledger().GetAccountByCode(savingsLedgerCode(accountID))
+	if errors.Is(err, repository.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	balance, err := tx.Ledger().BalanceAsOf(ledgerAccount.ID, date.AddDate(0, 0, 1))
+	if err != nil {
+		return 0, err
+	}
+	return balance.Neg(), nil
+}
+
+// dailyInterest is the savings interest balance earns over date.
+func dailyInterest(balance money.Money, rate float64, date time.Time) *big.Rat {
+	return interest.Simple(balance, rate, savingsDayCount, date, date.AddDate(0, 0, 1))
+}
+
+// Post credits each account with the interest it accrued up to the end of its
+// latest finished posting period. Accounts whose period has not ended yet
+// are left alone; a missed run, or an account that failed, is caught up by
+// the next one.
+func (is *InterestService) Post(date time.Time) (*PostingRun, error) {
+	date = BusinessDate(date)
+	if err := is.checkEnded(date); err != nil {
+		return nil, err
+	}
+	pending, err := is.store.Interest().FindAccruals(repository.AccrualFilter{Unposted: true, Through: &date})
+	if err != nil {
+		return nil, err
+	}
+
+	run := PostingRun{BusinessDate: date}
+	seen := map[uint]bool{}
+	for _, a := range pending {
+		if seen[a.AccountID] {
+			continue
+		}
+		seen[a.AccountID] = true
+
+		var credit *models.Transaction
+		err := is.store.Atomic(func(tx repository.Store) error {
+			account, err := tx.Accounts().GetForUpdate(a.AccountID)
+			if err != nil {
+				return lookupError("account", err)
+			}
+			if account.Status == AccountStatusClosed {
+				return nil
+			}
+			through := lastPostingDate(date, account.InterestPosting)
+			accruals, err := tx.Interest().FindAccruals(repository.AccrualFilter{AccountID: &account.ID, Unposted: true, Through: &through})
+			if err != nil {
+				return err
+			}
+			credit, err = postAccruals(tx, is.ledger, account, accruals)
+			return err
+		})
+		if err != nil {
+			log.Printf("interest: posting to account %d for %s: %v", a.AccountID, date.Format(time.DateOnly), err)
+			run.Failed++
+			run.FailedAccountIDs = append(run.FailedAccountIDs, a.AccountID)
+			continue
+		}
+		if credit != nil {
+			run.Posted++
+			run.Amount += credit.Amount
+		}
+	}
+	return &run, nil
+}
+
+// postAccruals credits the total of accruals to the locked account as one
+// interest transaction and marks them posted. It returns nil when there was
+// nothing to credit.
+func postAccruals(tx repository.Store, ledger *LedgerService, account *models.SavingsAccount, accruals []models.InterestAccrual) (*models.Transaction, error) {
+	if len(accruals) == 0 {
+		return nil, nil
+	}
+	ids := make([]uint, len(accruals))
+	var total money.Money
+	for i, a := range accruals {
+		ids[i] = a.ID
+		total += a.Amount
+	}
+	now := time.Now()
+	if !total.IsPositive() {
+		return nil, tx.Interest().MarkPosted(ids, nil, now)
+	}
+	if err := canCredit(account); err != nil {
+		return nil, err
+	}
+
+	account.Balance += total
+	if err := tx.Accounts().Update(account); err != nil {
+		return nil, err
+	}
+	entry, err := ledger.RecordInterestPosting(tx, account.ID, total)
+	if err != nil {
+		return nil, err
+	}
+	credit := models.Transaction{
+		AccountID:      account.ID,
+		Type:           TransactionInterest,
+		Amount:         total,
+		JournalEntryID: &entry.ID,
+	}
+	if err := tx.Transactions().Create(&credit); err != nil {
+		return nil, err
+	}
+	if err := tx.Interest().MarkPosted(ids, &credit.ID, now); err != nil {
+		return nil, err
+	}
+	return &credit, nil
+}
+
+// postPendingInterest pays out everything the locked account has accrued,
+// whatever its posting period.
+func (as *AccountService) postPendingInterest(tx repository.Store, account *models.SavingsAccount) error {
+	accruals, err := tx.Interest().FindAccruals(repository.AccrualFilter{AccountID: &account.ID, Unposted: true})
+	if err != nil {
+		return err
+	}
+	_, err = postAccruals(tx, as.ledger, account, accruals)
+	return err
+}
+
+// UpdateInterestSettings changes the account's savings rate or posting
+// frequency. A new rate applies from the next accrual.
+func (as *AccountService) UpdateInterestSettings(accountID uint, settings InterestSettings) (*models.SavingsAccount, error) {
+	if settings.InterestRate != nil && (*settings.InterestRate < 0 || *settings.InterestRate > 100) {
+		return nil, fmt.Errorf("%w: interest_rate must be between 0 and 100", ErrInvalidArgument)
+	}
+	posting := strings.ToUpper(settings.InterestPosting)
+	if posting != "" && posting != InterestPostingMonthly && posting != InterestPostingQuarterly {
+		return nil, fmt.Errorf("%w: interest_posting must be %s or %s", ErrInvalidArgument, InterestPostingMonthly, InterestPostingQuarterly)
+	}
+
+	var account *models.SavingsAccount
+	err := as.store.Atomic(func(tx repository.Store) error {
+		var err error
+		account, err = tx.Accounts().GetForUpdate(accountID)
+		if err != nil {
+			return lookupError("account", err)
+		}
+		if account.Status == AccountStatusClosed {
+			return accountInactive(account)
+		}
+		if settings.InterestRate != nil {
+			account.InterestRate = *settings.InterestRate
+		}
+		if posting != "" {
+			account.InterestPosting = posting
+		}
+		return tx.Accounts().Update(account)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+func (as *AccountService) GetInterestAccruals(accountID uint) ([]models.InterestAccrual, error) {
+	if _, err := as.store.Accounts().Get(accountID); err != nil {
+		return nil, lookupError("account", err)
+	}
+	return as.store.Interest().FindAccruals(repository.AccrualFilter{AccountID: &accountID})
+}
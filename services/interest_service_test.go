@@ -0,0 +1,95 @@
+package services
+
+import (
+	"banking-system/money"
+	"banking-system/repository"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestAccrueIsIdempotentPerBusinessDate(t *testing.T) {
+	f := newFixture(t)
+	account := f.openAccount(f.customer.ID, money.MustParse("10000"))
+	interest := NewInterestService(f.store)
+	interest.now = func() time.Time { return time.Now().AddDate(0, 0, 2) }
+	today := BusinessDate(time.Now())
+
+	first, err := interest.Accrue(today)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Accrued != 1 || !first.Amount.IsPositive() {
+		t.Fatalf("first run = %+v, want one positive accrual", first)
+	}
+	second, err := interest.Accrue(today)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Accrued != 0 || second.Skipped != 1 || !second.Amount.IsZero() {
+		t.Fatalf("rerun = %+v, want the account skipped", second)
+	}
+
+	accruals, err := f.store.Interest().FindAccruals(repository.AccrualFilter{AccountID: &account.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accruals) != 1 {
+		t.Fatalf("%d accruals recorded, want 1", len(accruals))
+	}
+	if got := f.ledgerBalance(LedgerInterestPayable); got != first.Amount.Neg() {
+		t.Errorf("interest payable = %s, want %s", got.Neg(), first.Amount)
+	}
+	f.assertReconciled()
+}
+
+func TestInterestJobsCarryOnPastAFailedAccount(t *testing.T) {
+	f := newFixture(t)
+	good := f.openAccount(f.customer.ID, money.MustParse("10000"))
+	bad := f.openAccount(f.customer.ID, money.MustParse("10000"))
+	now := func() time.Time { return time.Now().AddDate(0, 2, 0) }
+	today := BusinessDate(time.Now())
+
+	interest := NewInterestService(failingStore{Store: f.store, accountID: bad.ID})
+	interest.now = now
+	accrued, err := interest.Accrue(today)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if accrued.Accrued != 1 || accrued.Failed != 1 || !slices.Equal(accrued.FailedAccountIDs, []uint{bad.ID}) {
+		t.Fatalf("accrual run = %+v, want one accrued and account %d failed", accrued, bad.ID)
+	}
+
+	// A rerun against a healthy store picks up the account that failed.
+	interest = NewInterestService(f.store)
+	interest.now = now
+	retried, err := interest.Accrue(today)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if retried.Accrued != 1 || retried.Skipped != 1 || retried.Failed != 0 {
+		t.Fatalf("rerun = %+v, want the failed account accrued", retried)
+	}
+
+	interest = NewInterestService(failingStore{Store: f.store, accountID: good.ID})
+	interest.now = now
+	posted, err := interest.Post(today.AddDate(0, 1, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if posted.Posted != 1 || posted.Failed != 1 || !slices.Equal(posted.FailedAccountIDs, []uint{good.ID}) {
+		t.Fatalf("posting run = %+v, want one posted and account %d failed", posted, good.ID)
+	}
+	if f.account(bad.ID).Balance == money.MustParse("10000") {
+		t.Errorf("account %d was not credited", bad.ID)
+	}
+	f.assertReconciled()
+}
+
+func TestAccrueRejectsUnfinishedDays(t *testing.T) {
+	interest := NewInterestService(repository.NewMemoryStore())
+	if _, err := interest.Accrue(time.Now()); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatalf("err = %v, want ErrInvalidArgument", err)
+	}
+}
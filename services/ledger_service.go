@@ -0,0 +1,538 @@
+package services
+
+import (
+	"banking-system/models"
+	"banking-system/money"
+	"banking-system/repository"
+	"errors"
+	"fmt"
+)
+
+const (
+	LedgerCash            = "CASH"
+	LedgerInterestIncome  = "INTEREST_INCOME"
+	LedgerInterestExpense = "INTEREST_EXPENSE"
+	LedgerInterestPayable = "INTEREST_PAYABLE"
+	LedgerFeeIncome       = "FEE_INCOME"
+	LedgerLoanLosses      = "LOAN_LOSSES"
+	LedgerLoanRecoveries  = "LOAN_RECOVERIES"
+	LedgerRecoveryExpense = "RECOVERY_EXPENSE"
+)
+
+const (
+	EntryDeposit          = "deposit"
+	EntryWithdrawal       = "withdraw"
+	EntryTransfer         = "transfer"
+	EntryLoanDisbursement = "loan_disbursement"
+	EntryLoanRepayment    = "loan_repayment"
+	EntryInterest         = "interest"
+	EntryAccountClosure   = "account_closure"
+	EntryInterestAccrual  = "interest_accrual"
+	EntryInterestPosting  = "interest_posting"
+	EntryLoanCharge       = "loan_charge"
+	EntryLoanInterestCut  = "loan_interest_cut"
+	EntryLoanRateReset    = "loan_rate_reset"
+	EntryLoanRestructure  = "loan_restructure"
+	EntryLoanWriteOff     = "loan_write_off"
+	EntryLoanRecovery     = "loan_recovery"
+)
+
+var systemLedgerAccounts = map[string]struct {
+	name        string
+	accountType string
+}{
+	LedgerCash:            {"Cash", models.LedgerTypeAsset},
+	LedgerInterestIncome:  {"Interest income", models.LedgerTypeIncome},
+	LedgerInterestExpense: {"Interest expense", models.LedgerTypeExpense},
+	LedgerInterestPayable: {"Interest payable", models.LedgerTypeLiability},
+	LedgerFeeIncome:       {"Fee income", models.LedgerTypeIncome},
+	LedgerLoanLosses:      {"Loan losses", models.LedgerTypeExpense},
+	LedgerLoanRecoveries:  {"Recoveries on written-off loans", models.LedgerTypeIncome},
+	LedgerRecoveryExpense: {"Recovery expense", models.LedgerTypeExpense},
+}
+
+var ErrUnbalancedEntry = errors.New("journal entry does not balance")
+
+type LedgerService struct {
+	store repository.Store
+}
+
+func NewLedgerService(store repository.Store) *LedgerService {
+	return &LedgerService{store: store}
+}
+
+func Debit(account *models.LedgerAccount, amount money.Money) models.Posting {
+	return models.Posting{LedgerAccountID: account.ID, Amount: amount}
+}
+
+func Credit(account *models.LedgerAccount, amount money.Money) models.Posting {
+	return models.Posting{LedgerAccountID: account.ID, Amount: -amount}
+}
+
+// Post writes a journal entry through tx. The postings must sum to zero.
+func (ls *LedgerService) Post(tx repository.Store, entryType, description string, postings ...models.Posting) (*models.JournalEntry, error) {
+	if len(postings) < 2 {
+		return nil, fmt.Errorf("%w: at least two postings are required", ErrUnbalancedEntry)
+	}
+	var total money.Money
+	for _, p := range postings {
+		total += p.Amount
+	}
+	if !total.IsZero() {
+		return nil, fmt.Errorf("%w: postings sum to %s", ErrUnbalancedEntry, total)
+	}
+
+	entry := models.JournalEntry{
+		Type:        entryType,
+		Description: description,
+		Postings:    postings,
+	}
+	if err := tx.Ledger().CreateEntry(&entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (ls *LedgerService) SystemAccount(tx repository.Store, code string) (*models.LedgerAccount, error) {
+	def, ok := systemLedgerAccounts[code]
+	if !ok {
+		return nil, fmt.Errorf("unknown ledger account %s", code)
+	}
+	return tx.Ledger().EnsureAccount(models.LedgerAccount{Code: code, Name: def.name, Type: def.accountType})
+}
+
+func (ls *LedgerService) SavingsAccount(tx repository.Store, accountID uint) (*models.LedgerAccount, error) {
+	return tx.Ledger().EnsureAccount(models.LedgerAccount{
+		Code:             savingsLedgerCode(accountID),
+		Name:             fmt.Sprintf("Savings account %d", accountID),
+		Type:             models.LedgerTypeLiability,
+		SavingsAccountID: &accountID,
+	})
+}
+
+func (ls *LedgerService) LoanAccount(tx repository.Store, loanID uint) (*models.LedgerAccount, error) {
+	return tx.Ledger().EnsureAccount(models.LedgerAccount{
+		Code:   loanLedgerCode(loanID),
+		Name:   fmt.Sprintf("Loan %d receivable", loanID),
+		Type:   models.LedgerTypeAsset,
+		LoanID: &loanID,
+	})
+}
+
+func (ls *LedgerService) loanUnearnedAccount(tx repository.Store, loanID uint) (*models.LedgerAccount, error) {
+	return tx.Ledger().EnsureAccount(models.LedgerAccount{
+		Code:   fmt.Sprintf("UNEARNED-LOAN-%d", loanID),
+		Name:   fmt.Sprintf("Loan %d unearned interest", loanID),
+		Type:   models.LedgerTypeLiability,
+		LoanID: &loanID,
+	})
+}
+
+func savingsLedgerCode(accountID uint) string {
+	return fmt.Sprintf("SAV-%d", accountID)
+}
+
+func loanLedgerCode(loanID uint) string {
+	return fmt.Sprintf("LOAN-%d", loanID)
+}
+
+func (ls *LedgerService) RecordDeposit(tx repository.Store, accountID uint, amount money.Money) (*models.JournalEntry, error) {
+	cash, err := ls.SystemAccount(tx, LedgerCash)
+	if err != nil {
+		return nil, err
+	}
+	savings, err := ls.SavingsAccount(tx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	return ls.Post(tx, EntryDeposit, fmt.Sprintf("Deposit to account %d", accountID),
+		Debit(cash, amount),
+		Credit(savings, amount),
+	)
+}
+
+func (ls *LedgerService) RecordWithdrawal(tx repository.Store, accountID uint, amount money.Money) (*models.JournalEntry, error) {
+	cash, err := ls.SystemAccount(tx, LedgerCash)
+	if err != nil {
+		return nil, err
+	}
+	savings, err := ls.SavingsAccount(tx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	return ls.Post(tx, EntryWithdrawal, fmt.Sprintf("Withdrawal from account %d", accountID),
+		Debit(savings, amount),
+		Credit(cash, amount),
+	)
+}
+
+// RecordClosureSettlement pays the remaining balance of a closing account
+// out in cash.
+func (ls *LedgerService) RecordClosureSettlement(tx repository.Store, accountID uint, amount money.Money) (*models.JournalEntry, error) {
+	cash, err := ls.SystemAccount(tx, LedgerCash)
+	if err != nil {
+		return nil, err
+	}
+	savings, err := ls.SavingsAccount(tx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	return ls.Post(tx, EntryAccountClosure, fmt.Sprintf("Closing settlement of account %d", accountID),
+		Debit(savings, amount),
+		Credit(cash, amount),
+	)
+}
+
+func (ls *LedgerService) RecordTransfer(tx repository.Store, fromAccountID, toAccountID uint, amount money.Money) (*models.JournalEntry, error) {
+	from, err := ls.SavingsAccount(tx, fromAccountID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := ls.SavingsAccount(tx, toAccountID)
+	if err != nil {
+		return nil, err
+	}
+	return ls.Post(tx, EntryTransfer, fmt.Sprintf("Transfer from account %d to account %d", fromAccountID, toAccountID),
+		Debit(from, amount),
+		Credit(to, amount),
+	)
+}
+
+// RecordLoanDisbursement books the full payable amount as a receivable and
+// pays the principal into the borrower's savings account. The interest part
+// is held as unearned until it is repaid; the processing fee is kept back
+// from the amount paid out and earned straight away.
+func (ls *LedgerService) RecordLoanDisbursement(tx repository.Store, loan *models.Loan, accountID uint) (*models.JournalEntry, error) {
+	savings, err := ls.SavingsAccount(tx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	receivable, err := ls.LoanAccount(tx, loan.ID)
+	if err != nil {
+		return nil, err
+	}
+	unearned, err := ls.loanUnearnedAccount(tx, loan.ID)
+	if err != nil {
+		return nil, err
+	}
+	interest := loan.TotalPayableAmount - loan.PrincipalAmount
+	postings := []models.Posting{
+		Debit(receivable, loan.TotalPayableAmount),
+		Credit(savings, loan.PrincipalAmount-loan.ProcessingFee),
+		Credit(unearned, interest),
+	}
+	if loan.ProcessingFee.IsPositive() {
+		fees, err := ls.SystemAccount(tx, LedgerFeeIncome)
+		if err != nil {
+			return nil, err
+		}
+		postings = append(postings, Credit(fees, loan.ProcessingFee))
+	}
+	return ls.Post(tx, EntryLoanDisbursement, fmt.Sprintf("Disbursement of loan %d to account %d", loan.ID, accountID), postings...)
+}
+
+// RecordLoanRepayment books a repayment from the borrower's savings account
+// against the receivable and a separate interest entry earning the interest
+// part of it out of unearned interest. loan must already reflect the
+// repayment; the final repayment earns whatever interest is left so nothing
+// is stranded by rounding.
+func (ls *LedgerService) RecordLoanRepayment(tx repository.Store, loan *models.Loan, accountID uint, amount, interest money.Money) (*models.JournalEntry, error) {
+	savings, err := ls.SavingsAccount(tx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	receivable, err := ls.LoanAccount(tx, loan.ID)
+	if err != nil {
+		return nil, err
+	}
+	unearned, err := ls.loanUnearnedAccount(tx, loan.ID)
+	if err != nil {
+		return nil, err
+	}
+	income, err := ls.SystemAccount(tx, LedgerInterestIncome)
+	if err != nil {
+		return nil, err
+	}
+
+	remaining, err := tx.Ledger().Balance(unearned.ID)
+	if err != nil {
+		return nil, err
+	}
+	remaining = remaining.Neg()
+
+	earned := remaining
+	if !loan.PendingAmount.IsZero() {
+		earned = money.Min(interest, remaining)
+	}
+
+	entry, err := ls.Post(tx, EntryLoanRepayment, fmt.Sprintf("Repayment of loan %d from account %d", loan.ID, accountID),
+		Debit(savings, amount),
+		Credit(receivable, amount),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if earned.IsPositive() {
+		if _, err := ls.Post(tx, EntryInterest, fmt.Sprintf("Interest earned on loan %d", loan.ID),
+			Debit(unearned, earned),
+			Credit(income, earned),
+		); err != nil {
+			return nil, err
+		}
+	}
+	return entry, nil
+}
+
+// RecordLoanInterestCut takes interest that will no longer be charged, after
+// the loan was paid down early, off the receivable and out of unearned
+// interest.
+func (ls *LedgerService) RecordLoanInterestCut(tx repository.Store, loanID uint, amount money.Money) (*models.JournalEntry, error) {
+	receivable, err := ls.LoanAccount(tx, loanID)
+	if err != nil {
+		return nil, err
+	}
+	unearned, err := ls.loanUnearnedAccount(tx, loanID)
+	if err != nil {
+		return nil, err
+	}
+	return ls.Post(tx, EntryLoanInterestCut, fmt.Sprintf("Interest no longer due on loan %d", loanID),
+		Debit(unearned, amount),
+		Credit(receivable, amount),
+	)
+}
+
+// RecordLoanRateReset adds the extra interest a floating-rate loan is
+// charged after a reset to the receivable and to unearned interest. A
+// negative change, from a lower rate, takes it off both.
+func (ls *LedgerService) RecordLoanRateReset(tx repository.Store, loanID uint, change money.Money) (*models.JournalEntry, error) {
+	return ls.changeLoanInterest(tx, loanID, change, EntryLoanRateReset, fmt.Sprintf("Interest rate reset on loan %d", loanID))
+}
+
+// RecordLoanRestructure adds the change in what a loan owes after a
+// moratorium or restructuring, all of it interest, to the receivable and to
+// unearned interest. A negative change takes it off both.
+func (ls *LedgerService) RecordLoanRestructure(tx repository.Store, loanID uint, change money.Money) (*models.JournalEntry, error) {
+	return ls.changeLoanInterest(tx, loanID, change, EntryLoanRestructure, fmt.Sprintf("Restructuring of loan %d", loanID))
+}
+
+func (ls *LedgerService) changeLoanInterest(tx repository.Store, loanID uint, change money.Money, entryType, description string) (*models.JournalEntry, error) {
+	receivable, err := ls.LoanAccount(tx, loanID)
+	if err != nil {
+		return nil, err
+	}
+	unearned, err := ls.loanUnearnedAccount(tx, loanID)
+	if err != nil {
+		return nil, err
+	}
+	return ls.Post(tx, entryType, description,
+		Debit(receivable, change),
+		Credit(unearned, change),
+	)
+}
+
+// UnearnedInterest returns the interest still held as unearned for a loan.
+func (ls *LedgerService) UnearnedInterest(tx repository.Store, loanID uint) (money.Money, error) {
+	unearned, err := ls.loanUnearnedAccount(tx, loanID)
+	if err != nil {
+		return 0, err
+	}
+	balance, err := tx.Ledger().Balance(unearned.ID)
+	if err != nil {
+		return 0, err
+	}
+	return balance.Neg(), nil
+}
+
+// RecordLoanWriteOff clears amount off a written-off loan's receivable. The
+// interest part of it was never earned and comes out of unearned interest;
+// the rest is charged to loan losses.
+func (ls *LedgerService) RecordLoanWriteOff(tx repository.Store, loanID uint, amount, interest money.Money) (*models.JournalEntry, error) {
+	receivable, err := ls.LoanAccount(tx, loanID)
+	if err != nil {
+		return nil, err
+	}
+	postings := []models.Posting{Credit(receivable, amount)}
+	if interest.IsPositive() {
+		unearned, err := ls.loanUnearnedAccount(tx, loanID)
+		if err != nil {
+			return nil, err
+		}
+		postings = append(postings, Debit(unearned, interest))
+	}
+	if loss := amount - interest; loss.IsPositive() {
+		losses, err := ls.SystemAccount(tx, LedgerLoanLosses)
+		if err != nil {
+			return nil, err
+		}
+		postings = append(postings, Debit(losses, loss))
+	}
+	return ls.Post(tx, EntryLoanWriteOff, fmt.Sprintf("Write-off of loan %d", loanID), postings...)
+}
+
+// RecordLoanRecovery books money recovered on a written-off loan as income,
+// taken from the borrower's savings account when accountID is set and
+// received in cash otherwise. What the recovery cost is paid out in cash as
+// recovery expense.
+func (ls *LedgerService) RecordLoanRecovery(tx repository.Store, loanID uint, accountID *uint, amount, cost money.Money) (*models.JournalEntry, error) {
+	cash, err := ls.SystemAccount(tx, LedgerCash)
+	if err != nil {
+		return nil, err
+	}
+	source := cash
+	if accountID != nil {
+		if source, err = ls.SavingsAccount(tx, *accountID); err != nil {
+			return nil, err
+		}
+	}
+	recoveries, err := ls.SystemAccount(tx, LedgerLoanRecoveries)
+	if err != nil {
+		return nil, err
+	}
+	postings := []models.Posting{
+		Debit(source, amount),
+		Credit(recoveries, amount),
+	}
+	if cost.IsPositive() {
+		expense, err := ls.SystemAccount(tx, LedgerRecoveryExpense)
+		if err != nil {
+			return nil, err
+		}
+		postings = append(postings, Debit(expense, cost), Credit(cash, cost))
+	}
+	return ls.Post(tx, EntryLoanRecovery, fmt.Sprintf("Recovery on written-off loan %d", loanID), postings...)
+}
+
+// RecordLoanCharge adds a charge to the loan's receivable and earns it as fee
+// income.
+func (ls *LedgerService) RecordLoanCharge(tx repository.Store, loanID uint, amount money.Money, description string) (*models.JournalEntry, error) {
+	receivable, err := ls.LoanAccount(tx, loanID)
+	if err != nil {
+		return nil, err
+	}
+	fees, err := ls.SystemAccount(tx, LedgerFeeIncome)
+	if err != nil {
+		return nil, err
+	}
+	return ls.Post(tx, EntryLoanCharge, description,
+		Debit(receivable, amount),
+		Credit(fees, amount),
+	)
+}
+
+// RecordInterestAccrual books interest a savings account has earned but not
+// yet been paid as an expense owed to the customer.
+func (ls *LedgerService) RecordInterestAccrual(tx repository.Store, accountID uint, amount money.Money) (*models.JournalEntry, error) {
+	expense, err := ls.SystemAccount(tx, LedgerInterestExpense)
+	if err != nil {
+		return nil, err
+	}
+	payable, err := ls.SystemAccount(tx, LedgerInterestPayable)
+	if err != nil {
+		return nil, err
+	}
+	return ls.Post(tx, EntryInterestAccrual, fmt.Sprintf("Interest accrued on account %d", accountID),
+		Debit(expense, amount),
+		Credit(payable, amount),
+	)
+}
+
+// RecordInterestPosting pays accrued interest into the savings account.
+func (ls *LedgerService) RecordInterestPosting(tx repository.Store, accountID uint, amount money.Money) (*models.JournalEntry, error) {
+	payable, err := ls.SystemAccount(tx, LedgerInterestPayable)
+	if err != nil {
+		return nil, err
+	}
+	savings, err := ls.SavingsAccount(tx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	return ls.Post(tx, EntryInterestPosting, fmt.Sprintf("Interest paid to account %d", accountID),
+		Debit(payable, amount),
+		Credit(savings, amount),
+	)
+}
+
+// Balance returns the debit-positive balance of a ledger account.
+func (ls *LedgerService) Balance(ledgerAccountID uint) (money.Money, error) {
+	return ls.store.Ledger().Balance(ledgerAccountID)
+}
+
+func (ls *LedgerService) GetAccountByCode(code string) (*models.LedgerAccount, error) {
+	account, err := ls.store.Ledger().GetAccountByCode(code)
+	if err != nil {
+		return nil, lookupError("ledger account", err)
+	}
+	return account, nil
+}
+
+func (ls *LedgerService) GetEntry(entryID uint) (*models.JournalEntry, error) {
+	entry, err := ls.store.Ledger().GetEntry(entryID)
+	if err != nil {
+		return nil, lookupError("journal entry", err)
+	}
+	return entry, nil
+}
+
+type LedgerMismatch struct {
+	Resource      string      `json:"resource"`
+	ID            uint        `json:"id"`
+	Recorded      money.Money `json:"recorded"`
+	LedgerBalance money.Money `json:"ledger_balance"`
+}
+
+// Reconcile checks every savings balance and loan pending amount against the
+// ledger and reports the ones that differ.
+func (ls *LedgerService) Reconcile() ([]LedgerMismatch, error) {
+	mismatches := []LedgerMismatch{}
+
+	accounts, err := ls.store.Accounts().List()
+	if err != nil {
+		return nil, err
+	}
+	for _, account := range accounts {
+		ledgerBalance, err := ls.balanceByCode(savingsLedgerCode(account.ID))
+		if err != nil {
+			return nil, err
+		}
+		ledgerBalance = ledgerBalance.Neg()
+		if ledgerBalance != account.Balance {
+			mismatches = append(mismatches, LedgerMismatch{
+				Resource:      "savings_account",
+				ID:            account.ID,
+				Recorded:      account.Balance,
+				LedgerBalance: ledgerBalance,
+			})
+		}
+	}
+
+	loans, err := ls.store.Loans().List()
+	if err != nil {
+		return nil, err
+	}
+	for _, loan := range loans {
+		ledgerBalance, err := ls.balanceByCode(loanLedgerCode(loan.ID))
+		if err != nil {
+			return nil, err
+		}
+		if ledgerBalance != loan.PendingAmount {
+			mismatches = append(mismatches, LedgerMismatch{
+				Resource:      "loan",
+				ID:            loan.ID,
+				Recorded:      loan.PendingAmount,
+				LedgerBalance: ledgerBalance,
+			})
+		}
+	}
+	return mismatches, nil
+}
+
+func (ls *LedgerService) balanceByCode(code string) (money.Money, error) {
+	account, err := ls.store.Ledger().GetAccountByCode(code)
+	if errors.Is(err, repository.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return ls.store.Ledger().Balance(account.ID)
+}
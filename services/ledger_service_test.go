@@ -0,0 +1,46 @@
+package services
+
+import (
+	"banking-system/money"
+	"testing"
+)
+
+func TestLedgerFollowsDepositsAndWithdrawals(t *testing.T) {
+	f := newFixture(t)
+	account := f.openAccount(f.customer.ID, money.MustParse("250"))
+	accounts := NewAccountService(f.store)
+	if _, err := accounts.Withdraw(account.ID, money.MustParse("75.50")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := accounts.Deposit(account.ID, money.MustParse("10")); err != nil {
+		t.Fatal(err)
+	}
+
+	want := money.MustParse("184.50")
+	if got := f.account(account.ID).Balance; got != want {
+		t.Errorf("balance = %s, want %s", got, want)
+	}
+	if got := f.ledgerBalance(LedgerCash); got != want {
+		t.Errorf("cash = %s, want %s", got, want)
+	}
+	if got := f.ledgerBalance(savingsLedgerCode(account.ID)); got != want.Neg() {
+		t.Errorf("savings ledger = %s, want %s", got, want.Neg())
+	}
+	f.assertReconciled()
+}
+
+func TestReconcileReportsDrift(t *testing.T) {
+	f := newFixture(t)
+	account := f.openAccount(f.customer.ID, money.MustParse("100"))
+	drifted := f.account(account.ID)
+	drifted.Balance += money.MustParse("1")
+	f.must(f.store.Accounts().Update(&drifted))
+
+	mismatches, err := NewLedgerService(f.store).Reconcile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mismatches) != 1 || mismatches[0].ID != account.ID || mismatches[0].LedgerBalance != money.MustParse("100") {
+		t.Fatalf("mismatches = %+v, want account %d at 100.00", mismatches, account.ID)
+	}
+}
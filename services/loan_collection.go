@@ -0,0 +1,298 @@
+package services
+
+import (
+	"banking-system/models"
+	"banking-system/money"
+	"banking-system/repository"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	CollectionCollected = "COLLECTED"
+	CollectionBounced   = "BOUNCED"
+)
+
+const (
+	BounceInsufficientFunds = "insufficient_funds"
+	BounceAccountInactive   = "account_inactive"
+	BounceAccountNotHeld    = "account_not_held"
+)
+
+var errAlreadyCollected = errors.New("installment already attempted on this date")
+
+// CollectionRun summarises one auto-debit job. Failed counts loans whose
+// collection hit an error, which a rerun retries.
+type CollectionRun struct {
+	BusinessDate  time.Time   `json:"business_date"`
+	Collected     int         `json:"collected"`
+	Bounced       int         `json:"bounced"`
+	Failed        int         `json:"failed"`
+	FailedLoanIDs []uint      `json:"failed_loan_ids,omitempty"`
+	Amount        money.Money `json:"amount"`
+	Charges       money.Money `json:"charges"`
+}
+
+type CollectionService struct {
+	store repository.Store
+	loans *LoanService
+	now   func() time.Time
+}
+
+func NewCollectionService(store repository.Store) *CollectionService {
+	return &CollectionService{store: store, loans: NewLoanService(store), now: time.Now}
+}
+
+// collectionPolicy is how a loan's bounced debits are charged and retried,
+// and what it is charged for paying late.
+type collectionPolicy struct {
+	bounceCharge      money.Money
+	retryIntervalDays int
+	maxRetries        int
+	lateFee           money.Money
+	penalInterestRate float64
+}
+
+// loanCollectionPolicy reads the policy from the loan's product. Loans from
+// before the catalog are retried on the defaults and never charged.
+func loanCollectionPolicy(tx repository.Store, loan *models.Loan) (collectionPolicy, error) {
+	if loan.ProductID == nil {
+		return collectionPolicy{retryIntervalDays: DefaultRetryIntervalDays, maxRetries: DefaultMaxRetries}, nil
+	}
+	product, err := tx.LoanProducts().Get(*loan.ProductID)
+	if err != nil {
+		return collectionPolicy{}, lookupError("loan product", err)
+	}
+	return collectionPolicy{
+		bounceCharge:      product.BounceCharge,
+		retryIntervalDays: product.RetryIntervalDays,
+		maxRetries:        product.MaxRetries,
+		lateFee:           product.LateFee,
+		penalInterestRate: product.PenalInterestRate,
+	}, nil
+}
+
+// Collect auto-debits every active loan's installments due on or before date
+// from its repayment account. An installment is first tried on its due date;
+// a bounce is charged and retried every retry interval until the product's
+// retries run out. Each installment is tried at most once per business date,
+// so the job can be rerun safely. A loan that fails is logged and counted in
+// the run rather than stopping it.
+func (cs *CollectionService) Collect(date time.Time) (*CollectionRun, error) {
+	date = BusinessDate(date)
+	if date.After(BusinessDate(cs.now())) {
+		return nil, fmt.Errorf("%w: business date %s is in the future", ErrInvalidArgument, date.Format(time.DateOnly))
+	}
+	loans, err := cs.store.Loans().Find(repository.LoanFilter{Status: LoanStatusActive})
+	if err != nil {
+		return nil, err
+	}
+
+	run := CollectionRun{BusinessDate: date}
+	for _, loan := range loans {
+		if loan.RepaymentAccountID == nil {
+			continue
+		}
+		for {
+			var collection *models.LoanCollection
+			err := cs.store.Atomic(func(tx repository.Store) error {
+				var err error
+				collection, err = cs.collectNext(tx, loan.ID, date)
+				return err
+			})
+			if errors.Is(err, errAlreadyCollected) {
+				break
+			}
+			if err != nil {
+				log.Printf("collections: collecting loan %d for %s: %v", loan.ID, date.Format(time.DateOnly), err)
+				run.Failed++
+				run.FailedLoanIDs = append(run.FailedLoanIDs, loan.ID)
+				break
+			}
+			if collection == nil {
+				break
+			}
+			if collection.Status == CollectionBounced {
+				run.Bounced++
+				run.Charges += collection.BounceCharge
+				break
+			}
+			run.Collected++
+			run.Amount += collection.Amount
+		}
+	}
+	return &run, nil
+}
+
+// collectNext attempts the oldest installment of the loan that is due for an
+// attempt on date, and returns nil when there is none. The amount taken
+// covers outstanding charges and every earlier unpaid installment too, since
+// repayments settle those first.
+func (cs *CollectionService) collectNext(tx repository.Store, loanID uint, date time.Time) (*models.LoanCollection, error) {
+	loan, err := tx.Loans().GetForUpdate(loanID)
+	if err != nil {
+		return nil, lookupError("loan", err)
+	}
+	if loan.Status != LoanStatusActive || loan.RepaymentAccountID == nil {
+		return nil, nil
+	}
+	installments, err := tx.Loans().ListInstallments(loan.ID)
+	if err != nil {
+		return nil, err
+	}
+	collections, err := tx.Loans().ListCollections(loan.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	amount := loan.ChargesDue
+	for _, installment := range installments {
+		if installment.Status == InstallmentPaid {
+			continue
+		}
+		if installment.DueDate.After(date) {
+			break
+		}
+		amount += installment.Amount - installment.PrincipalPaid - installment.InterestPaid
+		attempt, due := nextAttempt(collections, installment.ID, date)
+		if !due {
+			continue
+		}
+		return cs.attempt(tx, loan, installment, attempt, money.Min(amount, loan.PendingAmount), date)
+	}
+	return nil, nil
+}
+
+// nextAttempt numbers the next attempt on an installment and reports whether
+// it is due on date.
+func nextAttempt(collections []models.LoanCollection, installmentID uint, date time.Time) (int, bool) {
+	var last *models.LoanCollection
+	attempts := 0
+	for i := range collections {
+		if collections[i].InstallmentID == installmentID {
+			last = &collections[i]
+			attempts++
+		}
+	}
+	if last == nil {
+		return 1, true
+	}
+	return attempts + 1, last.NextAttemptDate != nil && !last.NextAttemptDate.After(date)
+}
+
+func (cs *CollectionService) attempt(tx repository.Store, loan *models.Loan, installment models.LoanInstallment, attempt int, amount money.Money, date time.Time) (*models.LoanCollection, error) {
+	collection := models.LoanCollection{
+		LoanID:        loan.ID,
+		InstallmentID: installment.ID,
+		AccountID:     *loan.RepaymentAccountID,
+		BusinessDate:  date,
+		Attempt:       attempt,
+		Amount:        amount,
+	}
+	account, err := tx.Accounts().GetForUpdate(collection.AccountID)
+	if err != nil {
+		return nil, lookupError("account", err)
+	}
+	switch _, err := tx.Accounts().FindHolder(account.ID, loan.CustomerID); {
+	case errors.Is(err, repository.ErrNotFound):
+		collection.FailureReason = BounceAccountNotHeld
+	case err != nil:
+		return nil, err
+	case canDebit(account) != nil:
+		collection.FailureReason = BounceAccountInactive
+	case account.Balance < amount:
+		collection.FailureReason = BounceInsufficientFunds
+	}
+
+	if collection.FailureReason == "" {
+		payment, err := cs.loans.repay(tx, loan, account, amount)
+		if err != nil {
+			return nil, err
+		}
+		collection.Status = CollectionCollected
+		collection.PaymentID = &payment.ID
+	} else if err := cs.bounce(tx, loan, &collection); err != nil {
+		return nil, err
+	}
+
+	created, err := tx.Loans().CreateCollection(&collection)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, errAlreadyCollected
+	}
+	return &collection, nil
+}
+
+// bounce charges the loan for a failed debit and schedules the retry, if any
+// are left.
+func (cs *CollectionService) bounce(tx repository.Store, loan *models.Loan, collection *models.LoanCollection) error {
+	policy, err := loanCollectionPolicy(tx, loan)
+	if err != nil {
+		return err
+	}
+	collection.Status = CollectionBounced
+	if collection.Attempt <= policy.maxRetries {
+		next := collection.BusinessDate.AddDate(0, 0, policy.retryIntervalDays)
+		collection.NextAttemptDate = &next
+	}
+	if !policy.bounceCharge.IsPositive() {
+		return nil
+	}
+
+	entry, err := cs.loans.ledger.RecordLoanCharge(tx, loan.ID, policy.bounceCharge,
+		fmt.Sprintf("Bounce charge on loan %d installment %d", loan.ID, collection.InstallmentID))
+	if err != nil {
+		return err
+	}
+	loan.ChargesDue += policy.bounceCharge
+	loan.PendingAmount += policy.bounceCharge
+	if err := tx.Loans().Update(loan); err != nil {
+		return err
+	}
+	collection.BounceCharge = policy.bounceCharge
+	collection.ChargeEntryID = &entry.ID
+	return nil
+}
+
+// SetRepaymentAccount nominates the savings account the loan's installments
+// are auto-debited from. The borrower must hold it.
+func (ls *LoanService) SetRepaymentAccount(loanID, accountID uint) (*models.Loan, error) {
+	var loan *models.Loan
+	err := ls.store.Atomic(func(tx repository.Store) error {
+		var err error
+		loan, err = tx.Loans().GetForUpdate(loanID)
+		if err != nil {
+			return lookupError("loan", err)
+		}
+		if loan.Status == LoanStatusClosed || loan.Status == LoanStatusRejected || loan.Status == LoanStatusWrittenOff {
+			return fmt.Errorf("%w: loan %d is %s", ErrConflict, loan.ID, loan.Status)
+		}
+		account, err := tx.Accounts().Get(accountID)
+		if err != nil {
+			return lookupError("account", err)
+		}
+		if err := checkHolder(tx, account.ID, loan.CustomerID); err != nil {
+			return err
+		}
+		if account.Status == AccountStatusClosed {
+			return accountInactive(account)
+		}
+		loan.RepaymentAccountID = &account.ID
+		return tx.Loans().Update(loan)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return loan, nil
+}
+
+func (ls *LoanService) GetCollections(loanID uint) ([]models.LoanCollection, error) {
+	if _, err := ls.store.Loans().Get(loanID); err != nil {
+		return nil, lookupError("loan", err)
+	}
+	return ls.store.Loans().ListCollections(loanID)
+}
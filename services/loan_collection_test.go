@@ -0,0 +1,168 @@
+package services
+
+import (
+	"banking-system/models"
+	"banking-system/money"
+	"slices"
+	"testing"
+	"time"
+)
+
+// useCollectionPolicy switches the fixture to a product charging 5.00 for a
+// bounced debit and retrying it every 3 days, at most twice.
+func useCollectionPolicy(f *fixture) {
+	f.t.Helper()
+	f.useProduct("COLLECT", func(product *models.LoanProduct) {
+		product.BounceCharge = money.MustParse("5")
+		product.RetryIntervalDays = 3
+		product.MaxRetries = 2
+	})
+}
+
+// unfundedLoan disburses 1,200 over 12 months into a fresh account and
+// empties it, so the first auto-debit bounces. It returns the loan and the
+// date its first installment falls due.
+func unfundedLoan(f *fixture) (models.Loan, time.Time) {
+	f.t.Helper()
+	account := f.openAccount(f.customer.ID, 0)
+	loan := f.disburseLoan(account.ID, money.MustParse("1200"), 12)
+	_, err := NewAccountService(f.store).Withdraw(account.ID, f.account(account.ID).Balance)
+	f.must(err)
+	installments, err := f.store.Loans().ListInstallments(loan.ID)
+	f.must(err)
+	return loan, installments[0].DueDate
+}
+
+func TestCollectCarriesOnPastAFailedLoan(t *testing.T) {
+	f := newFixture(t)
+	account := f.openAccount(f.customer.ID, money.MustParse("5000"))
+	good := f.disburseLoan(account.ID, money.MustParse("1200"), 12)
+	bad := f.disburseLoan(account.ID, money.MustParse("1200"), 12)
+	due := BusinessDate(time.Now()).AddDate(0, 1, 1)
+
+	collections := NewCollectionService(failingStore{Store: f.store, loanID: bad.ID})
+	collections.now = func() time.Time { return due }
+	run, err := collections.Collect(due)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Collected != 1 || run.Failed != 1 || !slices.Equal(run.FailedLoanIDs, []uint{bad.ID}) {
+		t.Fatalf("run = %+v, want loan %d collected and loan %d failed", run, good.ID, bad.ID)
+	}
+	if run.Amount != good.InstallmentAmount {
+		t.Errorf("collected %s, want one installment of %s", run.Amount, good.InstallmentAmount)
+	}
+	f.assertReconciled()
+}
+
+func TestClassifyCarriesOnPastAFailedLoan(t *testing.T) {
+	f := newFixture(t)
+	account := f.openAccount(f.customer.ID, 0)
+	good := f.disburseLoan(account.ID, money.MustParse("1200"), 12)
+	bad := f.disburseLoan(account.ID, money.MustParse("1200"), 12)
+	overdue := BusinessDate(time.Now()).AddDate(0, 1, 10)
+
+	collections := NewCollectionService(failingStore{Store: f.store, loanID: bad.ID})
+	collections.now = func() time.Time { return overdue.AddDate(0, 0, 1) }
+	run, err := collections.Classify(overdue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Classified != 1 || run.Reclassified != 1 || run.Failed != 1 || !slices.Equal(run.FailedLoanIDs, []uint{bad.ID}) {
+		t.Fatalf("run = %+v, want loan %d classified and loan %d failed", run, good.ID, bad.ID)
+	}
+	loan, err := f.store.Loans().Get(good.ID)
+	f.must(err)
+	if loan.DelinquencyBucket != Bucket1To30 {
+		t.Errorf("loan %d is in %s, want %s", good.ID, loan.DelinquencyBucket, Bucket1To30)
+	}
+	f.assertReconciled()
+}
+
+func TestBouncesAreChargedAndRetriedUntilRetriesRunOut(t *testing.T) {
+	f := newFixture(t)
+	useCollectionPolicy(f)
+	loan, due := unfundedLoan(f)
+	collections := NewCollectionService(f.store)
+	collections.now = func() time.Time { return due.AddDate(0, 0, 30) }
+
+	// The first attempt is on the due date and the two retries 3 and 6 days
+	// later; nothing is tried in between or after.
+	bounces := map[int]bool{0: true, 3: true, 6: true}
+	for day := 0; day <= 12; day++ {
+		date := due.AddDate(0, 0, day)
+		run, err := collections.Collect(date)
+		f.must(err)
+		want := CollectionRun{BusinessDate: date}
+		if bounces[day] {
+			want.Bounced, want.Charges = 1, money.MustParse("5")
+		}
+		if run.BusinessDate != want.BusinessDate || run.Bounced != want.Bounced || run.Charges != want.Charges || run.Collected != 0 || run.Failed != 0 {
+			t.Errorf("day %d: run = %+v, want %+v", day, *run, want)
+		}
+	}
+
+	attempts, err := f.store.Loans().ListCollections(loan.ID)
+	f.must(err)
+	if len(attempts) != 3 {
+		t.Fatalf("loan has %d collection attempts, want 3", len(attempts))
+	}
+	for i, attempt := range attempts {
+		var next *time.Time
+		if i < 2 {
+			retry := attempt.BusinessDate.AddDate(0, 0, 3)
+			next = &retry
+		}
+		if attempt.Attempt != i+1 || attempt.Status != CollectionBounced || attempt.FailureReason != BounceInsufficientFunds ||
+			attempt.BounceCharge != money.MustParse("5") || !attempt.BusinessDate.Equal(due.AddDate(0, 0, 3*i)) {
+			t.Errorf("attempt %d = %+v", i+1, attempt)
+		}
+		if (attempt.NextAttemptDate == nil) != (next == nil) || next != nil && !attempt.NextAttemptDate.Equal(*next) {
+			t.Errorf("attempt %d is retried on %v, want %v", i+1, attempt.NextAttemptDate, next)
+		}
+	}
+
+	bounced, err := f.store.Loans().Get(loan.ID)
+	f.must(err)
+	if bounced.ChargesDue != money.MustParse("15") || bounced.PendingAmount != loan.PendingAmount+money.MustParse("15") {
+		t.Errorf("loan owes %s of charges and %s in all, want 15.00 and %s", bounced.ChargesDue, bounced.PendingAmount, loan.PendingAmount+money.MustParse("15"))
+	}
+	f.assertReconciled()
+}
+
+func TestRetryCollectsTheBounceChargeToo(t *testing.T) {
+	f := newFixture(t)
+	useCollectionPolicy(f)
+	loan, due := unfundedLoan(f)
+	collections := NewCollectionService(f.store)
+	collections.now = func() time.Time { return due.AddDate(0, 0, 30) }
+
+	run, err := collections.Collect(due)
+	f.must(err)
+	if run.Bounced != 1 {
+		t.Fatalf("run = %+v, want one bounce", *run)
+	}
+	_, err = NewAccountService(f.store).Deposit(*loan.RepaymentAccountID, money.MustParse("500"))
+	f.must(err)
+
+	// Money arriving early does not bring the retry forward.
+	run, err = collections.Collect(due.AddDate(0, 0, 2))
+	f.must(err)
+	if run.Collected != 0 || run.Bounced != 0 {
+		t.Errorf("run before the retry date = %+v, want nothing attempted", *run)
+	}
+	run, err = collections.Collect(due.AddDate(0, 0, 3))
+	f.must(err)
+	if want := loan.InstallmentAmount + money.MustParse("5"); run.Collected != 1 || run.Amount != want {
+		t.Errorf("retry run = %+v, want the installment and the bounce charge, %s, collected", *run, want)
+	}
+	if balance := f.account(*loan.RepaymentAccountID).Balance; balance != money.MustParse("500")-loan.InstallmentAmount-money.MustParse("5") {
+		t.Errorf("account holds %s after the retry", balance)
+	}
+	settled, err := f.store.Loans().Get(loan.ID)
+	f.must(err)
+	if !settled.ChargesDue.IsZero() {
+		t.Errorf("loan still owes %s of charges", settled.ChargesDue)
+	}
+	f.assertReconciled()
+}
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"banking-system/interest"
+	"banking-system/models"
+	"banking-system/money"
+	"banking-system/repository"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+)
+
+// Delinquency buckets, by days past due.
+const (
+	BucketCurrent = "CURRENT"
+	Bucket1To30   = "DPD_1_30"
+	Bucket31To60  = "DPD_31_60"
+	Bucket61To90  = "DPD_61_90"
+	BucketNPA     = "NPA"
+)
+
+var errAlreadyClassified = errors.New("loan already classified for this date")
+
+// ClassificationRun summarises one nightly delinquency classification.
+// Failed counts loans that could not be classified, which a rerun retries.
+type ClassificationRun struct {
+	BusinessDate  time.Time   `json:"business_date"`
+	Classified    int         `json:"classified"`
+	Reclassified  int         `json:"reclassified"`
+	Skipped       int         `json:"skipped"`
+	Failed        int         `json:"failed"`
+	FailedLoanIDs []uint      `json:"failed_loan_ids,omitempty"`
+	LateFees      money.Money `json:"late_fees"`
+	PenalInterest money.Money `json:"penal_interest"`
+}
+
+// delinquencyBucket places a loan whose oldest unpaid installment is dpd days
+// past due. Loans more than 90 days past due are non-performing.
+func delinquencyBucket(dpd int) string {
+	switch {
+	case dpd <= 0:
+		return BucketCurrent
+	case dpd <= 30:
+		return Bucket1To30
+	case dpd <= 60:
+		return Bucket31To60
+	case dpd <= 90:
+		return Bucket61To90
+	default:
+		return BucketNPA
+	}
+}
+
+// daysBetween counts the days from one business date to a later one.
+func daysBetween(from, to time.Time) int {
+	return int(to.Sub(from).Hours() / 24)
+}
+
+// Classify works out each active loan's days past due at the end of date
+// and moves it to the matching bucket. An installment is overdue from the
+// day after it falls due; each overdue installment is charged the product's
+// late fee once, and penal interest accrues daily on what is left of it.
+// Loans already classified for date are skipped, so the job can be rerun
+// safely, and a missed night is caught up by the next run. A loan that fails
+// is logged and counted in the run rather than stopping it.
+func (cs *CollectionService) Classify(date time.Time) (*ClassificationRun, error) {
+	date = BusinessDate(date)
+	if date.AddDate(0, 0, 1).After(cs.now()) {
+		return nil, fmt.Errorf("%w: business date %s has not ended", ErrInvalidArgument, date.Format(time.DateOnly))
+	}
+	loans, err := cs.store.Loans().Find(repository.LoanFilter{Status: LoanStatusActive})
+	if err != nil {
+		return nil, err
+	}
+
+	run := ClassificationRun{BusinessDate: date}
+	for _, loan := range loans {
+		var result *classification
+		err := cs.store.Atomic(func(tx repository.Store) error {
+			var err error
+			result, err = cs.classify(tx, loan.ID, date)
+			return err
+		})
+		if errors.Is(err, errAlreadyClassified) {
+			run.Skipped++
+			continue
+		}
+		if err != nil {
+			log.Printf("collections: classifying loan %d for %s: %v", loan.ID, date.Format(time.DateOnly), err)
+			run.Failed++
+			run.FailedLoanIDs = append(run.FailedLoanIDs, loan.ID)
+			continue
+		}
+		if result == nil {
+			continue
+		}
+		run.Classified++
+		if result.reclassified {
+			run.Reclassified++
+		}
+		run.LateFees += result.lateFees
+		run.PenalInterest += result.penalInterest
+	}
+	return &run, nil
+}
+
+type classification struct {
+	reclassified  bool
+	lateFees      money.Money
+	penalInterest money.Money
+}
+
+func (cs *CollectionService) classify(tx repository.Store, loanID uint, date time.Time) (*classification, error) {
+	loan, err := tx.Loans().GetForUpdate(loanID)
+	if err != nil {
+		return nil, lookupError("loan", err)
+	}
+	if loan.Status != LoanStatusActive {
+		return nil, nil
+	}
+	if loan.ClassifiedThrough != nil && !loan.ClassifiedThrough.Before(date) {
+		return nil, errAlreadyClassified
+	}
+	policy, err := loanCollectionPolicy(tx, loan)
+	if err != nil {
+		return nil, err
+	}
+	installments, err := tx.Loans().ListInstallments(loan.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var result classification
+	var overdue money.Money
+	dpd := 0
+	penal := new(big.Rat)
+	for i := range installments {
+		installment := &installments[i]
+		if installment.Status == InstallmentPaid {
+			continue
+		}
+		if !installment.DueDate.Before(date) {
+			break
+		}
+		unpaid := installment.Amount - installment.PrincipalPaid - installment.InterestPaid
+		overdue += unpaid
+		dpd = max(dpd, daysBetween(installment.DueDate, date))
+
+		from := installment.DueDate
+		if loan.ClassifiedThrough != nil && loan.ClassifiedThrough.After(from) {
+			from = *loan.ClassifiedThrough
+		}
+		penal.Add(penal, interest.Simple(unpaid, policy.penalInterestRate, interest.DayCount(loan.DayCount), from, date))
+
+		if installment.LateFee.IsZero() && policy.lateFee.IsPositive() {
+			installment.LateFee = policy.lateFee
+			if err := tx.Loans().UpdateInstallment(installment); err != nil {
+				return nil, err
+			}
+			result.lateFees += policy.lateFee
+		}
+	}
+	if result.penalInterest, err = money.FromRat(penal); err != nil {
+		return nil, err
+	}
+
+	if result.lateFees.IsPositive() {
+		if _, err := cs.loans.ledger.RecordLoanCharge(tx, loan.ID, result.lateFees,
+			fmt.Sprintf("Late fees on loan %d", loan.ID)); err != nil {
+			return nil, err
+		}
+	}
+	if result.penalInterest.IsPositive() {
+		if _, err := cs.loans.ledger.RecordLoanCharge(tx, loan.ID, result.penalInterest,
+			fmt.Sprintf("Penal interest on loan %d through %s", loan.ID, date.Format(time.DateOnly))); err != nil {
+			return nil, err
+		}
+	}
+	charges := result.lateFees + result.penalInterest
+	loan.ChargesDue += charges
+	loan.PendingAmount += charges
+
+	bucket := delinquencyBucket(dpd)
+	if bucket != loan.DelinquencyBucket {
+		err := tx.Loans().AddClassification(&models.LoanClassification{
+			LoanID:        loan.ID,
+			BusinessDate:  date,
+			FromBucket:    loan.DelinquencyBucket,
+			ToBucket:      bucket,
+			DaysPastDue:   dpd,
+			OverdueAmount: overdue,
+		})
+		if err != nil {
+			return nil, err
+		}
+		result.reclassified = true
+	}
+	loan.DaysPastDue = dpd
+	loan.OverdueAmount = overdue
+	loan.DelinquencyBucket = bucket
+	loan.ClassifiedThrough = &date
+	if err := tx.Loans().Update(loan); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (ls *LoanService) GetClassificationHistory(loanID uint) ([]models.LoanClassification, error) {
+	if _, err := ls.store.Loans().Get(loanID); err != nil {
+		return nil, lookupError("loan", err)
+	}
+	return ls.store.Loans().ClassificationHistory(loanID)
+}
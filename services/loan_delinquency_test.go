@@ -0,0 +1,120 @@
+package services
+
+import (
+	"banking-system/interest"
+	"banking-system/models"
+	"banking-system/money"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestDelinquencyBucket(t *testing.T) {
+	tests := []struct {
+		dpd  int
+		want string
+	}{
+		{-1, BucketCurrent},
+		{0, BucketCurrent},
+		{1, Bucket1To30},
+		{30, Bucket1To30},
+		{31, Bucket31To60},
+		{60, Bucket31To60},
+		{61, Bucket61To90},
+		{90, Bucket61To90},
+		{91, BucketNPA},
+		{365, BucketNPA},
+	}
+	for _, tt := range tests {
+		if got := delinquencyBucket(tt.dpd); got != tt.want {
+			t.Errorf("delinquencyBucket(%d) = %s, want %s", tt.dpd, got, tt.want)
+		}
+	}
+}
+
+// The loan is 1,200 at 12% over 12 months, so each installment is 106.62.
+// The product charges a 10.00 late fee and 24% penal interest.
+func TestLateFeesAndPenalInterest(t *testing.T) {
+	f := newFixture(t)
+	f.useProduct("LATE", func(product *models.LoanProduct) {
+		product.LateFee = money.MustParse("10")
+		product.PenalInterestRate = 24
+	})
+	account := f.openAccount(f.customer.ID, 0)
+	loan := f.disburseLoan(account.ID, money.MustParse("1200"), 12)
+	installments, err := f.store.Loans().ListInstallments(loan.ID)
+	f.must(err)
+	first, second := installments[0].DueDate, installments[1].DueDate
+	dayCount := interest.DayCount(loan.DayCount)
+	collections := NewCollectionService(f.store)
+	collections.now = func() time.Time { return second.AddDate(0, 0, 30) }
+
+	// penal rounds the penal interest on each unpaid amount over its days,
+	// added up exactly, the way one classification run does.
+	type span struct {
+		unpaid   money.Money
+		from, to time.Time
+	}
+	penal := func(spans ...span) money.Money {
+		total := new(big.Rat)
+		for _, s := range spans {
+			total.Add(total, interest.Simple(s.unpaid, 24, dayCount, s.from, s.to))
+		}
+		amount, err := money.FromRat(total)
+		f.must(err)
+		return amount
+	}
+	classify := func(date time.Time, lateFees, penalInterest money.Money) {
+		t.Helper()
+		run, err := collections.Classify(date)
+		f.must(err)
+		if run.Classified != 1 || run.LateFees != lateFees || run.PenalInterest != penalInterest {
+			t.Errorf("classifying %s: run = %+v, want %s of late fees and %s of penal interest",
+				date.Format(time.DateOnly), *run, lateFees, penalInterest)
+		}
+	}
+
+	// Ten days late, the first installment is charged its late fee and
+	// penal interest on its own 106.62, not on the loan's principal.
+	installment := loan.InstallmentAmount
+	classify(first.AddDate(0, 0, 10), money.MustParse("10"), penal(span{installment, first, first.AddDate(0, 0, 10)}))
+
+	// A day later only the day's penal interest is added.
+	classify(first.AddDate(0, 0, 11), 0, penal(span{installment, first.AddDate(0, 0, 10), first.AddDate(0, 0, 11)}))
+
+	// Paying the charges and 50.00 of the installment leaves 56.62 overdue.
+	owing, err := f.store.Loans().Get(loan.ID)
+	f.must(err)
+	_, err = NewAccountService(f.store).Deposit(account.ID, owing.ChargesDue)
+	f.must(err)
+	_, err = NewLoanService(f.store).RepayLoan(loan.ID, account.ID, owing.ChargesDue+money.MustParse("50"))
+	f.must(err)
+	left := installment - money.MustParse("50")
+
+	// A day after the second installment falls due, the run catches up: penal
+	// interest on what is left of the first since the last run and on the
+	// second for its one day, and a late fee for the second alone.
+	catchUp := second.AddDate(0, 0, 1)
+	classify(catchUp, money.MustParse("10"), penal(
+		span{left, first.AddDate(0, 0, 11), catchUp},
+		span{installment, second, catchUp},
+	))
+
+	installments, err = f.store.Loans().ListInstallments(loan.ID)
+	f.must(err)
+	for i, installment := range installments {
+		want := money.Money(0)
+		if i < 2 {
+			want = money.MustParse("10")
+		}
+		if installment.LateFee != want {
+			t.Errorf("installment %d was charged %s of late fees, want %s", installment.Number, installment.LateFee, want)
+		}
+	}
+	classified, err := f.store.Loans().Get(loan.ID)
+	f.must(err)
+	if classified.OverdueAmount != left+installment || classified.DaysPastDue != daysBetween(first, catchUp) {
+		t.Errorf("loan is %d days past due with %s overdue, want %d days with %s", classified.DaysPastDue, classified.OverdueAmount, daysBetween(first, catchUp), left+installment)
+	}
+	f.assertReconciled()
+}
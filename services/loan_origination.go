@@ -0,0 +1,364 @@
+package services
+
+import (
+	"banking-system/models"
+	"banking-system/money"
+	"banking-system/repository"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	LoanStatusSubmitted   = "SUBMITTED"
+	LoanStatusUnderReview = "UNDER_REVIEW"
+	LoanStatusApproved    = "APPROVED"
+	LoanStatusRejected    = "REJECTED"
+)
+
+const TransactionLoanDisbursement = "loan_disbursement"
+
+// loanTransitions lists the statuses each status of a loan may move to.
+// ACTIVE means disbursed; REJECTED, CLOSED and WRITTEN_OFF are final.
+var loanTransitions = map[string][]string{
+	LoanStatusSubmitted:   {LoanStatusUnderReview, LoanStatusRejected},
+	LoanStatusUnderReview: {LoanStatusApproved, LoanStatusRejected},
+	LoanStatusApproved:    {LoanStatusActive, LoanStatusRejected},
+	LoanStatusActive:      {LoanStatusClosed, LoanStatusWrittenOff},
+}
+
+var loanRejectionReasons = []string{
+	"not_eligible", "insufficient_income", "incomplete_documents", "credit_risk", "customer_withdrew",
+}
+
+// LoanApplication is a request for a loan under one of the bank's products,
+// to be paid into DisbursementAccountID once approved.
+type LoanApplication struct {
+	CustomerID            uint
+	ProductID             uint
+	PrincipalAmount       money.Money
+	Terms                 LoanTerms
+	DisbursementAccountID uint
+	SubmittedBy           string
+}
+
+// checkHolder fails unless customerID holds accountID.
+func checkHolder(tx repository.Store, accountID, customerID uint) error {
+	_, err := tx.Accounts().FindHolder(accountID, customerID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("%w: account %d is not held by customer %d", ErrInvalidArgument, accountID, customerID)
+	}
+	return err
+}
+
+func checkLoanTransition(loan *models.Loan, status string) error {
+	if !slices.Contains(loanTransitions[loan.Status], status) {
+		return fmt.Errorf("%w: loan %d cannot move from %s to %s", ErrConflict, loan.ID, loan.Status, status)
+	}
+	return nil
+}
+
+// checkChecker enforces maker-checker: decisions on an application must be
+// made by someone other than whoever submitted it.
+func checkChecker(loan *models.Loan, change StatusChange) error {
+	if change.ChangedBy == "" {
+		return fmt.Errorf("%w: the reviewer must be identified", ErrInvalidArgument)
+	}
+	if change.ChangedBy == loan.SubmittedBy {
+		return fmt.Errorf("%w: loan %d must be reviewed by someone other than its submitter", ErrForbidden, loan.ID)
+	}
+	return nil
+}
+
+// Apply records a loan application. The product's terms and the borrower's
+// eligibility are checked and the terms copied onto the application; nothing
+// is paid out until it is approved and disbursed.
+func (ls *LoanService) Apply(application LoanApplication) (*models.Loan, error) {
+	if !application.PrincipalAmount.IsPositive() {
+		return nil, ErrInvalidArgument
+	}
+
+	var loan models.Loan
+	err := ls.store.Atomic(func(tx repository.Store) error {
+		customer, err := tx.Customers().Get(application.CustomerID)
+		if err != nil {
+			return lookupError("customer", err)
+		}
+		product, err := tx.LoanProducts().Get(application.ProductID)
+		if err != nil {
+			return lookupError("loan product", err)
+		}
+		if !product.Active {
+			return fmt.Errorf("%w: product %s is no longer offered", ErrInvalidArgument, product.Code)
+		}
+		terms, err := productTerms(product, application.PrincipalAmount, application.Terms)
+		if err != nil {
+			return err
+		}
+		fee, err := processingFee(product, application.PrincipalAmount)
+		if err != nil {
+			return err
+		}
+		if fee >= application.PrincipalAmount {
+			return fmt.Errorf("%w: the processing fee of %s would take the whole principal", ErrInvalidArgument, fee)
+		}
+		now := time.Now()
+		if err := checkEligibility(tx, product, customer, now); err != nil {
+			return err
+		}
+		rate, err := productRate(tx, product, terms, now)
+		if err != nil {
+			return err
+		}
+		account, err := tx.Accounts().Get(application.DisbursementAccountID)
+		if err != nil {
+			return lookupError("account", err)
+		}
+		if err := checkHolder(tx, account.ID, customer.ID); err != nil {
+			return err
+		}
+		if err := canCredit(account); err != nil {
+			return err
+		}
+
+		installments, err := amortize(application.PrincipalAmount, rate, terms, now)
+		if err != nil {
+			return err
+		}
+		var totalPayableAmount money.Money
+		for _, installment := range installments {
+			totalPayableAmount += installment.Amount
+		}
+		loan = models.Loan{
+			CustomerID:            customer.ID,
+			ProductID:             &product.ID,
+			LoanType:              product.LoanType,
+			PrincipalAmount:       application.PrincipalAmount,
+			InterestRate:          rate,
+			RateType:              product.RateType,
+			BenchmarkID:           product.BenchmarkID,
+			Spread:                product.Spread,
+			ResetMonths:           product.ResetMonths,
+			TenureMonths:          terms.TenureMonths,
+			RepaymentFrequency:    terms.RepaymentFrequency,
+			DayCount:              product.DayCount,
+			CompoundingPerYear:    product.CompoundingPerYear,
+			InstallmentAmount:     installments[0].Amount,
+			ProcessingFee:         fee,
+			TotalPayableAmount:    totalPayableAmount,
+			StartDate:             now,
+			Status:                LoanStatusSubmitted,
+			DelinquencyBucket:     BucketCurrent,
+			ScheduleVersion:       1,
+			SubmittedBy:           application.SubmittedBy,
+			DisbursementAccountID: &account.ID,
+		}
+		if err := tx.Loans().Create(&loan); err != nil {
+			return err
+		}
+		return recordLoanStatus(tx, &loan, "", StatusChange{ChangedBy: application.SubmittedBy})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &loan, nil
+}
+
+// Review takes a submitted application up for review.
+func (ls *LoanService) Review(loanID uint, change StatusChange) (*models.Loan, error) {
+	return ls.decide(loanID, LoanStatusUnderReview, change)
+}
+
+// Approve approves an application under review, checking the borrower's
+// eligibility again since it may have changed since submission.
+func (ls *LoanService) Approve(loanID uint, change StatusChange) (*models.Loan, error) {
+	return ls.decide(loanID, LoanStatusApproved, change)
+}
+
+// Reject turns an application down with one of loanRejectionReasons.
+func (ls *LoanService) Reject(loanID uint, change StatusChange) (*models.Loan, error) {
+	if !slices.Contains(loanRejectionReasons, change.Reason) {
+		return nil, fmt.Errorf("%w: reason must be one of %s", ErrInvalidArgument, strings.Join(loanRejectionReasons, ", "))
+	}
+	return ls.decide(loanID, LoanStatusRejected, change)
+}
+
+func (ls *LoanService) decide(loanID uint, status string, change StatusChange) (*models.Loan, error) {
+	var loan *models.Loan
+	err := ls.store.Atomic(func(tx repository.Store) error {
+		var err error
+		loan, err = tx.Loans().GetForUpdate(loanID)
+		if err != nil {
+			return lookupError("loan", err)
+		}
+		if err := checkLoanTransition(loan, status); err != nil {
+			return err
+		}
+		if err := checkChecker(loan, change); err != nil {
+			return err
+		}
+		if status == LoanStatusApproved {
+			if err := ls.recheckEligibility(tx, loan); err != nil {
+				return err
+			}
+			loan.ApprovedBy = change.ChangedBy
+		}
+		from := loan.Status
+		loan.Status = status
+		if err := tx.Loans().Update(loan); err != nil {
+			return err
+		}
+		return recordLoanStatus(tx, loan, from, change)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return loan, nil
+}
+
+func (ls *LoanService) recheckEligibility(tx repository.Store, loan *models.Loan) error {
+	if loan.ProductID == nil {
+		return nil
+	}
+	product, err := tx.LoanProducts().Get(*loan.ProductID)
+	if err != nil {
+		return lookupError("loan product", err)
+	}
+	customer, err := tx.Customers().Get(loan.CustomerID)
+	if err != nil {
+		return lookupError("customer", err)
+	}
+	return checkEligibility(tx, product, customer, time.Now())
+}
+
+// Disburse pays an approved loan into its disbursement account and starts
+// it. The schedule runs from the day of disbursement, and a floating rate is
+// priced afresh off the benchmark that day. The account credit,
+// its transaction, the ledger entry and the loan's activation commit
+// together.
+func (ls *LoanService) Disburse(loanID uint, change StatusChange) (*models.Loan, error) {
+	var loan *models.Loan
+	err := ls.store.Atomic(func(tx repository.Store) error {
+		var err error
+		loan, err = tx.Loans().GetForUpdate(loanID)
+		if err != nil {
+			return lookupError("loan", err)
+		}
+		if err := checkLoanTransition(loan, LoanStatusActive); err != nil {
+			return err
+		}
+		if loan.DisbursementAccountID == nil {
+			return fmt.Errorf("%w: loan %d has no disbursement account", ErrConflict, loan.ID)
+		}
+		account, err := tx.Accounts().GetForUpdate(*loan.DisbursementAccountID)
+		if err != nil {
+			return lookupError("account", err)
+		}
+		if err := checkHolder(tx, account.ID, loan.CustomerID); err != nil {
+			return err
+		}
+		if err := canCredit(account); err != nil {
+			return err
+		}
+		amount := loan.PrincipalAmount - loan.ProcessingFee
+		if !amount.IsPositive() {
+			return fmt.Errorf("%w: loan %d's processing fee of %s leaves nothing to disburse", ErrConflict, loan.ID, loan.ProcessingFee)
+		}
+
+		now := time.Now()
+		pricing := models.LoanRateChange{
+			LoanID:        loan.ID,
+			EffectiveDate: BusinessDate(now),
+			Reason:        RateChangeDisbursement,
+			Spread:        loan.Spread,
+			PreviousRate:  loan.InterestRate,
+		}
+		if loan.RateType == RateFloating {
+			rate, benchmark, err := floatingRate(tx, *loan.BenchmarkID, loan.Spread, now)
+			if err != nil {
+				return err
+			}
+			loan.InterestRate = rate
+			pricing.BenchmarkRate = &benchmark
+		}
+		terms := LoanTerms{TenureMonths: loan.TenureMonths, RepaymentFrequency: loan.RepaymentFrequency}
+		installments, err := amortize(loan.PrincipalAmount, loan.InterestRate, terms, now)
+		if err != nil {
+			return err
+		}
+		var totalPayableAmount money.Money
+		for i := range installments {
+			installments[i].LoanID = loan.ID
+			if err := tx.Loans().CreateInstallment(&installments[i]); err != nil {
+				return err
+			}
+			totalPayableAmount += installments[i].Amount
+		}
+		endDate := installments[len(installments)-1].DueDate
+		loan.InstallmentAmount = installments[0].Amount
+		loan.TotalPayableAmount = totalPayableAmount
+		loan.PendingAmount = totalPayableAmount
+		loan.StartDate = now
+		loan.EndDate = &endDate
+		loan.DisbursedAt = &now
+		loan.RepaymentAccountID = &account.ID
+		if loan.RateType == RateFloating {
+			loan.NextResetDate = nextReset(loan, BusinessDate(now), endDate)
+		}
+		pricing.InterestRate = loan.InterestRate
+		pricing.InstallmentAmount = loan.InstallmentAmount
+		if err := tx.Loans().AddRateChange(&pricing); err != nil {
+			return err
+		}
+
+		account.Balance += amount
+		if err := tx.Accounts().Update(account); err != nil {
+			return err
+		}
+		entry, err := ls.ledger.RecordLoanDisbursement(tx, loan, account.ID)
+		if err != nil {
+			return err
+		}
+		credit := models.Transaction{
+			AccountID:      account.ID,
+			Type:           TransactionLoanDisbursement,
+			Amount:         amount,
+			JournalEntryID: &entry.ID,
+		}
+		if err := tx.Transactions().Create(&credit); err != nil {
+			return err
+		}
+		loan.DisbursementTransactionID = &credit.ID
+
+		from := loan.Status
+		loan.Status = LoanStatusActive
+		if err := tx.Loans().Update(loan); err != nil {
+			return err
+		}
+		return recordLoanStatus(tx, loan, from, change)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return loan, nil
+}
+
+func recordLoanStatus(tx repository.Store, loan *models.Loan, from string, change StatusChange) error {
+	return tx.Loans().AddStatusChange(&models.LoanStatusChange{
+		LoanID:     loan.ID,
+		FromStatus: from,
+		ToStatus:   loan.Status,
+		Reason:     change.Reason,
+		Note:       change.Note,
+		ChangedBy:  change.ChangedBy,
+	})
+}
+
+func (ls *LoanService) GetStatusHistory(loanID uint) ([]models.LoanStatusChange, error) {
+	if _, err := ls.store.Loans().Get(loanID); err != nil {
+		return nil, lookupError("loan", err)
+	}
+	return ls.store.Loans().StatusHistory(loanID)
+}
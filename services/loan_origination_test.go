@@ -0,0 +1,127 @@
+package services
+
+import (
+	"banking-system/money"
+	"errors"
+	"testing"
+)
+
+func TestLoanFromApplicationToClosure(t *testing.T) {
+	f := newFixture(t)
+	account := f.openAccount(f.customer.ID, 0)
+	principal := money.MustParse("1200")
+	loan := f.disburseLoan(account.ID, principal, 6)
+
+	if loan.Status != LoanStatusActive || loan.PendingAmount != loan.TotalPayableAmount {
+		t.Fatalf("disbursed loan is %s owing %s, want ACTIVE owing %s", loan.Status, loan.PendingAmount, loan.TotalPayableAmount)
+	}
+	if got := f.account(account.ID).Balance; got != principal {
+		t.Fatalf("account balance after disbursement = %s, want %s", got, principal)
+	}
+	loans := NewLoanService(f.store)
+	schedule, err := loans.GetLoanSchedule(loan.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(schedule) != 6 {
+		t.Fatalf("schedule has %d installments, want 6", len(schedule))
+	}
+	f.assertReconciled()
+
+	if _, err := NewAccountService(f.store).Deposit(account.ID, loan.TotalPayableAmount-principal); err != nil {
+		t.Fatal(err)
+	}
+	for _, installment := range schedule {
+		if _, err := loans.RepayLoan(loan.ID, account.ID, installment.Amount); err != nil {
+			t.Fatalf("repaying installment %d: %v", installment.Number, err)
+		}
+		f.assertReconciled()
+	}
+
+	closed, err := loans.GetLoanByID(loan.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if closed.Status != LoanStatusClosed || !closed.PendingAmount.IsZero() {
+		t.Fatalf("repaid loan is %s owing %s, want CLOSED owing nothing", closed.Status, closed.PendingAmount)
+	}
+	if got := f.account(account.ID).Balance; !got.IsZero() {
+		t.Errorf("account balance after repayment = %s, want 0.00", got)
+	}
+	interest := loan.TotalPayableAmount - principal
+	if got := f.ledgerBalance(LedgerInterestIncome); got != interest.Neg() {
+		t.Errorf("interest income = %s, want %s", got.Neg(), interest)
+	}
+	if _, err := loans.RepayLoan(loan.ID, account.ID, money.MustParse("1")); !errors.Is(err, ErrLoanClosed) {
+		t.Errorf("repaying a closed loan: err = %v, want ErrLoanClosed", err)
+	}
+}
+
+func TestLoanDecisionsNeedAChecker(t *testing.T) {
+	f := newFixture(t)
+	account := f.openAccount(f.customer.ID, 0)
+	loans := NewLoanService(f.store)
+	loan, err := loans.Apply(LoanApplication{
+		CustomerID:            f.customer.ID,
+		ProductID:             f.product.ID,
+		PrincipalAmount:       money.MustParse("500"),
+		Terms:                 LoanTerms{TenureMonths: 6},
+		DisbursementAccountID: account.ID,
+		SubmittedBy:           "teller",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loans.Review(loan.ID, StatusChange{ChangedBy: "teller"}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("submitter reviewing: err = %v, want ErrForbidden", err)
+	}
+	if _, err := loans.Disburse(loan.ID, StatusChange{ChangedBy: "manager"}); !errors.Is(err, ErrConflict) {
+		t.Errorf("disbursing unapproved loan: err = %v, want ErrConflict", err)
+	}
+	if got := f.account(account.ID).Balance; !got.IsZero() {
+		t.Errorf("account balance = %s, want nothing paid out", got)
+	}
+}
+
+func TestProcessingFeesMustLeaveSomethingToDisburse(t *testing.T) {
+	f := newFixture(t)
+	account := f.openAccount(f.customer.ID, money.MustParse("100"))
+	products := NewLoanProductService(f.store)
+	costly := f.product
+	costly.ID, costly.Code = 0, "COSTLY"
+	costly.ProcessingFee, costly.ProcessingFeeRate = money.MustParse("90"), 50
+	f.must(products.CreateProduct(&costly))
+
+	loans := NewLoanService(f.store)
+	application := LoanApplication{
+		CustomerID:            f.customer.ID,
+		ProductID:             costly.ID,
+		PrincipalAmount:       money.MustParse("100"),
+		Terms:                 LoanTerms{TenureMonths: 6},
+		DisbursementAccountID: account.ID,
+		SubmittedBy:           "teller",
+	}
+	if _, err := loans.Apply(application); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatalf("applying for a loan the fee would swallow: err = %v, want ErrInvalidArgument", err)
+	}
+
+	// A loan whose fee has come to exceed its principal some other way must
+	// still not be disbursed.
+	application.ProductID = f.product.ID
+	loan, err := loans.Apply(application)
+	f.must(err)
+	checker := StatusChange{ChangedBy: "manager"}
+	_, err = loans.Review(loan.ID, checker)
+	f.must(err)
+	loan, err = loans.Approve(loan.ID, checker)
+	f.must(err)
+	loan.ProcessingFee = money.MustParse("140")
+	f.must(f.store.Loans().Update(loan))
+	if _, err := loans.Disburse(loan.ID, checker); !errors.Is(err, ErrConflict) {
+		t.Fatalf("disbursing a loan the fee swallows: err = %v, want ErrConflict", err)
+	}
+	if got := f.account(account.ID).Balance; got != money.MustParse("100") {
+		t.Errorf("account balance = %s, want it left at 100.00", got)
+	}
+	f.assertReconciled()
+}
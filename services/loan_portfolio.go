@@ -0,0 +1,90 @@
+package services
+
+import (
+	"banking-system/money"
+	"banking-system/repository"
+)
+
+// PortfolioQuery scopes a portfolio report to a bank, branch or loan type.
+type PortfolioQuery struct {
+	BranchID *uint
+	BankID   *uint
+	LoanType string
+	Scope    ListScope
+}
+
+// PortfolioLine counts loans and adds up an amount over them.
+type PortfolioLine struct {
+	Loans  int         `json:"loans"`
+	Amount money.Money `json:"amount"`
+}
+
+// LoanPortfolio summarises a loan book. Active, Buckets and Restructured add
+// up what active loans still owe, Buckets by delinquency bucket. WrittenOff
+// adds up what was written off and WriteOffLoss the part of it charged to
+// loan losses. GrossRecovered is everything recovered on written-off loans
+// since and NetRecovered what is left of it after RecoveryCosts; NetLoss is
+// the loss that remains.
+type LoanPortfolio struct {
+	Active         PortfolioLine            `json:"active"`
+	Buckets        map[string]PortfolioLine `json:"buckets"`
+	Restructured   PortfolioLine            `json:"restructured"`
+	Closed         int                      `json:"closed"`
+	WrittenOff     PortfolioLine            `json:"written_off"`
+	WriteOffLoss   money.Money              `json:"write_off_loss"`
+	GrossRecovered money.Money              `json:"gross_recovered"`
+	RecoveryCosts  money.Money              `json:"recovery_costs"`
+	NetRecovered   money.Money              `json:"net_recovered"`
+	NetLoss        money.Money              `json:"net_loss"`
+}
+
+func (ls *LoanService) Portfolio(query PortfolioQuery) (*LoanPortfolio, error) {
+	filter := repository.LoanFilter{LoanType: query.LoanType}
+	var err error
+	if filter.CustomerID, err = narrow(nil, query.Scope.CustomerID); err != nil {
+		return nil, err
+	}
+	if filter.BranchID, err = narrow(query.BranchID, query.Scope.BranchID); err != nil {
+		return nil, err
+	}
+	if filter.BankID, err = narrow(query.BankID, query.Scope.BankID); err != nil {
+		return nil, err
+	}
+	loans, err := ls.store.Loans().Find(filter)
+	if err != nil {
+		return nil, err
+	}
+	writeOffs, err := ls.store.Loans().FindWriteOffs(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	portfolio := LoanPortfolio{Buckets: map[string]PortfolioLine{}}
+	for _, loan := range loans {
+		switch loan.Status {
+		case LoanStatusActive:
+			portfolio.Active.Loans++
+			portfolio.Active.Amount += loan.PendingAmount
+			bucket := portfolio.Buckets[loan.DelinquencyBucket]
+			bucket.Loans++
+			bucket.Amount += loan.PendingAmount
+			portfolio.Buckets[loan.DelinquencyBucket] = bucket
+			if loan.Restructured {
+				portfolio.Restructured.Loans++
+				portfolio.Restructured.Amount += loan.PendingAmount
+			}
+		case LoanStatusClosed:
+			portfolio.Closed++
+		}
+	}
+	for _, writeOff := range writeOffs {
+		portfolio.WrittenOff.Loans++
+		portfolio.WrittenOff.Amount += writeOff.Amount
+		portfolio.WriteOffLoss += writeOff.Loss
+		portfolio.GrossRecovered += writeOff.GrossRecovered
+		portfolio.RecoveryCosts += writeOff.RecoveryCosts
+	}
+	portfolio.NetRecovered = portfolio.GrossRecovered - portfolio.RecoveryCosts
+	portfolio.NetLoss = portfolio.WriteOffLoss - portfolio.NetRecovered
+	return &portfolio, nil
+}
@@ -0,0 +1,359 @@
+package services
+
+import (
+	"banking-system/models"
+	"banking-system/money"
+	"banking-system/repository"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Ways a part-prepayment can shorten a loan.
+const (
+	PrepayReduceTenure      = "REDUCE_TENURE"
+	PrepayReduceInstallment = "REDUCE_INSTALLMENT"
+)
+
+// Prepayment is the outcome of paying part of a loan's principal early.
+type Prepayment struct {
+	Loan              *models.Loan        `json:"loan"`
+	Payment           *models.LoanPayment `json:"payment"`
+	DuesPaid          money.Money         `json:"dues_paid"`
+	PrincipalPrepaid  money.Money         `json:"principal_prepaid"`
+	PrepaymentPenalty money.Money         `json:"prepayment_penalty"`
+	InterestSaved     money.Money         `json:"interest_saved"`
+}
+
+// ForeclosureQuote is what it takes to pay a loan off on AsOf: its charges,
+// the installments due by then, the principal still outstanding with
+// interest accrued on it since the last due date, and the prepayment
+// penalty. InterestSaved is the scheduled interest that would no longer be
+// charged.
+type ForeclosureQuote struct {
+	LoanID               uint        `json:"loan_id"`
+	AsOf                 time.Time   `json:"as_of"`
+	ChargesDue           money.Money `json:"charges_due"`
+	OverdueAmount        money.Money `json:"overdue_amount"`
+	OutstandingPrincipal money.Money `json:"outstanding_principal"`
+	AccruedInterest      money.Money `json:"accrued_interest"`
+	PrepaymentPenalty    money.Money `json:"prepayment_penalty"`
+	InterestSaved        money.Money `json:"interest_saved"`
+	Total                money.Money `json:"total"`
+}
+
+// prepaymentPenaltyRate is the loan's product's penalty on principal paid
+// early. Loans from before the catalog pay none.
+func prepaymentPenaltyRate(tx repository.Store, loan *models.Loan) (float64, error) {
+	if loan.ProductID == nil {
+		return 0, nil
+	}
+	product, err := tx.LoanProducts().Get(*loan.ProductID)
+	if err != nil {
+		return 0, lookupError("loan product", err)
+	}
+	return product.PrepaymentPenaltyRate, nil
+}
+
+// scheduledInstallments loads the schedule of an active loan. Loans from
+// before schedules existed cannot be paid off early.
+func scheduledInstallments(tx repository.Store, loan *models.Loan) ([]models.LoanInstallment, error) {
+	if err := checkRepayable(loan, 0); err != nil {
+		return nil, err
+	}
+	installments, err := tx.Loans().ListInstallments(loan.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(installments) == 0 {
+		return nil, fmt.Errorf("%w: loan %d has no schedule", ErrConflict, loan.ID)
+	}
+	return installments, nil
+}
+
+// debitable locks accountID and checks the borrower can pay amount from it.
+func debitable(tx repository.Store, loan *models.Loan, accountID uint, amount money.Money) (*models.SavingsAccount, error) {
+	account, err := tx.Accounts().GetForUpdate(accountID)
+	if err != nil {
+		return nil, lookupError("account", err)
+	}
+	if err := checkHolder(tx, account.ID, loan.CustomerID); err != nil {
+		return nil, err
+	}
+	if err := canDebit(account); err != nil {
+		return nil, err
+	}
+	if account.Balance < amount {
+		return nil, ErrInsufficientFunds
+	}
+	return account, nil
+}
+
+// chargePenalty adds the prepayment penalty to the loan's charges.
+func (ls *LoanService) chargePenalty(tx repository.Store, loan *models.Loan, penalty money.Money) error {
+	if !penalty.IsPositive() {
+		return nil
+	}
+	if _, err := ls.ledger.RecordLoanCharge(tx, loan.ID, penalty, fmt.Sprintf("Prepayment penalty on loan %d", loan.ID)); err != nil {
+		return err
+	}
+	loan.ChargesDue += penalty
+	loan.PendingAmount += penalty
+	return nil
+}
+
+// cutInterest takes interest the loan will no longer be charged off what it
+// owes.
+func (ls *LoanService) cutInterest(tx repository.Store, loan *models.Loan, amount money.Money) error {
+	if !amount.IsPositive() {
+		return nil
+	}
+	if _, err := ls.ledger.RecordLoanInterestCut(tx, loan.ID, amount); err != nil {
+		return err
+	}
+	loan.TotalPayableAmount -= amount
+	loan.PendingAmount -= amount
+	return nil
+}
+
+// Prepay pays principal off the loan ahead of schedule from a savings account
+// the borrower holds. Charges, installments already due and any part-paid
+// installment are settled first out of the same debit, along with the
+// product's prepayment penalty on principal. The rest of the schedule is then
+// rebuilt on the smaller principal, either keeping the installment and
+// finishing sooner (REDUCE_TENURE) or keeping the end date and lowering the
+// installment (REDUCE_INSTALLMENT). Installments that remain keep their due
+// dates.
+func (ls *LoanService) Prepay(loanID, accountID uint, principal money.Money, mode string) (*Prepayment, error) {
+	if !principal.IsPositive() {
+		return nil, ErrInvalidArgument
+	}
+	mode = strings.ToUpper(mode)
+	if mode != PrepayReduceTenure && mode != PrepayReduceInstallment {
+		return nil, fmt.Errorf("%w: mode must be %s or %s", ErrInvalidArgument, PrepayReduceTenure, PrepayReduceInstallment)
+	}
+
+	var result Prepayment
+	err := ls.store.Atomic(func(tx repository.Store) error {
+		loan, err := tx.Loans().GetForUpdate(loanID)
+		if err != nil {
+			return lookupError("loan", err)
+		}
+		installments, err := scheduledInstallments(tx, loan)
+		if err != nil {
+			return err
+		}
+		rate, err := prepaymentPenaltyRate(tx, loan)
+		if err != nil {
+			return err
+		}
+
+		today := BusinessDate(time.Now())
+		dues := loan.ChargesDue
+		var future []models.LoanInstallment
+		var outstanding, scheduledInterest money.Money
+		for _, installment := range installments {
+			switch {
+			case installment.Status == InstallmentPaid:
+			case installment.Status == InstallmentPartial || !installment.DueDate.After(today):
+				dues += installment.Amount - installment.PrincipalPaid - installment.InterestPaid
+			default:
+				future = append(future, installment)
+				outstanding += installment.PrincipalDue
+				scheduledInterest += installment.InterestDue
+			}
+		}
+		if len(future) == 0 || principal >= outstanding {
+			return fmt.Errorf("%w: at most %s of principal can be prepaid; foreclose the loan to pay it all", ErrInvalidArgument, money.Max(outstanding-1, 0))
+		}
+		penalty, err := principal.MulRate(rate)
+		if err != nil {
+			return err
+		}
+		amount := dues + penalty + principal
+		account, err := debitable(tx, loan, accountID, amount)
+		if err != nil {
+			return err
+		}
+
+		n := len(future)
+		if mode == PrepayReduceTenure {
+			r := periodRate(loan.InterestRate, loan.RepaymentFrequency)
+			for n = 1; n < len(future); n++ {
+				emi, err := installmentAmount(outstanding-principal, r, n)
+				if err != nil {
+					return err
+				}
+				if emi <= loan.InstallmentAmount {
+					break
+				}
+			}
+		}
+		rebuilt, err := amortizeFrom(outstanding-principal, loan.InterestRate, loan.RepaymentFrequency, loan.StartDate, future[0].Number, n)
+		if err != nil {
+			return err
+		}
+		rebuiltInterest, err := reschedule(tx, loan, future, rebuilt)
+		if err != nil {
+			return err
+		}
+		if loan.NextResetDate != nil && !loan.NextResetDate.Before(*loan.EndDate) {
+			loan.NextResetDate = nil
+		}
+
+		if err := ls.chargePenalty(tx, loan, penalty); err != nil {
+			return err
+		}
+		if err := ls.cutInterest(tx, loan, scheduledInterest-rebuiltInterest); err != nil {
+			return err
+		}
+		allocation, err := allocateRepayment(tx, loan, dues+penalty)
+		if err != nil {
+			return err
+		}
+		allocation.principal += principal
+		payment, err := ls.pay(tx, loan, account, amount, allocation, "repaid")
+		if err != nil {
+			return err
+		}
+		result = Prepayment{
+			Loan:              loan,
+			Payment:           payment,
+			DuesPaid:          dues,
+			PrincipalPrepaid:  principal,
+			PrepaymentPenalty: penalty,
+			InterestSaved:     scheduledInterest - rebuiltInterest,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// foreclosure quotes paying the loan off on asOf and returns the unpaid
+// installments still to fall due, with their interest cut to what will have
+// accrued by then.
+func foreclosure(loan *models.Loan, installments []models.LoanInstallment, penaltyRate float64, asOf time.Time) (*ForeclosureQuote, []models.LoanInstallment, error) {
+	quote := ForeclosureQuote{LoanID: loan.ID, AsOf: asOf, ChargesDue: loan.ChargesDue}
+	since := BusinessDate(loan.StartDate)
+	var future []models.LoanInstallment
+	var unearned money.Money
+	for _, installment := range installments {
+		if !installment.DueDate.After(asOf) {
+			since = installment.DueDate
+			quote.OverdueAmount += installment.Amount - installment.PrincipalPaid - installment.InterestPaid
+			continue
+		}
+		if installment.Status == InstallmentPaid {
+			continue
+		}
+		future = append(future, installment)
+		quote.OutstandingPrincipal += installment.PrincipalDue - installment.PrincipalPaid
+		unearned += installment.InterestDue
+	}
+
+	accrued, err := loanInterest(loan, quote.OutstandingPrincipal, since, asOf)
+	if err != nil {
+		return nil, nil, err
+	}
+	owedInFull, err := money.FromRat(accrued)
+	if err != nil {
+		return nil, nil, err
+	}
+	owed := money.Min(owedInFull, unearned)
+	for i := range future {
+		due := future[i].InterestPaid
+		if i == 0 {
+			due = money.Max(due, owed)
+		}
+		quote.InterestSaved += future[i].InterestDue - due
+		quote.AccruedInterest += due - future[i].InterestPaid
+		future[i].InterestDue = due
+		future[i].Amount = future[i].PrincipalDue + due
+	}
+
+	if quote.PrepaymentPenalty, err = quote.OutstandingPrincipal.MulRate(penaltyRate); err != nil {
+		return nil, nil, err
+	}
+	quote.Total = quote.ChargesDue + quote.OverdueAmount + quote.OutstandingPrincipal + quote.AccruedInterest + quote.PrepaymentPenalty
+	return &quote, future, nil
+}
+
+// QuoteForeclosure works out what paying the loan off on asOf would cost. The
+// quote holds as long as nothing else is paid or charged before then.
+func (ls *LoanService) QuoteForeclosure(loanID uint, asOf time.Time) (*ForeclosureQuote, error) {
+	asOf = BusinessDate(asOf)
+	if asOf.Before(BusinessDate(time.Now())) {
+		return nil, fmt.Errorf("%w: as_of must not be in the past", ErrInvalidArgument)
+	}
+	loan, err := ls.store.Loans().Get(loanID)
+	if err != nil {
+		return nil, lookupError("loan", err)
+	}
+	installments, err := scheduledInstallments(ls.store, loan)
+	if err != nil {
+		return nil, err
+	}
+	rate, err := prepaymentPenaltyRate(ls.store, loan)
+	if err != nil {
+		return nil, err
+	}
+	quote, _, err := foreclosure(loan, installments, rate, asOf)
+	return quote, err
+}
+
+// Foreclose pays the loan off today from a savings account the borrower
+// holds, for the amount QuoteForeclosure gives, and closes it.
+func (ls *LoanService) Foreclose(loanID, accountID uint) (*ForeclosureQuote, error) {
+	var quote *ForeclosureQuote
+	err := ls.store.Atomic(func(tx repository.Store) error {
+		loan, err := tx.Loans().GetForUpdate(loanID)
+		if err != nil {
+			return lookupError("loan", err)
+		}
+		installments, err := scheduledInstallments(tx, loan)
+		if err != nil {
+			return err
+		}
+		rate, err := prepaymentPenaltyRate(tx, loan)
+		if err != nil {
+			return err
+		}
+		var future []models.LoanInstallment
+		quote, future, err = foreclosure(loan, installments, rate, BusinessDate(time.Now()))
+		if err != nil {
+			return err
+		}
+		account, err := debitable(tx, loan, accountID, quote.Total)
+		if err != nil {
+			return err
+		}
+
+		for i := range future {
+			if err := tx.Loans().UpdateInstallment(&future[i]); err != nil {
+				return err
+			}
+		}
+		if err := ls.chargePenalty(tx, loan, quote.PrepaymentPenalty); err != nil {
+			return err
+		}
+		if err := ls.cutInterest(tx, loan, quote.InterestSaved); err != nil {
+			return err
+		}
+		if loan.PendingAmount != quote.Total {
+			return fmt.Errorf("%w: loan %d: foreclosure quote %s does not match the %s pending", ErrConflict, loan.ID, quote.Total, loan.PendingAmount)
+		}
+		allocation, err := allocateRepayment(tx, loan, quote.Total)
+		if err != nil {
+			return err
+		}
+		_, err = ls.pay(tx, loan, account, quote.Total, allocation, "foreclosed")
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return quote, nil
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"banking-system/interest"
+	"banking-system/models"
+	"banking-system/money"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// usePenalty switches the fixture to a product charging rate percent on
+// principal paid early.
+func usePenalty(f *fixture, rate float64) {
+	f.t.Helper()
+	f.useProduct("PREPAY", func(product *models.LoanProduct) { product.PrepaymentPenaltyRate = rate })
+}
+
+// assertScheduleMatches fails the test unless the loan's pending amount is
+// what its unpaid installments and charges add up to.
+func assertScheduleMatches(f *fixture, loanID uint) {
+	f.t.Helper()
+	loan, err := f.store.Loans().Get(loanID)
+	f.must(err)
+	installments, err := f.store.Loans().ListInstallments(loanID)
+	f.must(err)
+	unpaid := loan.ChargesDue
+	for _, installment := range installments {
+		unpaid += installment.Amount - installment.PrincipalPaid - installment.InterestPaid
+	}
+	if unpaid != loan.PendingAmount {
+		f.t.Fatalf("loan %d has %s pending but its schedule and charges add up to %s", loanID, loan.PendingAmount, unpaid)
+	}
+}
+
+// 1,200 at 12% over 12 months pays 106.62 a month. Prepaying 600 leaves 600
+// to repay: at the same installment that takes six months of 103.53, and over
+// the same twelve months the installment falls to 53.31.
+func TestPrepayReamortizes(t *testing.T) {
+	tests := []struct {
+		mode        string
+		tenure      int
+		installment string
+	}{
+		{PrepayReduceTenure, 6, "103.53"},
+		{PrepayReduceInstallment, 12, "53.31"},
+	}
+	saved := map[string]money.Money{}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			f := newFixture(t)
+			usePenalty(f, 2)
+			account := f.openAccount(f.customer.ID, money.MustParse("1000"))
+			loan := f.disburseLoan(account.ID, money.MustParse("1200"), 12)
+			before := f.account(account.ID).Balance
+
+			result, err := NewLoanService(f.store).Prepay(loan.ID, account.ID, money.MustParse("600"), tt.mode)
+			f.must(err)
+
+			if result.PrepaymentPenalty != money.MustParse("12") {
+				t.Errorf("penalty = %s, want 2%% of 600, 12.00", result.PrepaymentPenalty)
+			}
+			if result.DuesPaid != 0 || result.PrincipalPrepaid != money.MustParse("600") {
+				t.Errorf("paid %s of dues and %s of principal, want 0.00 and 600.00", result.DuesPaid, result.PrincipalPrepaid)
+			}
+			if paid := before - f.account(account.ID).Balance; paid != money.MustParse("612") {
+				t.Errorf("account paid %s, want the 600.00 prepaid and the 12.00 penalty", paid)
+			}
+			if result.Loan.TenureMonths != tt.tenure || result.Loan.InstallmentAmount != money.MustParse(tt.installment) {
+				t.Errorf("loan now runs %d months at %s, want %d months at %s", result.Loan.TenureMonths, result.Loan.InstallmentAmount, tt.tenure, tt.installment)
+			}
+			installments, err := f.store.Loans().ListInstallments(loan.ID)
+			f.must(err)
+			if len(installments) != tt.tenure {
+				t.Fatalf("loan has %d installments, want %d", len(installments), tt.tenure)
+			}
+			if last := installments[len(installments)-1]; !result.Loan.EndDate.Equal(last.DueDate) {
+				t.Errorf("loan ends %s, want its last due date %s", result.Loan.EndDate, last.DueDate)
+			}
+			if !installments[0].DueDate.Equal(addMonths(BusinessDate(loan.StartDate), 1)) {
+				t.Errorf("first installment moved to %s", installments[0].DueDate)
+			}
+			if !result.InterestSaved.IsPositive() || result.Loan.TotalPayableAmount != loan.TotalPayableAmount-result.InterestSaved {
+				t.Errorf("saved %s of interest, payable went from %s to %s", result.InterestSaved, loan.TotalPayableAmount, result.Loan.TotalPayableAmount)
+			}
+			saved[tt.mode] = result.InterestSaved
+
+			assertScheduleMatches(f, loan.ID)
+			f.assertReconciled()
+		})
+	}
+	if saved[PrepayReduceTenure] <= saved[PrepayReduceInstallment] {
+		t.Errorf("shortening the loan saved %s of interest, lowering the installment %s; want the shorter loan to save more",
+			saved[PrepayReduceTenure], saved[PrepayReduceInstallment])
+	}
+}
+
+func TestPrepayRejects(t *testing.T) {
+	f := newFixture(t)
+	account := f.openAccount(f.customer.ID, money.MustParse("5000"))
+	loan := f.disburseLoan(account.ID, money.MustParse("1200"), 12)
+	loans := NewLoanService(f.store)
+
+	tests := []struct {
+		name      string
+		principal string
+		mode      string
+		want      error
+	}{
+		{"nothing", "0", PrepayReduceTenure, ErrInvalidArgument},
+		{"an unknown mode", "100", "SKIP_A_MONTH", ErrInvalidArgument},
+		{"the whole principal", "1200", PrepayReduceTenure, ErrInvalidArgument},
+	}
+	for _, tt := range tests {
+		if _, err := loans.Prepay(loan.ID, account.ID, money.MustParse(tt.principal), tt.mode); !errors.Is(err, tt.want) {
+			t.Errorf("prepaying %s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	poor := f.openAccount(f.customer.ID, money.MustParse("50"))
+	if _, err := loans.Prepay(loan.ID, poor.ID, money.MustParse("100"), PrepayReduceTenure); !errors.Is(err, ErrInsufficientFunds) {
+		t.Errorf("prepaying from an account that cannot cover it: err = %v, want %v", err, ErrInsufficientFunds)
+	}
+	assertScheduleMatches(f, loan.ID)
+	f.assertReconciled()
+}
+
+func TestForecloseDebitsTheQuote(t *testing.T) {
+	f := newFixture(t)
+	usePenalty(f, 2)
+	account := f.openAccount(f.customer.ID, money.MustParse("2000"))
+	loan := f.disburseLoan(account.ID, money.MustParse("1200"), 12)
+
+	// Ten days into the loan, interest has accrued on the whole principal.
+	today := BusinessDate(time.Now())
+	started, err := f.store.Loans().Get(loan.ID)
+	f.must(err)
+	started.StartDate = today.AddDate(0, 0, -10)
+	f.must(f.store.Loans().Update(started))
+
+	loans := NewLoanService(f.store)
+	quote, err := loans.QuoteForeclosure(loan.ID, today)
+	f.must(err)
+	accrued, err := money.FromRat(interest.Simple(money.MustParse("1200"), 12, interest.DayCount(loan.DayCount), started.StartDate, today))
+	f.must(err)
+	want := ForeclosureQuote{
+		LoanID:               loan.ID,
+		AsOf:                 today,
+		OutstandingPrincipal: money.MustParse("1200"),
+		AccruedInterest:      accrued,
+		PrepaymentPenalty:    money.MustParse("24"),
+		InterestSaved:        loan.TotalPayableAmount - loan.PrincipalAmount - accrued,
+		Total:                money.MustParse("1224") + accrued,
+	}
+	if *quote != want {
+		t.Fatalf("quote = %+v, want %+v", *quote, want)
+	}
+
+	before := f.account(account.ID).Balance
+	paid, err := loans.Foreclose(loan.ID, account.ID)
+	f.must(err)
+	if *paid != *quote {
+		t.Errorf("foreclosed on %+v, quoted %+v", *paid, *quote)
+	}
+	if debited := before - f.account(account.ID).Balance; debited != quote.Total {
+		t.Errorf("account was debited %s, quote was %s", debited, quote.Total)
+	}
+	closed, err := f.store.Loans().Get(loan.ID)
+	f.must(err)
+	if closed.Status != LoanStatusClosed || !closed.PendingAmount.IsZero() || !closed.ChargesDue.IsZero() {
+		t.Errorf("loan is %s with %s pending and %s of charges, want it closed and settled", closed.Status, closed.PendingAmount, closed.ChargesDue)
+	}
+	if balance := f.ledgerBalance(fmt.Sprintf("LOAN-%d", loan.ID)); !balance.IsZero() {
+		t.Errorf("loan ledger account holds %s after foreclosure", balance)
+	}
+	f.assertReconciled()
+}
+
+func TestForecloseRejectsAStaleQuote(t *testing.T) {
+	f := newFixture(t)
+	account := f.openAccount(f.customer.ID, money.MustParse("2000"))
+	loan := f.disburseLoan(account.ID, money.MustParse("1200"), 12)
+
+	// The loan owes more than its schedule and charges account for, so any
+	// quote built from them is out of date.
+	drifted, err := f.store.Loans().Get(loan.ID)
+	f.must(err)
+	drifted.PendingAmount += money.MustParse("1")
+	f.must(f.store.Loans().Update(drifted))
+
+	before := f.account(account.ID).Balance
+	if _, err := NewLoanService(f.store).Foreclose(loan.ID, account.ID); !errors.Is(err, ErrConflict) {
+		t.Fatalf("foreclosing on a stale quote: err = %v, want %v", err, ErrConflict)
+	}
+	if after := f.account(account.ID).Balance; after != before {
+		t.Errorf("balance went from %s to %s on a refused foreclosure", before, after)
+	}
+	still, err := f.store.Loans().Get(loan.ID)
+	f.must(err)
+	if still.Status != LoanStatusActive || still.PendingAmount != drifted.PendingAmount {
+		t.Errorf("loan is %s with %s pending, want it left active with %s", still.Status, still.PendingAmount, drifted.PendingAmount)
+	}
+	installments, err := f.store.Loans().ListInstallments(loan.ID)
+	f.must(err)
+	for _, installment := range installments {
+		if installment.InterestDue.IsZero() {
+			t.Fatalf("installment %d lost its interest on a refused foreclosure", installment.Number)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package services
+
+import (
+	"banking-system/interest"
+	"banking-system/models"
+	"banking-system/money"
+	"banking-system/repository"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type LoanProductService struct {
+	store repository.Store
+}
+
+func NewLoanProductService(store repository.Store) *LoanProductService {
+	return &LoanProductService{store: store}
+}
+
+// CreateProduct adds product to its bank's catalog. New products are on
+// offer straight away.
+func (ps *LoanProductService) CreateProduct(product *models.LoanProduct) error {
+	if err := validateProduct(product); err != nil {
+		return err
+	}
+	if _, err := ps.store.Banks().Get(product.BankID); err != nil {
+		return lookupError("bank", err)
+	}
+	if err := checkBenchmark(ps.store, product); err != nil {
+		return err
+	}
+	product.Active = true
+	return storeError(ps.store.LoanProducts().Create(product))
+}
+
+func (ps *LoanProductService) GetProduct(id uint) (*models.LoanProduct, error) {
+	product, err := ps.store.LoanProducts().Get(id)
+	if err != nil {
+		return nil, lookupError("loan product", err)
+	}
+	return product, nil
+}
+
+// UpdateProduct replaces the product's terms with those of updates. Loans
+// already originated keep the terms they were given.
+func (ps *LoanProductService) UpdateProduct(id uint, updates models.LoanProduct) (*models.LoanProduct, error) {
+	product, err := ps.store.LoanProducts().Get(id)
+	if err != nil {
+		return nil, lookupError("loan product", err)
+	}
+	updates.ID = product.ID
+	updates.BankID = product.BankID
+	updates.CreatedAt = product.CreatedAt
+	if err := validateProduct(&updates); err != nil {
+		return nil, err
+	}
+	if err := checkBenchmark(ps.store, &updates); err != nil {
+		return nil, err
+	}
+	if err := ps.store.LoanProducts().Update(&updates); err != nil {
+		return nil, storeError(err)
+	}
+	return &updates, nil
+}
+
+type LoanProductQuery struct {
+	BankID   *uint
+	LoanType string
+	Active   *bool
+	Scope    ListScope
+	ListQuery
+}
+
+func (ps *LoanProductService) ListProducts(query LoanProductQuery) (*Page[models.LoanProduct], error) {
+	opts, err := query.options("code", "name", "loan_type", "interest_rate", "created_at")
+	if err != nil {
+		return nil, err
+	}
+	filter := repository.LoanProductFilter{LoanType: query.LoanType, Active: query.Active, ListOptions: opts}
+	if filter.BankID, err = narrow(query.BankID, query.Scope.BankID); err != nil {
+		return nil, err
+	}
+	products, err := ps.store.LoanProducts().Find(filter)
+	if err != nil {
+		return nil, err
+	}
+	return newPage(products, opts, query.ListQuery, func() (int64, error) { return ps.store.LoanProducts().Count(filter) })
+}
+
+const (
+	DefaultRetryIntervalDays = 3
+	DefaultMaxRetries        = 3
+)
+
+func validateProduct(p *models.LoanProduct) error {
+	p.Code = strings.ToUpper(strings.TrimSpace(p.Code))
+	p.RepaymentFrequency = strings.ToUpper(p.RepaymentFrequency)
+	p.RateType = strings.ToUpper(p.RateType)
+	if p.RateType == "" {
+		p.RateType = RateFixed
+	}
+	if p.RetryIntervalDays == 0 {
+		p.RetryIntervalDays = DefaultRetryIntervalDays
+	}
+	dayCount, err := interest.ParseDayCount(p.DayCount)
+	if err != nil {
+		return fmt.Errorf("%w: day_count must be %s, %s or %s", ErrInvalidArgument, interest.Actual365, interest.Actual360, interest.Thirty360)
+	}
+	p.DayCount = string(dayCount)
+	switch {
+	case p.Code == "" || p.Name == "" || p.LoanType == "":
+		return fmt.Errorf("%w: code, name and loan_type are required", ErrInvalidArgument)
+	case p.InterestRate < 0 || p.InterestRate > 100:
+		return fmt.Errorf("%w: interest_rate must be between 0 and 100", ErrInvalidArgument)
+	case !p.MinPrincipal.IsPositive() || p.MaxPrincipal < p.MinPrincipal:
+		return fmt.Errorf("%w: principal limits must be positive with min_principal <= max_principal", ErrInvalidArgument)
+	case p.MinTenureMonths < 1 || p.MaxTenureMonths < p.MinTenureMonths || p.MaxTenureMonths > MaxLoanTenureMonths:
+		return fmt.Errorf("%w: tenure limits must be between 1 and %d months with min_tenure_months <= max_tenure_months", ErrInvalidArgument, MaxLoanTenureMonths)
+	case p.RateType != RateFixed && p.RateType != RateFloating:
+		return fmt.Errorf("%w: rate_type must be %s or %s", ErrInvalidArgument, RateFixed, RateFloating)
+	case p.RateType == RateFixed && (p.BenchmarkID != nil || p.Spread != 0 || p.ResetMonths != 0):
+		return fmt.Errorf("%w: benchmark_id, spread and reset_months only apply to %s products", ErrInvalidArgument, RateFloating)
+	case p.RateType == RateFloating && (p.BenchmarkID == nil || p.ResetMonths < 1 || p.ResetMonths > MaxLoanTenureMonths):
+		return fmt.Errorf("%w: %s products need a benchmark_id and reset_months between 1 and %d", ErrInvalidArgument, RateFloating, MaxLoanTenureMonths)
+	case p.Spread < -100 || p.Spread > 100:
+		return fmt.Errorf("%w: spread must be between -100 and 100", ErrInvalidArgument)
+	case p.RepaymentFrequency != "" && p.RepaymentFrequency != RepaymentMonthly && p.RepaymentFrequency != RepaymentQuarterly:
+		return fmt.Errorf("%w: repayment_frequency must be %s or %s", ErrInvalidArgument, RepaymentMonthly, RepaymentQuarterly)
+	case p.ProcessingFeeRate < 0 || p.ProcessingFeeRate >= 100 || p.ProcessingFee.IsNegative():
+		return fmt.Errorf("%w: processing fees must not be negative and processing_fee_rate must be below 100", ErrInvalidArgument)
+	case p.ProcessingFee >= p.MinPrincipal:
+		return fmt.Errorf("%w: processing_fee must be less than min_principal", ErrInvalidArgument)
+	case p.MaxActiveLoans < 0 || p.MinSavingsBalance.IsNegative() || p.MinRelationshipDays < 0:
+		return fmt.Errorf("%w: eligibility limits must not be negative", ErrInvalidArgument)
+	case p.BounceCharge.IsNegative() || p.RetryIntervalDays < 1 || p.MaxRetries < 0:
+		return fmt.Errorf("%w: bounce_charge and max_retries must not be negative and retry_interval_days must be positive", ErrInvalidArgument)
+	case p.PrepaymentPenaltyRate < 0 || p.PrepaymentPenaltyRate >= 100:
+		return fmt.Errorf("%w: prepayment_penalty_rate must be at least 0 and below 100", ErrInvalidArgument)
+	case p.CompoundingPerYear < 0 || p.CompoundingPerYear > 365:
+		return fmt.Errorf("%w: compounding_per_year must be between 0 (simple interest) and 365", ErrInvalidArgument)
+	case p.LateFee.IsNegative() || p.PenalInterestRate < 0 || p.PenalInterestRate > 100:
+		return fmt.Errorf("%w: late_fee must not be negative and penal_interest_rate must be between 0 and 100", ErrInvalidArgument)
+	}
+	return nil
+}
+
+// checkBenchmark fails unless a floating-rate product's benchmark belongs to
+// the product's bank.
+func checkBenchmark(store repository.Store, p *models.LoanProduct) error {
+	if p.BenchmarkID == nil {
+		return nil
+	}
+	benchmark, err := store.Benchmarks().Get(*p.BenchmarkID)
+	if err != nil {
+		return lookupError("benchmark", err)
+	}
+	if benchmark.BankID != p.BankID {
+		return fmt.Errorf("%w: benchmark %s belongs to another bank", ErrInvalidArgument, benchmark.Code)
+	}
+	return nil
+}
+
+// productRate is the rate a loan under product starts at on date. A
+// floating rate's reset period must be a whole number of repayment periods.
+func productRate(tx repository.Store, product *models.LoanProduct, terms LoanTerms, date time.Time) (float64, error) {
+	if product.RateType != RateFloating {
+		return product.InterestRate, nil
+	}
+	if product.ResetMonths%periodMonths(terms.RepaymentFrequency) != 0 {
+		return 0, fmt.Errorf("%w: product %s resets every %d months, which does not suit %s repayments", ErrInvalidArgument, product.Code, product.ResetMonths, strings.ToLower(terms.RepaymentFrequency))
+	}
+	rate, _, err := floatingRate(tx, *product.BenchmarkID, product.Spread, date)
+	return rate, err
+}
+
+// processingFee is what origination of principal under product costs:
+// the flat fee plus the percentage of the principal.
+func processingFee(product *models.LoanProduct, principal money.Money) (money.Money, error) {
+	fee, err := principal.MulRate(product.ProcessingFeeRate)
+	if err != nil {
+		return 0, err
+	}
+	return product.ProcessingFee + fee, nil
+}
+
+// productTerms checks principal and terms against product's limits and
+// fills in what the borrower left out: the product's frequency, and a tenure
+// of DefaultLoanTenureMonths brought within the product's range.
+func productTerms(product *models.LoanProduct, principal money.Money, terms LoanTerms) (LoanTerms, error) {
+	if principal < product.MinPrincipal || principal > product.MaxPrincipal {
+		return terms, fmt.Errorf("%w: principal_amount must be between %s and %s for product %s", ErrInvalidArgument, product.MinPrincipal, product.MaxPrincipal, product.Code)
+	}
+	if terms.TenureMonths == 0 {
+		terms.TenureMonths = min(max(DefaultLoanTenureMonths, product.MinTenureMonths), product.MaxTenureMonths)
+	}
+	if terms.TenureMonths < product.MinTenureMonths || terms.TenureMonths > product.MaxTenureMonths {
+		return terms, fmt.Errorf("%w: tenure_months must be between %d and %d for product %s", ErrInvalidArgument, product.MinTenureMonths, product.MaxTenureMonths, product.Code)
+	}
+	if product.RepaymentFrequency != "" {
+		if terms.RepaymentFrequency != "" && !strings.EqualFold(terms.RepaymentFrequency, product.RepaymentFrequency) {
+			return terms, fmt.Errorf("%w: product %s is repaid %s", ErrInvalidArgument, product.Code, strings.ToLower(product.RepaymentFrequency))
+		}
+		terms.RepaymentFrequency = product.RepaymentFrequency
+	}
+	return terms.normalize()
+}
+
+// checkEligibility applies product's eligibility rules to the customer.
+func checkEligibility(tx repository.Store, product *models.LoanProduct, customer *models.Customer, now time.Time) error {
+	branch, err := tx.Branches().Get(customer.BranchID)
+	if err != nil {
+		return lookupError("branch", err)
+	}
+	if branch.BankID != product.BankID {
+		return fmt.Errorf("%w: product %s is not offered by the customer's bank", ErrNotEligible, product.Code)
+	}
+	if product.MaxActiveLoans > 0 {
+		active, err := tx.Loans().Count(repository.LoanFilter{CustomerID: &customer.ID, Status: LoanStatusActive})
+		if err != nil {
+			return err
+		}
+		if active >= int64(product.MaxActiveLoans) {
+			return fmt.Errorf("%w: customer already has %d active loans", ErrNotEligible, active)
+		}
+	}
+	if product.MinSavingsBalance.IsPositive() {
+		accounts, err := tx.Accounts().Find(repository.AccountFilter{CustomerID: &customer.ID})
+		if err != nil {
+			return err
+		}
+		var savings money.Money
+		for _, account := range accounts {
+			if account.Status != AccountStatusClosed {
+				savings += account.Balance
+			}
+		}
+		if savings < product.MinSavingsBalance {
+			return fmt.Errorf("%w: savings of %s are below the required %s", ErrNotEligible, savings, product.MinSavingsBalance)
+		}
+	}
+	if product.MinRelationshipDays > 0 && now.Sub(customer.CreatedAt) < time.Duration(product.MinRelationshipDays)*24*time.Hour {
+		return fmt.Errorf("%w: customer must have banked with us for %d days", ErrNotEligible, product.MinRelationshipDays)
+	}
+	return nil
+}
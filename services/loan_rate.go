@@ -0,0 +1,168 @@
+package services
+
+import (
+	"banking-system/models"
+	"banking-system/money"
+	"banking-system/repository"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Why a loan's rate was set.
+const (
+	RateChangeDisbursement = "disbursement"
+	RateChangeReset        = "reset"
+)
+
+// RateResetRun summarises one rate reset job. Repriced counts the resets
+// that changed a loan's rate; Failed counts loans whose reset hit an error,
+// which a rerun retries.
+type RateResetRun struct {
+	BusinessDate   time.Time   `json:"business_date"`
+	Reset          int         `json:"reset"`
+	Repriced       int         `json:"repriced"`
+	Failed         int         `json:"failed"`
+	FailedLoanIDs  []uint      `json:"failed_loan_ids,omitempty"`
+	InterestChange money.Money `json:"interest_change"`
+}
+
+// nextReset is the first reset of a floating-rate loan after from, or nil
+// when the loan ends by then and no installment would be repriced.
+func nextReset(loan *models.Loan, from, endDate time.Time) *time.Time {
+	next := addMonths(from, loan.ResetMonths)
+	if !next.Before(endDate) {
+		return nil
+	}
+	return &next
+}
+
+// ResetRates reprices every floating-rate loan with a reset due on or before
+// date. A loan whose resets were missed is caught up one reset at a time,
+// each at the benchmark rate in effect on its own reset date. Each reset
+// moves the loan's next reset date on in the same transaction, so the job
+// can be rerun safely. A loan that fails is logged and counted in the run
+// rather than stopping it.
+func (cs *CollectionService) ResetRates(date time.Time) (*RateResetRun, error) {
+	date = BusinessDate(date)
+	if date.After(BusinessDate(cs.now())) {
+		return nil, fmt.Errorf("%w: business date %s is in the future", ErrInvalidArgument, date.Format(time.DateOnly))
+	}
+	loans, err := cs.store.Loans().Find(repository.LoanFilter{Status: LoanStatusActive, ResetThrough: &date})
+	if err != nil {
+		return nil, err
+	}
+
+	run := RateResetRun{BusinessDate: date}
+	for _, loan := range loans {
+		for {
+			var change *models.LoanRateChange
+			err := cs.store.Atomic(func(tx repository.Store) error {
+				var err error
+				change, err = cs.resetNext(tx, loan.ID, date)
+				return err
+			})
+			if err != nil {
+				log.Printf("collections: resetting the rate of loan %d for %s: %v", loan.ID, date.Format(time.DateOnly), err)
+				run.Failed++
+				run.FailedLoanIDs = append(run.FailedLoanIDs, loan.ID)
+				break
+			}
+			if change == nil {
+				break
+			}
+			run.Reset++
+			if change.InterestRate != change.PreviousRate {
+				run.Repriced++
+			}
+			run.InterestChange += change.InterestChange
+		}
+	}
+	return &run, nil
+}
+
+// resetNext applies the loan's next reset if it is due on or before date,
+// and returns nil when it is not.
+func (cs *CollectionService) resetNext(tx repository.Store, loanID uint, date time.Time) (*models.LoanRateChange, error) {
+	loan, err := tx.Loans().GetForUpdate(loanID)
+	if err != nil {
+		return nil, lookupError("loan", err)
+	}
+	if loan.Status != LoanStatusActive || loan.RateType != RateFloating || loan.NextResetDate == nil || loan.NextResetDate.After(date) {
+		return nil, nil
+	}
+	return cs.loans.reprice(tx, loan, BusinessDate(*loan.NextResetDate))
+}
+
+// reprice sets a floating-rate loan's rate from its benchmark on the reset
+// date on and rebuilds the installments falling due after it, keeping their
+// number and due dates. The difference in scheduled interest is added to or
+// taken off what the loan owes. Installments already part-paid are left as
+// they are.
+func (ls *LoanService) reprice(tx repository.Store, loan *models.Loan, on time.Time) (*models.LoanRateChange, error) {
+	rate, benchmark, err := floatingRate(tx, *loan.BenchmarkID, loan.Spread, on)
+	if err != nil {
+		return nil, err
+	}
+	installments, err := tx.Loans().ListInstallments(loan.ID)
+	if err != nil {
+		return nil, err
+	}
+	var future []models.LoanInstallment
+	var outstanding, scheduledInterest money.Money
+	for _, installment := range installments {
+		if installment.Status == InstallmentPending && installment.DueDate.After(on) {
+			future = append(future, installment)
+			outstanding += installment.PrincipalDue
+			scheduledInterest += installment.InterestDue
+		}
+	}
+
+	change := models.LoanRateChange{
+		LoanID:        loan.ID,
+		EffectiveDate: on,
+		Reason:        RateChangeReset,
+		BenchmarkRate: &benchmark,
+		Spread:        loan.Spread,
+		PreviousRate:  loan.InterestRate,
+		InterestRate:  rate,
+	}
+	loan.InterestRate = rate
+	loan.NextResetDate = nil
+	if len(future) > 0 {
+		if rate != change.PreviousRate {
+			rebuilt, err := amortizeFrom(outstanding, rate, loan.RepaymentFrequency, loan.StartDate, future[0].Number, len(future))
+			if err != nil {
+				return nil, err
+			}
+			rebuiltInterest, err := reschedule(tx, loan, future, rebuilt)
+			if err != nil {
+				return nil, err
+			}
+			change.InterestChange = rebuiltInterest - scheduledInterest
+		}
+		loan.NextResetDate = nextReset(loan, on, future[len(future)-1].DueDate)
+	}
+	if !change.InterestChange.IsZero() {
+		if _, err := ls.ledger.RecordLoanRateReset(tx, loan.ID, change.InterestChange); err != nil {
+			return nil, err
+		}
+		loan.TotalPayableAmount += change.InterestChange
+		loan.PendingAmount += change.InterestChange
+	}
+	if err := tx.Loans().Update(loan); err != nil {
+		return nil, err
+	}
+	change.InstallmentAmount = loan.InstallmentAmount
+	if err := tx.Loans().AddRateChange(&change); err != nil {
+		return nil, err
+	}
+	return &change, nil
+}
+
+func (ls *LoanService) GetRateHistory(loanID uint) ([]models.LoanRateChange, error) {
+	if _, err := ls.store.Loans().Get(loanID); err != nil {
+		return nil, lookupError("loan", err)
+	}
+	return ls.store.Loans().RateHistory(loanID)
+}
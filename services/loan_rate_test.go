@@ -0,0 +1,57 @@
+package services
+
+import (
+	"banking-system/models"
+	"banking-system/money"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestResetRatesCarriesOnPastAFailedLoan(t *testing.T) {
+	f := newFixture(t)
+	today := BusinessDate(time.Now())
+	benchmarks := NewBenchmarkService(f.store)
+	benchmark := models.Benchmark{BankID: f.bank.ID, Code: "BASE", Name: "Base rate"}
+	f.must(benchmarks.CreateBenchmark(&benchmark))
+	_, err := benchmarks.PublishRate(benchmark.ID, today, 8, "treasury")
+	f.must(err)
+	_, err = benchmarks.PublishRate(benchmark.ID, today.AddDate(0, 3, 0), 9, "treasury")
+	f.must(err)
+
+	f.product = models.LoanProduct{
+		BankID:          f.bank.ID,
+		Code:            "FLOATING",
+		Name:            "Floating personal loan",
+		LoanType:        "personal",
+		RateType:        RateFloating,
+		BenchmarkID:     &benchmark.ID,
+		Spread:          2,
+		ResetMonths:     3,
+		MinPrincipal:    money.MustParse("100"),
+		MaxPrincipal:    money.MustParse("1000000"),
+		MinTenureMonths: 1,
+		MaxTenureMonths: 60,
+	}
+	f.must(NewLoanProductService(f.store).CreateProduct(&f.product))
+	account := f.openAccount(f.customer.ID, 0)
+	good := f.disburseLoan(account.ID, money.MustParse("1200"), 12)
+	bad := f.disburseLoan(account.ID, money.MustParse("1200"), 12)
+
+	reset := *good.NextResetDate
+	collections := NewCollectionService(failingStore{Store: f.store, loanID: bad.ID})
+	collections.now = func() time.Time { return reset }
+	run, err := collections.ResetRates(reset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Reset != 1 || run.Repriced != 1 || run.Failed != 1 || !slices.Equal(run.FailedLoanIDs, []uint{bad.ID}) {
+		t.Fatalf("run = %+v, want loan %d repriced and loan %d failed", run, good.ID, bad.ID)
+	}
+	loan, err := f.store.Loans().Get(good.ID)
+	f.must(err)
+	if loan.InterestRate != 11 {
+		t.Errorf("loan %d is at %v%%, want 11%%", good.ID, loan.InterestRate)
+	}
+	f.assertReconciled()
+}
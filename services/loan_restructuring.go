@@ -0,0 +1,429 @@
+package services
+
+import (
+	"banking-system/models"
+	"banking-system/money"
+	"banking-system/repository"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Kinds of relief a borrower in hardship can be granted.
+const (
+	RestructureMoratorium = "MORATORIUM"
+	RestructureTerms      = "RESTRUCTURE"
+)
+
+// How interest accrued over a moratorium is paid.
+const (
+	MoratoriumCapitalise = "CAPITALISE"
+	MoratoriumDefer      = "DEFER"
+)
+
+const (
+	MaxMoratoriumMonths   = 24
+	RateChangeRestructure = "restructure"
+)
+
+var restructureReasons = []string{
+	"job_loss", "income_reduction", "medical_emergency", "natural_disaster", "business_disruption",
+}
+
+// Moratorium puts a loan's installments off for Months. Interest accrues
+// meanwhile and is either capitalised into the principal or deferred to be
+// paid with the installments that follow.
+type Moratorium struct {
+	Months            int
+	InterestTreatment string
+	Reason            string
+	Note              string
+	ApprovedBy        string
+}
+
+// Restructure gives a loan new terms. A nil rate or spread and a zero tenure
+// keep the loan's own; InterestRate applies to fixed-rate loans and Spread to
+// floating-rate ones. TenureMonths is the loan's whole new tenure, counted
+// from disbursement.
+type Restructure struct {
+	InterestRate *float64
+	Spread       *float64
+	TenureMonths int
+	Reason       string
+	Note         string
+	ApprovedBy   string
+}
+
+// Relief is the outcome of a moratorium or restructuring: the loan, the
+// record of what was done and the schedule now in force.
+type Relief struct {
+	Loan          *models.Loan              `json:"loan"`
+	Restructuring *models.LoanRestructuring `json:"restructuring"`
+	Schedule      []models.LoanInstallment  `json:"schedule"`
+}
+
+// ScheduleVersion is one version of a loan's schedule. Version 1 is the one
+// built at disbursement and each restructuring makes the next; Restructuring
+// is the one that made it. Replaced versions are shown as they stood when
+// they were replaced.
+type ScheduleVersion struct {
+	Version       int                       `json:"version"`
+	Current       bool                      `json:"current"`
+	Restructuring *models.LoanRestructuring `json:"restructuring,omitempty"`
+	Installments  []models.LoanInstallment  `json:"installments"`
+}
+
+func checkRestructureReason(reason string) error {
+	if !slices.Contains(restructureReasons, reason) {
+		return fmt.Errorf("%w: reason must be one of %s", ErrInvalidArgument, strings.Join(restructureReasons, ", "))
+	}
+	return nil
+}
+
+// restructurable is the part of an active loan's schedule a restructuring
+// may change: the pending installments falling due after today, which are
+// always the last ones. Installments already due or part-paid stay as they
+// are.
+type restructurable struct {
+	future      []models.LoanInstallment
+	outstanding money.Money
+	scheduled   money.Money
+}
+
+// beginRestructure loads the schedule of an active loan, keeps a copy of it
+// as the loan's current schedule version and returns the part of it that can
+// be rebuilt.
+func beginRestructure(tx repository.Store, loan *models.Loan, today time.Time) (*restructurable, error) {
+	installments, err := scheduledInstallments(tx, loan)
+	if err != nil {
+		return nil, err
+	}
+	var r restructurable
+	snapshots := make([]models.LoanScheduleSnapshot, 0, len(installments))
+	for _, installment := range installments {
+		if installment.Status == InstallmentPending && installment.DueDate.After(today) {
+			r.future = append(r.future, installment)
+			r.outstanding += installment.PrincipalDue
+			r.scheduled += installment.Amount
+		}
+		snapshots = append(snapshots, models.LoanScheduleSnapshot{
+			LoanID:               loan.ID,
+			Version:              loan.ScheduleVersion,
+			InstallmentID:        installment.ID,
+			Number:               installment.Number,
+			DueDate:              installment.DueDate,
+			Amount:               installment.Amount,
+			PrincipalDue:         installment.PrincipalDue,
+			InterestDue:          installment.InterestDue,
+			DeferredInterest:     installment.DeferredInterest,
+			OutstandingPrincipal: installment.OutstandingPrincipal,
+			PrincipalPaid:        installment.PrincipalPaid,
+			InterestPaid:         installment.InterestPaid,
+			LateFee:              installment.LateFee,
+			Status:               installment.Status,
+			PaidAt:               installment.PaidAt,
+			CreatedAt:            installment.CreatedAt,
+		})
+	}
+	if len(r.future) == 0 {
+		return nil, fmt.Errorf("%w: loan %d has no installments left to restructure", ErrConflict, loan.ID)
+	}
+	if err := tx.Loans().ArchiveSchedule(snapshots); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// finishRestructure puts rebuilt in place of the future installments as the
+// loan's next schedule version, books the change in what the loan owes as
+// interest, flags the loan as restructured and stores record.
+func (ls *LoanService) finishRestructure(tx repository.Store, loan *models.Loan, r *restructurable, rebuilt []models.LoanInstallment, record *models.LoanRestructuring) error {
+	var total money.Money
+	for _, installment := range rebuilt {
+		total += installment.Amount
+	}
+	if _, err := reschedule(tx, loan, r.future, rebuilt); err != nil {
+		return err
+	}
+	if loan.NextResetDate != nil && !loan.NextResetDate.Before(*loan.EndDate) {
+		loan.NextResetDate = nil
+	}
+	change := total - r.scheduled
+	if !change.IsZero() {
+		if _, err := ls.ledger.RecordLoanRestructure(tx, loan.ID, change); err != nil {
+			return err
+		}
+		loan.TotalPayableAmount += change
+		loan.PendingAmount += change
+	}
+	loan.ScheduleVersion++
+	loan.Restructured = true
+	loan.RestructuredOn = &record.EffectiveDate
+	if err := tx.Loans().Update(loan); err != nil {
+		return err
+	}
+
+	record.LoanID = loan.ID
+	record.InterestRate = loan.InterestRate
+	record.TenureMonths = loan.TenureMonths
+	record.InstallmentAmount = loan.InstallmentAmount
+	record.InterestChange = change
+	record.ScheduleVersion = loan.ScheduleVersion
+	return tx.Loans().AddRestructuring(record)
+}
+
+// GrantMoratorium puts off every pending installment of an active loan
+// falling due after today by the moratorium's months, extending the loan by
+// as much. Interest accrues on the outstanding principal over the
+// moratorium under the loan's day count. CAPITALISE adds it to the principal
+// and rebuilds the installments on the larger balance; DEFER spreads it
+// evenly, without further interest, over the installments that follow.
+func (ls *LoanService) GrantMoratorium(loanID uint, moratorium Moratorium) (*Relief, error) {
+	treatment := strings.ToUpper(moratorium.InterestTreatment)
+	if treatment != MoratoriumCapitalise && treatment != MoratoriumDefer {
+		return nil, fmt.Errorf("%w: interest_treatment must be %s or %s", ErrInvalidArgument, MoratoriumCapitalise, MoratoriumDefer)
+	}
+	if moratorium.Months < 1 || moratorium.Months > MaxMoratoriumMonths {
+		return nil, fmt.Errorf("%w: months must be between 1 and %d", ErrInvalidArgument, MaxMoratoriumMonths)
+	}
+	if err := checkRestructureReason(moratorium.Reason); err != nil {
+		return nil, err
+	}
+
+	var relief Relief
+	err := ls.store.Atomic(func(tx repository.Store) error {
+		loan, err := tx.Loans().GetForUpdate(loanID)
+		if err != nil {
+			return lookupError("loan", err)
+		}
+		if moratorium.Months%periodMonths(loan.RepaymentFrequency) != 0 {
+			return fmt.Errorf("%w: months must be a whole number of %s periods", ErrInvalidArgument, strings.ToLower(loan.RepaymentFrequency))
+		}
+		today := BusinessDate(time.Now())
+		r, err := beginRestructure(tx, loan, today)
+		if err != nil {
+			return err
+		}
+
+		record := models.LoanRestructuring{
+			Kind:                 RestructureMoratorium,
+			EffectiveDate:        today,
+			Reason:               moratorium.Reason,
+			Note:                 moratorium.Note,
+			MoratoriumMonths:     moratorium.Months,
+			InterestTreatment:    treatment,
+			PreviousRate:         loan.InterestRate,
+			PreviousTenureMonths: loan.TenureMonths,
+			PreviousInstallment:  loan.InstallmentAmount,
+			ApprovedBy:           moratorium.ApprovedBy,
+		}
+		exact, err := loanInterest(loan, r.outstanding, today, addMonths(today, moratorium.Months))
+		if err != nil {
+			return err
+		}
+		accrued, err := money.FromRat(exact)
+		if err != nil {
+			return err
+		}
+		record.MoratoriumInterest = accrued
+
+		loan.MoratoriumMonths += moratorium.Months
+		for i := range r.future {
+			r.future[i].DueDate = dueDate(loan, r.future[i].Number)
+		}
+		principal := r.outstanding
+		if treatment == MoratoriumCapitalise {
+			principal += accrued
+		}
+		rebuilt, err := amortizeFrom(principal, loan.InterestRate, loan.RepaymentFrequency, loan.StartDate, r.future[0].Number, len(r.future))
+		if err != nil {
+			return err
+		}
+		if treatment == MoratoriumDefer {
+			deferInterest(rebuilt, accrued)
+		}
+		if err := ls.finishRestructure(tx, loan, r, rebuilt, &record); err != nil {
+			return err
+		}
+		relief = Relief{Loan: loan, Restructuring: &record}
+		relief.Schedule, err = tx.Loans().ListInstallments(loan.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &relief, nil
+}
+
+// Restructure gives an active loan a new rate, tenure or both from today.
+// The principal outstanding on its pending installments falling due after
+// today is amortized afresh over what is left of the new tenure, and the
+// difference in interest is added to or taken off what the loan owes. A
+// floating-rate loan takes a new spread instead of a rate and is priced off
+// its benchmark today; a change of rate is kept in the rate history.
+func (ls *LoanService) Restructure(loanID uint, terms Restructure) (*Relief, error) {
+	if terms.InterestRate == nil && terms.Spread == nil && terms.TenureMonths == 0 {
+		return nil, fmt.Errorf("%w: give a new interest_rate, spread or tenure_months", ErrInvalidArgument)
+	}
+	if terms.InterestRate != nil && (*terms.InterestRate < 0 || *terms.InterestRate > 100) {
+		return nil, fmt.Errorf("%w: interest_rate must be between 0 and 100", ErrInvalidArgument)
+	}
+	if terms.Spread != nil && (*terms.Spread < -100 || *terms.Spread > 100) {
+		return nil, fmt.Errorf("%w: spread must be between -100 and 100", ErrInvalidArgument)
+	}
+	if err := checkRestructureReason(terms.Reason); err != nil {
+		return nil, err
+	}
+
+	var relief Relief
+	err := ls.store.Atomic(func(tx repository.Store) error {
+		loan, err := tx.Loans().GetForUpdate(loanID)
+		if err != nil {
+			return lookupError("loan", err)
+		}
+		if loan.RateType == RateFloating && terms.InterestRate != nil {
+			return fmt.Errorf("%w: loan %d has a floating rate; restructure its spread instead", ErrInvalidArgument, loan.ID)
+		}
+		if loan.RateType != RateFloating && terms.Spread != nil {
+			return fmt.Errorf("%w: spread only applies to floating-rate loans", ErrInvalidArgument)
+		}
+		today := BusinessDate(time.Now())
+		r, err := beginRestructure(tx, loan, today)
+		if err != nil {
+			return err
+		}
+
+		record := models.LoanRestructuring{
+			Kind:                 RestructureTerms,
+			EffectiveDate:        today,
+			Reason:               terms.Reason,
+			Note:                 terms.Note,
+			PreviousRate:         loan.InterestRate,
+			PreviousTenureMonths: loan.TenureMonths,
+			PreviousInstallment:  loan.InstallmentAmount,
+			ApprovedBy:           terms.ApprovedBy,
+		}
+		pricing := models.LoanRateChange{
+			LoanID:        loan.ID,
+			EffectiveDate: today,
+			Reason:        RateChangeRestructure,
+			Spread:        loan.Spread,
+			PreviousRate:  loan.InterestRate,
+		}
+		switch {
+		case terms.InterestRate != nil:
+			loan.InterestRate = *terms.InterestRate
+		case terms.Spread != nil:
+			rate, benchmark, err := floatingRate(tx, *loan.BenchmarkID, *terms.Spread, today)
+			if err != nil {
+				return err
+			}
+			loan.InterestRate = rate
+			loan.Spread = *terms.Spread
+			pricing.BenchmarkRate = &benchmark
+		}
+
+		n := len(r.future)
+		if terms.TenureMonths != 0 {
+			step := periodMonths(loan.RepaymentFrequency)
+			if terms.TenureMonths < 1 || terms.TenureMonths > MaxLoanTenureMonths || terms.TenureMonths%step != 0 {
+				return fmt.Errorf("%w: tenure_months must be a whole number of %s periods up to %d", ErrInvalidArgument, strings.ToLower(loan.RepaymentFrequency), MaxLoanTenureMonths)
+			}
+			n = (terms.TenureMonths-loan.MoratoriumMonths)/step - (r.future[0].Number - 1)
+			if n < 1 {
+				return fmt.Errorf("%w: tenure_months must leave at least one installment after today", ErrInvalidArgument)
+			}
+		}
+		if loan.InterestRate == record.PreviousRate && loan.Spread == pricing.Spread && n == len(r.future) {
+			return fmt.Errorf("%w: the new terms are the loan's current terms", ErrInvalidArgument)
+		}
+
+		rebuilt, err := amortizeFrom(r.outstanding, loan.InterestRate, loan.RepaymentFrequency, loan.StartDate, r.future[0].Number, n)
+		if err != nil {
+			return err
+		}
+		if err := ls.finishRestructure(tx, loan, r, rebuilt, &record); err != nil {
+			return err
+		}
+		if loan.InterestRate != pricing.PreviousRate {
+			pricing.Spread = loan.Spread
+			pricing.InterestRate = loan.InterestRate
+			pricing.InstallmentAmount = loan.InstallmentAmount
+			pricing.InterestChange = record.InterestChange
+			if err := tx.Loans().AddRateChange(&pricing); err != nil {
+				return err
+			}
+		}
+		relief = Relief{Loan: loan, Restructuring: &record}
+		relief.Schedule, err = tx.Loans().ListInstallments(loan.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &relief, nil
+}
+
+func (ls *LoanService) GetRestructurings(loanID uint) ([]models.LoanRestructuring, error) {
+	if _, err := ls.store.Loans().Get(loanID); err != nil {
+		return nil, lookupError("loan", err)
+	}
+	return ls.store.Loans().ListRestructurings(loanID)
+}
+
+// GetScheduleVersions returns every version of the loan's schedule, oldest
+// first, ending with the one in force.
+func (ls *LoanService) GetScheduleVersions(loanID uint) ([]ScheduleVersion, error) {
+	loan, err := ls.store.Loans().Get(loanID)
+	if err != nil {
+		return nil, lookupError("loan", err)
+	}
+	restructurings, err := ls.store.Loans().ListRestructurings(loanID)
+	if err != nil {
+		return nil, err
+	}
+	snapshots, err := ls.store.Loans().ListSnapshots(loanID)
+	if err != nil {
+		return nil, err
+	}
+	current, err := ls.store.Loans().ListInstallments(loanID)
+	if err != nil {
+		return nil, err
+	}
+
+	madeBy := make(map[int]*models.LoanRestructuring, len(restructurings))
+	for i := range restructurings {
+		madeBy[restructurings[i].ScheduleVersion] = &restructurings[i]
+	}
+	versions := []ScheduleVersion{}
+	for _, s := range snapshots {
+		if len(versions) == 0 || versions[len(versions)-1].Version != s.Version {
+			versions = append(versions, ScheduleVersion{Version: s.Version, Restructuring: madeBy[s.Version]})
+		}
+		version := &versions[len(versions)-1]
+		version.Installments = append(version.Installments, models.LoanInstallment{
+			ID:                   s.InstallmentID,
+			LoanID:               s.LoanID,
+			Number:               s.Number,
+			DueDate:              s.DueDate,
+			Amount:               s.Amount,
+			PrincipalDue:         s.PrincipalDue,
+			InterestDue:          s.InterestDue,
+			DeferredInterest:     s.DeferredInterest,
+			OutstandingPrincipal: s.OutstandingPrincipal,
+			PrincipalPaid:        s.PrincipalPaid,
+			InterestPaid:         s.InterestPaid,
+			LateFee:              s.LateFee,
+			Status:               s.Status,
+			PaidAt:               s.PaidAt,
+			CreatedAt:            s.CreatedAt,
+		})
+	}
+	return append(versions, ScheduleVersion{
+		Version:       loan.ScheduleVersion,
+		Current:       true,
+		Restructuring: madeBy[loan.ScheduleVersion],
+		Installments:  current,
+	}), nil
+}
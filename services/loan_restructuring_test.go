@@ -0,0 +1,229 @@
+package services
+
+import (
+	"banking-system/interest"
+	"banking-system/models"
+	"banking-system/money"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGrantMoratorium(t *testing.T) {
+	tests := []struct {
+		treatment string
+		// principal is what the rebuilt installments repay, given the
+		// interest accrued over the moratorium.
+		principal func(accrued money.Money) money.Money
+		deferred  func(accrued money.Money) money.Money
+	}{
+		{
+			MoratoriumCapitalise,
+			func(accrued money.Money) money.Money { return money.MustParse("1200") + accrued },
+			func(money.Money) money.Money { return 0 },
+		},
+		{
+			MoratoriumDefer,
+			func(money.Money) money.Money { return money.MustParse("1200") },
+			func(accrued money.Money) money.Money { return accrued },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.treatment, func(t *testing.T) {
+			f := newFixture(t)
+			account := f.openAccount(f.customer.ID, 0)
+			loan := f.disburseLoan(account.ID, money.MustParse("1200"), 12)
+			today := BusinessDate(time.Now())
+
+			relief, err := NewLoanService(f.store).GrantMoratorium(loan.ID, Moratorium{
+				Months:            3,
+				InterestTreatment: tt.treatment,
+				Reason:            "job_loss",
+				ApprovedBy:        "manager",
+			})
+			f.must(err)
+
+			exact := interest.Simple(money.MustParse("1200"), 12, interest.DayCount(loan.DayCount), today, addMonths(today, 3))
+			accrued, err := money.FromRat(exact)
+			f.must(err)
+			record := relief.Restructuring
+			if record.Kind != RestructureMoratorium || record.InterestTreatment != tt.treatment || record.MoratoriumInterest != accrued {
+				t.Errorf("restructuring = %+v, want a %s moratorium accruing %s", *record, tt.treatment, accrued)
+			}
+
+			got := relief.Loan
+			start := BusinessDate(loan.StartDate)
+			if got.MoratoriumMonths != 3 || got.TenureMonths != 15 || !got.EndDate.Equal(addMonths(start, 15)) {
+				t.Errorf("loan runs %d months with %d of moratorium to %s, want 15 with 3 to %s", got.TenureMonths, got.MoratoriumMonths, got.EndDate, addMonths(start, 15))
+			}
+			if !got.Restructured || got.RestructuredOn == nil || !got.RestructuredOn.Equal(today) || got.ScheduleVersion != 2 {
+				t.Errorf("loan restructured %v on %v at schedule version %d, want true on %s at version 2", got.Restructured, got.RestructuredOn, got.ScheduleVersion, today)
+			}
+			if record.ScheduleVersion != 2 || record.PreviousTenureMonths != 12 || record.PreviousInstallment != loan.InstallmentAmount {
+				t.Errorf("restructuring = %+v, want version 2 replacing 12 months at %s", *record, loan.InstallmentAmount)
+			}
+
+			if len(relief.Schedule) != 12 {
+				t.Fatalf("schedule has %d installments, want 12", len(relief.Schedule))
+			}
+			var principal, deferred, total money.Money
+			for _, installment := range relief.Schedule {
+				if want := addMonths(start, installment.Number+3); !installment.DueDate.Equal(want) {
+					t.Errorf("installment %d falls due %s, want %s", installment.Number, installment.DueDate, want)
+				}
+				principal += installment.PrincipalDue
+				deferred += installment.DeferredInterest
+				total += installment.Amount
+			}
+			if principal != tt.principal(accrued) || deferred != tt.deferred(accrued) {
+				t.Errorf("schedule repays %s of principal and %s of deferred interest, want %s and %s", principal, deferred, tt.principal(accrued), tt.deferred(accrued))
+			}
+			if change := total - loan.TotalPayableAmount; change != record.InterestChange {
+				t.Errorf("interest change = %s, want the %s the schedule grew by", record.InterestChange, change)
+			}
+			if got.TotalPayableAmount != loan.TotalPayableAmount+record.InterestChange {
+				t.Errorf("loan owes %s in all, want %s plus the %s change", got.TotalPayableAmount, loan.TotalPayableAmount, record.InterestChange)
+			}
+			switch tt.treatment {
+			case MoratoriumDefer:
+				if record.InterestChange != accrued {
+					t.Errorf("deferring added %s of interest, want the %s accrued", record.InterestChange, accrued)
+				}
+			case MoratoriumCapitalise:
+				if record.InterestChange <= accrued {
+					t.Errorf("capitalising added %s of interest, want more than the %s accrued", record.InterestChange, accrued)
+				}
+			}
+
+			assertScheduleMatches(f, loan.ID)
+			f.assertReconciled()
+		})
+	}
+}
+
+func TestRestructure(t *testing.T) {
+	f := newFixture(t)
+	account := f.openAccount(f.customer.ID, 0)
+	loan := f.disburseLoan(account.ID, money.MustParse("1200"), 12)
+	rate := 9.0
+
+	relief, err := NewLoanService(f.store).Restructure(loan.ID, Restructure{
+		InterestRate: &rate,
+		TenureMonths: 24,
+		Reason:       "income_reduction",
+		ApprovedBy:   "manager",
+	})
+	f.must(err)
+
+	got := relief.Loan
+	if got.InterestRate != 9 || got.TenureMonths != 24 || len(relief.Schedule) != 24 {
+		t.Errorf("loan runs %d months (%d installments) at %v%%, want 24 at 9%%", got.TenureMonths, len(relief.Schedule), got.InterestRate)
+	}
+	if !got.Restructured || got.ScheduleVersion != 2 || got.InstallmentAmount >= loan.InstallmentAmount {
+		t.Errorf("loan = %+v, want it restructured at version 2 with a smaller installment than %s", *got, loan.InstallmentAmount)
+	}
+	record := relief.Restructuring
+	if record.Kind != RestructureTerms || record.PreviousRate != 12 || record.InterestRate != 9 || record.PreviousTenureMonths != 12 || record.TenureMonths != 24 {
+		t.Errorf("restructuring = %+v, want 12%% over 12 months made 9%% over 24", *record)
+	}
+	if got.TotalPayableAmount != loan.TotalPayableAmount+record.InterestChange {
+		t.Errorf("loan owes %s in all, want %s plus the %s change", got.TotalPayableAmount, loan.TotalPayableAmount, record.InterestChange)
+	}
+	changes, err := f.store.Loans().RateHistory(loan.ID)
+	f.must(err)
+	if len(changes) == 0 {
+		t.Fatal("restructuring the rate left no rate history")
+	}
+	if last := changes[len(changes)-1]; last.Reason != RateChangeRestructure || last.PreviousRate != 12 || last.InterestRate != 9 {
+		t.Errorf("last rate change = %+v, want 12%% to 9%% on restructuring", last)
+	}
+
+	assertScheduleMatches(f, loan.ID)
+	f.assertReconciled()
+}
+
+func TestScheduleVersionsKeepReplacedSchedules(t *testing.T) {
+	f := newFixture(t)
+	account := f.openAccount(f.customer.ID, 0)
+	loan := f.disburseLoan(account.ID, money.MustParse("1200"), 12)
+	loans := NewLoanService(f.store)
+	original, err := f.store.Loans().ListInstallments(loan.ID)
+	f.must(err)
+
+	moratorium, err := loans.GrantMoratorium(loan.ID, Moratorium{Months: 2, InterestTreatment: MoratoriumDefer, Reason: "medical_emergency"})
+	f.must(err)
+	rate := 10.0
+	restructured, err := loans.Restructure(loan.ID, Restructure{InterestRate: &rate, Reason: "medical_emergency"})
+	f.must(err)
+
+	versions, err := loans.GetScheduleVersions(loan.ID)
+	f.must(err)
+	if len(versions) != 3 {
+		t.Fatalf("got %d schedule versions, want 3", len(versions))
+	}
+	want := []struct {
+		current      bool
+		kind         string
+		installments []models.LoanInstallment
+	}{
+		{false, "", original},
+		{false, RestructureMoratorium, moratorium.Schedule},
+		{true, RestructureTerms, restructured.Schedule},
+	}
+	for i, version := range versions {
+		if version.Version != i+1 || version.Current != want[i].current {
+			t.Errorf("version %d = %d, current %v, want %d, current %v", i, version.Version, version.Current, i+1, want[i].current)
+		}
+		var kind string
+		if version.Restructuring != nil {
+			kind = version.Restructuring.Kind
+		}
+		if kind != want[i].kind {
+			t.Errorf("version %d was made by %q, want %q", version.Version, kind, want[i].kind)
+		}
+		if !reflect.DeepEqual(version.Installments, want[i].installments) {
+			t.Errorf("version %d installments = %+v, want %+v", version.Version, version.Installments, want[i].installments)
+		}
+	}
+}
+
+func TestRestructuringNeedsAnActiveLoan(t *testing.T) {
+	f := newFixture(t)
+	account := f.openAccount(f.customer.ID, money.MustParse("5000"))
+	loans := NewLoanService(f.store)
+
+	pending, err := loans.Apply(LoanApplication{
+		CustomerID:            f.customer.ID,
+		ProductID:             f.product.ID,
+		PrincipalAmount:       money.MustParse("1200"),
+		Terms:                 LoanTerms{TenureMonths: 12},
+		DisbursementAccountID: account.ID,
+		SubmittedBy:           "teller",
+	})
+	f.must(err)
+	closed := f.disburseLoan(account.ID, money.MustParse("1200"), 12)
+	_, err = loans.Foreclose(closed.ID, account.ID)
+	f.must(err)
+
+	rate := 10.0
+	for _, tt := range []struct {
+		name   string
+		loanID uint
+		want   error
+	}{
+		{"an undisbursed loan", pending.ID, ErrConflict},
+		{"a closed loan", closed.ID, ErrLoanClosed},
+	} {
+		if _, err := loans.GrantMoratorium(tt.loanID, Moratorium{Months: 3, InterestTreatment: MoratoriumDefer, Reason: "job_loss"}); !errors.Is(err, tt.want) {
+			t.Errorf("moratorium on %s: err = %v, want %v", tt.name, err, tt.want)
+		}
+		if _, err := loans.Restructure(tt.loanID, Restructure{InterestRate: &rate, Reason: "job_loss"}); !errors.Is(err, tt.want) {
+			t.Errorf("restructuring %s: err = %v, want %v", tt.name, err, tt.want)
+		}
+		if versions, err := loans.GetScheduleVersions(tt.loanID); err != nil || len(versions) != 1 {
+			t.Errorf("%s has schedule versions %+v, %v, want only its own", tt.name, versions, err)
+		}
+	}
+	f.assertReconciled()
+}
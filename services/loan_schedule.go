@@ -0,0 +1,311 @@
+package services
+
+import (
+	"banking-system/models"
+	"banking-system/money"
+	"banking-system/repository"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+const (
+	RepaymentMonthly   = "MONTHLY"
+	RepaymentQuarterly = "QUARTERLY"
+)
+
+const (
+	InstallmentPending = "PENDING"
+	InstallmentPartial = "PARTIAL"
+	InstallmentPaid    = "PAID"
+)
+
+const (
+	DefaultLoanTenureMonths = 12
+	MaxLoanTenureMonths     = 360
+)
+
+// LoanTerms is how long a loan runs and how often it is repaid. Zero values
+// take the defaults: 12 months, repaid monthly.
+type LoanTerms struct {
+	TenureMonths       int
+	RepaymentFrequency string
+}
+
+func (t LoanTerms) normalize() (LoanTerms, error) {
+	if t.TenureMonths == 0 {
+		t.TenureMonths = DefaultLoanTenureMonths
+	}
+	t.RepaymentFrequency = strings.ToUpper(t.RepaymentFrequency)
+	if t.RepaymentFrequency == "" {
+		t.RepaymentFrequency = RepaymentMonthly
+	}
+	if t.RepaymentFrequency != RepaymentMonthly && t.RepaymentFrequency != RepaymentQuarterly {
+		return t, fmt.Errorf("%w: repayment_frequency must be %s or %s", ErrInvalidArgument, RepaymentMonthly, RepaymentQuarterly)
+	}
+	if t.TenureMonths < 1 || t.TenureMonths > MaxLoanTenureMonths {
+		return t, fmt.Errorf("%w: tenure_months must be between 1 and %d", ErrInvalidArgument, MaxLoanTenureMonths)
+	}
+	if t.TenureMonths%periodMonths(t.RepaymentFrequency) != 0 {
+		return t, fmt.Errorf("%w: tenure_months must be a whole number of %s periods", ErrInvalidArgument, strings.ToLower(t.RepaymentFrequency))
+	}
+	return t, nil
+}
+
+func periodMonths(frequency string) int {
+	if frequency == RepaymentQuarterly {
+		return 3
+	}
+	return 1
+}
+
+// periodRate is the interest rate for one repayment period of a loan at
+// annualRate percent.
+func periodRate(annualRate float64, frequency string) *big.Rat {
+	r := money.RateRat(annualRate)
+	return r.Mul(r, big.NewRat(int64(periodMonths(frequency)), 12))
+}
+
+// checkInstallmentCount rejects schedules of fewer than one installment,
+// which the amortization formulas cannot divide by.
+func checkInstallmentCount(n int) error {
+	if n < 1 {
+		return fmt.Errorf("%w: a schedule needs at least one installment, not %d", ErrInvalidArgument, n)
+	}
+	return nil
+}
+
+// installmentAmount is the equated installment that repays principal over n
+// periods at rate r per period: P*r*(1+r)^n / ((1+r)^n - 1).
+func installmentAmount(principal money.Money, r *big.Rat, n int) (money.Money, error) {
+	if err := checkInstallmentCount(n); err != nil {
+		return 0, err
+	}
+	if r.Sign() == 0 {
+		return money.FromRat(new(big.Rat).Quo(principal.Rat(), big.NewRat(int64(n), 1)))
+	}
+	growth := new(big.Rat).Add(big.NewRat(1, 1), r)
+	pow := big.NewRat(1, 1)
+	for i := 0; i < n; i++ {
+		pow.Mul(pow, growth)
+	}
+	emi := new(big.Rat).Mul(principal.Rat(), r)
+	emi.Mul(emi, pow)
+	return money.FromRat(emi.Quo(emi, pow.Sub(pow, big.NewRat(1, 1))))
+}
+
+// amortize builds the equated-installment schedule of a loan on the reducing
+// balance. Each period's interest is charged on the principal still
+// outstanding; the last installment clears whatever rounding has left.
+func amortize(principal money.Money, annualRate float64, terms LoanTerms, start time.Time) ([]models.LoanInstallment, error) {
+	return amortizeFrom(principal, annualRate, terms.RepaymentFrequency, start, 1, terms.TenureMonths/periodMonths(terms.RepaymentFrequency))
+}
+
+// amortizeFrom builds n equated installments of principal numbered from
+// first, where installment k falls due k periods after start. It is how the
+// rest of a schedule is rebuilt partway through a loan.
+func amortizeFrom(principal money.Money, annualRate float64, frequency string, start time.Time, first, n int) ([]models.LoanInstallment, error) {
+	r := periodRate(annualRate, frequency)
+	step := periodMonths(frequency)
+	emi, err := installmentAmount(principal, r, n)
+	if err != nil {
+		return nil, err
+	}
+
+	installments := make([]models.LoanInstallment, 0, n)
+	outstanding := principal
+	for k := first; k < first+n; k++ {
+		interest, err := money.FromRat(new(big.Rat).Mul(outstanding.Rat(), r))
+		if err != nil {
+			return nil, err
+		}
+		principalDue := money.Min(money.Max(emi-interest, 0), outstanding)
+		if k == first+n-1 {
+			principalDue = outstanding
+		}
+		outstanding -= principalDue
+		installments = append(installments, models.LoanInstallment{
+			Number:               k,
+			DueDate:              addMonths(BusinessDate(start), k*step),
+			Amount:               principalDue + interest,
+			PrincipalDue:         principalDue,
+			InterestDue:          interest,
+			OutstandingPrincipal: outstanding,
+			Status:               InstallmentPending,
+		})
+	}
+	return installments, nil
+}
+
+// deferInterest spreads amount evenly over installments as deferred
+// interest, the last taking what division leaves.
+func deferInterest(installments []models.LoanInstallment, amount money.Money) {
+	n := money.Money(len(installments))
+	for i := range installments {
+		part := amount / n
+		if i == len(installments)-1 {
+			part = amount - part*(n-1)
+		}
+		installments[i].InterestDue += part
+		installments[i].DeferredInterest += part
+		installments[i].Amount += part
+	}
+}
+
+// reschedule replaces the future installments of loan with rebuilt, keeping
+// their ids and due dates. Installments left over are removed, and any
+// rebuilt beyond them are added a period apart after them. Interest deferred
+// by a moratorium is carried over to the rebuilt installments, and the
+// loan's installment, tenure and end date are brought into line. It returns
+// the interest the rebuilt installments charge.
+func reschedule(tx repository.Store, loan *models.Loan, future, rebuilt []models.LoanInstallment) (money.Money, error) {
+	if err := checkInstallmentCount(len(rebuilt)); err != nil {
+		return 0, err
+	}
+	var deferred money.Money
+	for _, installment := range future {
+		deferred += installment.DeferredInterest
+	}
+	deferInterest(rebuilt, deferred)
+
+	var interest money.Money
+	for i := range future {
+		if i >= len(rebuilt) {
+			if err := tx.Loans().DeleteInstallment(future[i].ID); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		rebuilt[i].ID = future[i].ID
+		rebuilt[i].LoanID = loan.ID
+		rebuilt[i].DueDate = future[i].DueDate
+		rebuilt[i].CreatedAt = future[i].CreatedAt
+		if err := tx.Loans().UpdateInstallment(&rebuilt[i]); err != nil {
+			return 0, err
+		}
+		interest += rebuilt[i].InterestDue
+	}
+	for i := len(future); i < len(rebuilt); i++ {
+		rebuilt[i].LoanID = loan.ID
+		rebuilt[i].DueDate = dueDate(loan, rebuilt[i].Number)
+		if err := tx.Loans().CreateInstallment(&rebuilt[i]); err != nil {
+			return 0, err
+		}
+		interest += rebuilt[i].InterestDue
+	}
+	last := rebuilt[len(rebuilt)-1]
+	loan.InstallmentAmount = rebuilt[0].Amount
+	loan.TenureMonths = last.Number*periodMonths(loan.RepaymentFrequency) + loan.MoratoriumMonths
+	loan.EndDate = &last.DueDate
+	return interest, nil
+}
+
+// dueDate is when installment number of loan falls due: that many periods
+// after disbursement, put off by any moratorium it has been granted.
+func dueDate(loan *models.Loan, number int) time.Time {
+	return addMonths(BusinessDate(loan.StartDate), number*periodMonths(loan.RepaymentFrequency)+loan.MoratoriumMonths)
+}
+
+// addMonths moves date forward by months, keeping the day of the month where
+// it exists and using the month's last day where it does not.
+func addMonths(date time.Time, months int) time.Time {
+	y, m, d := date.Date()
+	last := time.Date(y, m+time.Month(months)+1, 0, 0, 0, 0, 0, date.Location()).Day()
+	return time.Date(y, m+time.Month(months), min(d, last), 0, 0, 0, 0, date.Location())
+}
+
+// repaymentAllocation is how one repayment splits between outstanding charges
+// and the schedule.
+type repaymentAllocation struct {
+	charges      money.Money
+	principal    money.Money
+	interest     money.Money
+	installments []models.LoanInstallment
+	parts        []models.LoanPaymentAllocation
+}
+
+// allocateRepayment settles the loan's outstanding charges first and applies
+// the rest to its unpaid installments, oldest first and interest before
+// principal within each. Loans from before schedules existed have no
+// installments; their repayments are split between principal and interest in
+// proportion to the payable amount.
+func allocateRepayment(tx repository.Store, loan *models.Loan, amount money.Money) (*repaymentAllocation, error) {
+	installments, err := tx.Loans().ListInstallments(loan.ID)
+	if err != nil {
+		return nil, err
+	}
+	allocation := repaymentAllocation{charges: money.Min(amount, loan.ChargesDue)}
+	remaining := amount - allocation.charges
+	if len(installments) == 0 {
+		interest := loan.TotalPayableAmount - loan.PrincipalAmount
+		share := remaining.Rat()
+		share.Mul(share, interest.Rat())
+		share.Quo(share, loan.TotalPayableAmount.Rat())
+		if allocation.interest, err = money.FromRat(share); err != nil {
+			return nil, err
+		}
+		allocation.principal = remaining - allocation.interest
+		return &allocation, nil
+	}
+
+	now := time.Now()
+	for _, installment := range installments {
+		if remaining.IsZero() {
+			break
+		}
+		if installment.Status == InstallmentPaid {
+			continue
+		}
+		interest := money.Min(remaining, installment.InterestDue-installment.InterestPaid)
+		remaining -= interest
+		principal := money.Min(remaining, installment.PrincipalDue-installment.PrincipalPaid)
+		remaining -= principal
+		if interest.IsZero() && principal.IsZero() {
+			continue
+		}
+
+		installment.InterestPaid += interest
+		installment.PrincipalPaid += principal
+		installment.Status = InstallmentPartial
+		if installment.InterestPaid == installment.InterestDue && installment.PrincipalPaid == installment.PrincipalDue {
+			installment.Status = InstallmentPaid
+			installment.PaidAt = &now
+		}
+		allocation.principal += principal
+		allocation.interest += interest
+		allocation.installments = append(allocation.installments, installment)
+		allocation.parts = append(allocation.parts, models.LoanPaymentAllocation{
+			InstallmentID: installment.ID,
+			Principal:     principal,
+			Interest:      interest,
+		})
+	}
+	if remaining.IsPositive() {
+		return nil, fmt.Errorf("%w: loan %d: schedule leaves %s of the repayment unallocated", ErrConflict, loan.ID, remaining)
+	}
+	return &allocation, nil
+}
+
+// save writes the updated installments and the allocation rows of payment.
+func (a *repaymentAllocation) save(tx repository.Store, paymentID uint) error {
+	for i := range a.installments {
+		if err := tx.Loans().UpdateInstallment(&a.installments[i]); err != nil {
+			return err
+		}
+	}
+	for i := range a.parts {
+		a.parts[i].PaymentID = paymentID
+		if err := tx.Loans().CreateAllocation(&a.parts[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ls *LoanService) GetLoanSchedule(loanID uint) ([]models.LoanInstallment, error) {
+	if _, err := ls.store.Loans().Get(loanID); err != nil {
+		return nil, lookupError("loan", err)
+	}
+	return ls.store.Loans().ListInstallments(loanID)
+}
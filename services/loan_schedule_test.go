@@ -0,0 +1,46 @@
+package services
+
+import (
+	"banking-system/money"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestAmortize(t *testing.T) {
+	principal := money.MustParse("1200")
+	installments, err := amortize(principal, 12, LoanTerms{TenureMonths: 12, RepaymentFrequency: RepaymentMonthly}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(installments) != 12 {
+		t.Fatalf("%d installments, want 12", len(installments))
+	}
+	// 1200 at 1% a month over 12 months is 106.62 a month.
+	if want := money.MustParse("106.62"); installments[0].Amount != want {
+		t.Errorf("installment = %s, want %s", installments[0].Amount, want)
+	}
+	var repaid money.Money
+	for _, installment := range installments {
+		repaid += installment.PrincipalDue
+	}
+	if repaid != principal || !installments[11].OutstandingPrincipal.IsZero() {
+		t.Errorf("schedule repays %s and leaves %s, want %s and nothing", repaid, installments[11].OutstandingPrincipal, principal)
+	}
+}
+
+func TestSchedulesNeedAnInstallment(t *testing.T) {
+	if _, err := installmentAmount(money.MustParse("100"), big.NewRat(1, 100), 0); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("installmentAmount over 0 periods: err = %v, want ErrInvalidArgument", err)
+	}
+	if _, err := amortizeFrom(money.MustParse("100"), 12, RepaymentMonthly, time.Now(), 1, 0); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("amortizeFrom over 0 periods: err = %v, want ErrInvalidArgument", err)
+	}
+	f := newFixture(t)
+	account := f.openAccount(f.customer.ID, 0)
+	loan := f.disburseLoan(account.ID, money.MustParse("1200"), 12)
+	if _, err := reschedule(f.store, &loan, nil, nil); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("reschedule onto nothing: err = %v, want ErrInvalidArgument", err)
+	}
+}
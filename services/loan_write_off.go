@@ -0,0 +1,193 @@
+package services
+
+import (
+	"banking-system/models"
+	"banking-system/money"
+	"banking-system/repository"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const TransactionLoanRecovery = "loan_recovery"
+
+var writeOffReasons = []string{
+	"uncollectible", "borrower_deceased", "bankruptcy", "fraud", "settlement",
+}
+
+// Recovery is money recovered on a written-off loan. Cost is what recovering
+// it cost and is paid out of it. A nil AccountID means it was received in
+// cash.
+type Recovery struct {
+	Amount     money.Money
+	Cost       money.Money
+	AccountID  *uint
+	Note       string
+	RecordedBy string
+}
+
+// WriteOff writes an active loan off as a loss. Everything it still owes is
+// cleared from the receivable: the interest on it, never earned, comes out
+// of unearned interest and the rest is charged to loan losses. The loan
+// stays WRITTEN_OFF for good; anything recovered on it later is recorded
+// with RecordRecovery rather than as a repayment.
+func (ls *LoanService) WriteOff(loanID uint, change StatusChange) (*models.LoanWriteOff, error) {
+	if !slices.Contains(writeOffReasons, change.Reason) {
+		return nil, fmt.Errorf("%w: reason must be one of %s", ErrInvalidArgument, strings.Join(writeOffReasons, ", "))
+	}
+	if change.ChangedBy == "" {
+		return nil, fmt.Errorf("%w: the approver must be identified", ErrInvalidArgument)
+	}
+
+	var writeOff models.LoanWriteOff
+	err := ls.store.Atomic(func(tx repository.Store) error {
+		loan, err := tx.Loans().GetForUpdate(loanID)
+		if err != nil {
+			return lookupError("loan", err)
+		}
+		if err := checkLoanTransition(loan, LoanStatusWrittenOff); err != nil {
+			return err
+		}
+		unearned, err := ls.ledger.UnearnedInterest(tx, loan.ID)
+		if err != nil {
+			return err
+		}
+		amount := loan.PendingAmount
+		interest := money.Min(unearned, amount-loan.ChargesDue)
+		entry, err := ls.ledger.RecordLoanWriteOff(tx, loan.ID, amount, interest)
+		if err != nil {
+			return err
+		}
+
+		writeOff = models.LoanWriteOff{
+			LoanID:         loan.ID,
+			WriteOffDate:   BusinessDate(time.Now()),
+			Principal:      amount - interest - loan.ChargesDue,
+			Interest:       interest,
+			Charges:        loan.ChargesDue,
+			Amount:         amount,
+			Loss:           amount - interest,
+			Reason:         change.Reason,
+			Note:           change.Note,
+			ApprovedBy:     change.ChangedBy,
+			JournalEntryID: &entry.ID,
+		}
+		if err := tx.Loans().CreateWriteOff(&writeOff); err != nil {
+			return storeError(err)
+		}
+
+		from := loan.Status
+		loan.Status = LoanStatusWrittenOff
+		loan.PendingAmount = 0
+		loan.ChargesDue = 0
+		if err := tx.Loans().Update(loan); err != nil {
+			return err
+		}
+		return recordLoanStatus(tx, loan, from, change)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &writeOff, nil
+}
+
+// RecordRecovery records money recovered on a written-off loan, taken from a
+// savings account the borrower holds or received in cash. It is booked as
+// income, apart from the loan's payments, and added to the write-off's
+// totals; no more can be recovered than was written off.
+func (ls *LoanService) RecordRecovery(loanID uint, recovery Recovery) (*models.LoanRecovery, error) {
+	if !recovery.Amount.IsPositive() {
+		return nil, fmt.Errorf("%w: amount must be positive", ErrInvalidArgument)
+	}
+	if recovery.Cost < 0 || recovery.Cost > recovery.Amount {
+		return nil, fmt.Errorf("%w: cost must be between 0 and the amount recovered", ErrInvalidArgument)
+	}
+
+	var record models.LoanRecovery
+	err := ls.store.Atomic(func(tx repository.Store) error {
+		loan, err := tx.Loans().GetForUpdate(loanID)
+		if err != nil {
+			return lookupError("loan", err)
+		}
+		if loan.Status != LoanStatusWrittenOff {
+			return fmt.Errorf("%w: loan %d is %s, not written off", ErrConflict, loan.ID, loan.Status)
+		}
+		writeOff, err := tx.Loans().GetWriteOff(loan.ID)
+		if err != nil {
+			return lookupError("write-off", err)
+		}
+		if left := writeOff.Amount - writeOff.GrossRecovered; recovery.Amount > left {
+			return fmt.Errorf("%w: only %s of loan %d is left to recover", ErrInvalidArgument, left, loan.ID)
+		}
+
+		var account *models.SavingsAccount
+		if recovery.AccountID != nil {
+			if account, err = debitable(tx, loan, *recovery.AccountID, recovery.Amount); err != nil {
+				return err
+			}
+		}
+		entry, err := ls.ledger.RecordLoanRecovery(tx, loan.ID, recovery.AccountID, recovery.Amount, recovery.Cost)
+		if err != nil {
+			return err
+		}
+
+		record = models.LoanRecovery{
+			LoanID:         loan.ID,
+			WriteOffID:     writeOff.ID,
+			RecoveryDate:   BusinessDate(time.Now()),
+			Amount:         recovery.Amount,
+			Cost:           recovery.Cost,
+			NetAmount:      recovery.Amount - recovery.Cost,
+			AccountID:      recovery.AccountID,
+			JournalEntryID: &entry.ID,
+			Note:           recovery.Note,
+			RecordedBy:     recovery.RecordedBy,
+		}
+		if account != nil {
+			account.Balance -= recovery.Amount
+			if err := tx.Accounts().Update(account); err != nil {
+				return err
+			}
+			debit := models.Transaction{
+				AccountID:      account.ID,
+				Type:           TransactionLoanRecovery,
+				Amount:         recovery.Amount,
+				JournalEntryID: &entry.ID,
+			}
+			if err := tx.Transactions().Create(&debit); err != nil {
+				return err
+			}
+			record.TransactionID = &debit.ID
+		}
+		if err := tx.Loans().AddRecovery(&record); err != nil {
+			return err
+		}
+
+		writeOff.GrossRecovered += recovery.Amount
+		writeOff.RecoveryCosts += recovery.Cost
+		return tx.Loans().UpdateWriteOff(writeOff)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (ls *LoanService) GetWriteOff(loanID uint) (*models.LoanWriteOff, error) {
+	if _, err := ls.store.Loans().Get(loanID); err != nil {
+		return nil, lookupError("loan", err)
+	}
+	writeOff, err := ls.store.Loans().GetWriteOff(loanID)
+	if err != nil {
+		return nil, lookupError("write-off", err)
+	}
+	return writeOff, nil
+}
+
+func (ls *LoanService) GetRecoveries(loanID uint) ([]models.LoanRecovery, error) {
+	if _, err := ls.store.Loans().Get(loanID); err != nil {
+		return nil, lookupError("loan", err)
+	}
+	return ls.store.Loans().ListRecoveries(loanID)
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"banking-system/models"
+	"banking-system/money"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// writtenOffLoan disburses 1,200 at 12% over 12 months into a fresh
+// account, repays the first installment of 106.62, of which 12.00 is
+// interest, and writes the rest off.
+func writtenOffLoan(f *fixture) (models.Loan, models.SavingsAccount, *models.LoanWriteOff) {
+	f.t.Helper()
+	account := f.openAccount(f.customer.ID, money.MustParse("500"))
+	loan := f.disburseLoan(account.ID, money.MustParse("1200"), 12)
+	loans := NewLoanService(f.store)
+	_, err := loans.RepayLoan(loan.ID, account.ID, loan.InstallmentAmount)
+	f.must(err)
+	writeOff, err := loans.WriteOff(loan.ID, StatusChange{Reason: "uncollectible", ChangedBy: "manager"})
+	f.must(err)
+	return loan, f.account(account.ID), writeOff
+}
+
+func TestWriteOff(t *testing.T) {
+	f := newFixture(t)
+	loan, _, writeOff := writtenOffLoan(f)
+
+	owed := loan.TotalPayableAmount - loan.InstallmentAmount
+	interest := loan.TotalPayableAmount - loan.PrincipalAmount - money.MustParse("12")
+	principal := money.MustParse("1200") - (loan.InstallmentAmount - money.MustParse("12"))
+	if writeOff.Amount != owed || writeOff.Interest != interest || writeOff.Principal != principal || writeOff.Charges != 0 || writeOff.Loss != principal {
+		t.Errorf("write-off = %+v, want %s owed: %s of principal lost and %s of interest never earned", *writeOff, owed, principal, interest)
+	}
+
+	written, err := f.store.Loans().Get(loan.ID)
+	f.must(err)
+	if written.Status != LoanStatusWrittenOff || !written.PendingAmount.IsZero() {
+		t.Errorf("loan is %s with %s pending, want it written off with nothing pending", written.Status, written.PendingAmount)
+	}
+	for code, want := range map[string]money.Money{
+		fmt.Sprintf("LOAN-%d", loan.ID):          0,
+		fmt.Sprintf("UNEARNED-LOAN-%d", loan.ID): 0,
+		LedgerLoanLosses:                         principal,
+	} {
+		if got := f.ledgerBalance(code); got != want {
+			t.Errorf("%s holds %s after the write-off, want %s", code, got, want)
+		}
+	}
+	f.assertReconciled()
+
+	if _, err := NewLoanService(f.store).WriteOff(loan.ID, StatusChange{Reason: "uncollectible", ChangedBy: "manager"}); err == nil {
+		t.Error("writing the loan off twice succeeded")
+	}
+}
+
+func TestWriteOffRejects(t *testing.T) {
+	f := newFixture(t)
+	account := f.openAccount(f.customer.ID, 0)
+	loan := f.disburseLoan(account.ID, money.MustParse("1200"), 12)
+	loans := NewLoanService(f.store)
+
+	for _, change := range []StatusChange{
+		{Reason: "bored", ChangedBy: "manager"},
+		{Reason: "uncollectible"},
+	} {
+		if _, err := loans.WriteOff(loan.ID, change); !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("writing off with %+v: err = %v, want %v", change, err, ErrInvalidArgument)
+		}
+	}
+	if _, err := loans.RecordRecovery(loan.ID, Recovery{Amount: money.MustParse("10")}); !errors.Is(err, ErrConflict) {
+		t.Errorf("recovering on an active loan: err = %v, want %v", err, ErrConflict)
+	}
+	f.assertReconciled()
+}
+
+func TestRecoveries(t *testing.T) {
+	f := newFixture(t)
+	loan, account, writeOff := writtenOffLoan(f)
+	loans := NewLoanService(f.store)
+
+	if _, err := loans.RecordRecovery(loan.ID, Recovery{Amount: money.MustParse("10"), Cost: money.MustParse("11")}); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("recovering less than it cost: err = %v, want %v", err, ErrInvalidArgument)
+	}
+
+	cash, err := loans.RecordRecovery(loan.ID, Recovery{Amount: money.MustParse("300"), Cost: money.MustParse("50"), RecordedBy: "collector"})
+	f.must(err)
+	if cash.NetAmount != money.MustParse("250") || cash.TransactionID != nil {
+		t.Errorf("cash recovery = %+v, want 250.00 net and no savings transaction", *cash)
+	}
+	fromSavings, err := loans.RecordRecovery(loan.ID, Recovery{Amount: money.MustParse("200"), AccountID: &account.ID})
+	f.must(err)
+	if fromSavings.TransactionID == nil {
+		t.Fatal("recovery from savings recorded no transaction")
+	}
+	if after := f.account(account.ID).Balance; after != account.Balance-money.MustParse("200") {
+		t.Errorf("account holds %s after the recovery, want %s", after, account.Balance-money.MustParse("200"))
+	}
+	for code, want := range map[string]money.Money{
+		LedgerLoanRecoveries:            money.MustParse("-500"),
+		LedgerRecoveryExpense:           money.MustParse("50"),
+		LedgerLoanLosses:                writeOff.Loss,
+		fmt.Sprintf("LOAN-%d", loan.ID): 0,
+	} {
+		if got := f.ledgerBalance(code); got != want {
+			t.Errorf("%s holds %s after the recoveries, want %s", code, got, want)
+		}
+	}
+	f.assertReconciled()
+
+	// No more can be recovered than was written off.
+	left := writeOff.Amount - money.MustParse("500")
+	if _, err := loans.RecordRecovery(loan.ID, Recovery{Amount: left + 1}); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("recovering %s with %s left: err = %v, want %v", left+1, left, err, ErrInvalidArgument)
+	}
+	_, err = loans.RecordRecovery(loan.ID, Recovery{Amount: left})
+	f.must(err)
+	if _, err := loans.RecordRecovery(loan.ID, Recovery{Amount: money.MustParse("0.01")}); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("recovering on a fully recovered loan: err = %v, want %v", err, ErrInvalidArgument)
+	}
+
+	recovered, err := loans.GetWriteOff(loan.ID)
+	f.must(err)
+	if recovered.GrossRecovered != writeOff.Amount || recovered.RecoveryCosts != money.MustParse("50") {
+		t.Errorf("write-off = %+v, want all %s recovered at a cost of 50.00", *recovered, writeOff.Amount)
+	}
+	recoveries, err := loans.GetRecoveries(loan.ID)
+	f.must(err)
+	if len(recoveries) != 3 {
+		t.Errorf("loan has %d recoveries, want 3", len(recoveries))
+	}
+	f.assertReconciled()
+}
+
+func TestPortfolio(t *testing.T) {
+	f := newFixture(t)
+	loan, _, writeOff := writtenOffLoan(f)
+	loans := NewLoanService(f.store)
+	_, err := loans.RecordRecovery(loan.ID, Recovery{Amount: money.MustParse("300"), Cost: money.MustParse("50")})
+	f.must(err)
+
+	account := f.openAccount(f.customer.ID, money.MustParse("2000"))
+	active := f.disburseLoan(account.ID, money.MustParse("2400"), 24)
+	closed := f.disburseLoan(account.ID, money.MustParse("600"), 6)
+	_, err = loans.Foreclose(closed.ID, account.ID)
+	f.must(err)
+
+	portfolio, err := loans.Portfolio(PortfolioQuery{})
+	f.must(err)
+	want := LoanPortfolio{
+		Active:         PortfolioLine{Loans: 1, Amount: active.PendingAmount},
+		Buckets:        map[string]PortfolioLine{active.DelinquencyBucket: {Loans: 1, Amount: active.PendingAmount}},
+		Closed:         1,
+		WrittenOff:     PortfolioLine{Loans: 1, Amount: writeOff.Amount},
+		WriteOffLoss:   writeOff.Loss,
+		GrossRecovered: money.MustParse("300"),
+		RecoveryCosts:  money.MustParse("50"),
+		NetRecovered:   money.MustParse("250"),
+		NetLoss:        writeOff.Loss - money.MustParse("250"),
+	}
+	if !reflect.DeepEqual(*portfolio, want) {
+		t.Errorf("portfolio = %+v, want %+v", *portfolio, want)
+	}
+	f.assertReconciled()
+}
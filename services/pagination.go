@@ -0,0 +1,125 @@
+package services
+
+import (
+	"banking-system/repository"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 200
+)
+
+// pageSize applies the default and rejects sizes outside 1..MaxPageSize.
+func pageSize(limit int) (int, error) {
+	switch {
+	case limit == 0:
+		return DefaultPageSize, nil
+	case limit < 0 || limit > MaxPageSize:
+		return 0, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidArgument, MaxPageSize)
+	}
+	return limit, nil
+}
+
+// Cursors are opaque to clients: base64url-encoded JSON of the position the
+// next page starts after.
+func encodeCursor(position interface{}) string {
+	b, _ := json.Marshal(position)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(cursor string, position interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err == nil {
+		err = json.Unmarshal(b, position)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: malformed cursor", ErrInvalidArgument)
+	}
+	return nil
+}
+
+// ListQuery pages and orders a collection. Sort names a column, prefixed
+// with "-" for descending order; Cursor is the NextCursor of the previous
+// page.
+type ListQuery struct {
+	Sort         string
+	Cursor       string
+	Limit        int
+	IncludeTotal bool
+}
+
+// Page is one page of a collection. Total is only set when it was asked for.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Total      *int64 `json:"total,omitempty"`
+}
+
+// Collection cursors carry an offset. Unlike transaction history these
+// lists can be sorted on any column, and they change slowly enough that an
+// offset is stable in practice.
+type offsetCursor struct {
+	Offset int `json:"o"`
+}
+
+// options checks q against the sortable columns and turns it into
+// repository options that fetch one row more than the page size, so that
+// newPage can tell whether another page follows.
+func (q ListQuery) options(sortable ...string) (repository.ListOptions, error) {
+	limit, err := pageSize(q.Limit)
+	if err != nil {
+		return repository.ListOptions{}, err
+	}
+	opts := repository.ListOptions{Limit: limit + 1}
+
+	column := strings.TrimPrefix(q.Sort, "-")
+	opts.Desc = column != q.Sort
+	if column != "" && column != "id" {
+		found := false
+		for _, s := range sortable {
+			found = found || s == column
+		}
+		if !found {
+			return repository.ListOptions{}, fmt.Errorf("%w: cannot sort by %q (use id, %s)", ErrInvalidArgument, column, strings.Join(sortable, ", "))
+		}
+		opts.Sort = column
+	}
+
+	if q.Cursor != "" {
+		var cursor offsetCursor
+		if err := decodeCursor(q.Cursor, &cursor); err != nil {
+			return repository.ListOptions{}, err
+		}
+		if cursor.Offset < 0 {
+			return repository.ListOptions{}, fmt.Errorf("%w: malformed cursor", ErrInvalidArgument)
+		}
+		opts.Offset = cursor.Offset
+	}
+	return opts, nil
+}
+
+// newPage trims the extra row fetched by options and, when asked, counts
+// the whole collection.
+func newPage[T any](rows []T, opts repository.ListOptions, q ListQuery, count func() (int64, error)) (*Page[T], error) {
+	size := opts.Limit - 1
+	page := Page[T]{Items: rows}
+	if len(rows) > size {
+		page.Items = rows[:size]
+		page.NextCursor = encodeCursor(offsetCursor{Offset: opts.Offset + size})
+	}
+	if page.Items == nil {
+		page.Items = []T{}
+	}
+	if q.IncludeTotal {
+		total, err := count()
+		if err != nil {
+			return nil, err
+		}
+		page.Total = &total
+	}
+	return &page, nil
+}
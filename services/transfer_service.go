@@ -0,0 +1,142 @@
+package services
+
+import (
+	"banking-system/models"
+	"banking-system/money"
+	"banking-system/repository"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+const (
+	TransactionTransferOut = "transfer_out"
+	TransactionTransferIn  = "transfer_in"
+)
+
+type Transfer struct {
+	TransferID  string                `json:"transfer_id"`
+	FromAccount models.SavingsAccount `json:"from_account"`
+	ToAccount   models.SavingsAccount `json:"to_account"`
+	Debit       models.Transaction    `json:"debit"`
+	Credit      models.Transaction    `json:"credit"`
+}
+
+type TransferService struct {
+	store  repository.Store
+	ledger *LedgerService
+}
+
+func NewTransferService(store repository.Store) *TransferService {
+	return &TransferService{store: store, ledger: NewLedgerService(store)}
+}
+
+// Transfer moves amount between two savings accounts in one database
+// transaction. Both rows are locked in ascending id order so that two
+// transfers running in opposite directions cannot deadlock.
+func (ts *TransferService) Transfer(fromAccountID, toAccountID uint, amount money.Money) (*Transfer, error) {
+	if fromAccountID == toAccountID {
+		return nil, ErrSameAccount
+	}
+	if !amount.IsPositive() {
+		return nil, ErrInvalidArgument
+	}
+
+	transferID, err := newTransferID()
+	if err != nil {
+		return nil, err
+	}
+
+	var result Transfer
+	err = ts.store.Atomic(func(tx repository.Store) error {
+		first, second := fromAccountID, toAccountID
+		if second < first {
+			first, second = second, first
+		}
+		locked := map[uint]*models.SavingsAccount{}
+		for _, id := range []uint{first, second} {
+			account, err := tx.Accounts().GetForUpdate(id)
+			if err != nil {
+				return lookupError("account", err)
+			}
+			locked[id] = account
+		}
+		from, to := locked[fromAccountID], locked[toAccountID]
+
+		if err := canDebit(from); err != nil {
+			return err
+		}
+		if err := canCredit(to); err != nil {
+			return err
+		}
+		if from.Balance < amount {
+			return ErrInsufficientFunds
+		}
+		from.Balance -= amount
+		to.Balance += amount
+
+		if err := tx.Accounts().Update(from); err != nil {
+			return err
+		}
+		if err := tx.Accounts().Update(to); err != nil {
+			return err
+		}
+
+		entry, err := ts.ledger.RecordTransfer(tx, from.ID, to.ID, amount)
+		if err != nil {
+			return err
+		}
+
+		debit := models.Transaction{
+			AccountID:      from.ID,
+			Type:           TransactionTransferOut,
+			Amount:         amount,
+			TransferID:     transferID,
+			JournalEntryID: &entry.ID,
+		}
+		credit := models.Transaction{
+			AccountID:      to.ID,
+			Type:           TransactionTransferIn,
+			Amount:         amount,
+			TransferID:     transferID,
+			JournalEntryID: &entry.ID,
+		}
+		if err := tx.Transactions().Create(&debit); err != nil {
+			return err
+		}
+		if err := tx.Transactions().Create(&credit); err != nil {
+			return err
+		}
+
+		result = Transfer{
+			TransferID:  transferID,
+			FromAccount: *from,
+			ToAccount:   *to,
+			Debit:       debit,
+			Credit:      credit,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (ts *TransferService) GetTransfer(transferID string) ([]models.Transaction, error) {
+	transactions, err := ts.store.Transactions().ListByTransfer(transferID)
+	if err != nil {
+		return nil, err
+	}
+	if len(transactions) == 0 {
+		return nil, notFound("transfer")
+	}
+	return transactions, nil
+}
+
+func newTransferID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"banking-system/money"
+	"errors"
+	"testing"
+)
+
+func TestTransfer(t *testing.T) {
+	f := newFixture(t)
+	bob := f.newCustomer(f.branch.ID, "bob@example.com")
+	from := f.openAccount(f.customer.ID, money.MustParse("100"))
+	to := f.openAccount(bob.ID, 0)
+
+	transfer, err := NewTransferService(f.store).Transfer(from.ID, to.ID, money.MustParse("30"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := f.account(from.ID).Balance; got != money.MustParse("70") {
+		t.Errorf("from balance = %s, want 70.00", got)
+	}
+	if got := f.account(to.ID).Balance; got != money.MustParse("30") {
+		t.Errorf("to balance = %s, want 30.00", got)
+	}
+	legs, err := NewTransferService(f.store).GetTransfer(transfer.TransferID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(legs) != 2 {
+		t.Errorf("transfer has %d transactions, want 2", len(legs))
+	}
+	f.assertReconciled()
+}
+
+func TestTransferFailuresLeaveBalancesAlone(t *testing.T) {
+	f := newFixture(t)
+	from := f.openAccount(f.customer.ID, money.MustParse("100"))
+	to := f.openAccount(f.customer.ID, 0)
+	transfers := NewTransferService(f.store)
+
+	tests := []struct {
+		name     string
+		from, to uint
+		amount   money.Money
+		want     error
+	}{
+		{"insufficient funds", from.ID, to.ID, money.MustParse("100.01"), ErrInsufficientFunds},
+		{"same account", from.ID, from.ID, money.MustParse("1"), ErrSameAccount},
+		{"zero amount", from.ID, to.ID, 0, ErrInvalidArgument},
+		{"unknown account", from.ID, 999, money.MustParse("1"), ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := transfers.Transfer(tt.from, tt.to, tt.amount); !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+	if got := f.account(from.ID).Balance; got != money.MustParse("100") {
+		t.Errorf("from balance = %s, want 100.00", got)
+	}
+	if got := f.account(to.ID).Balance; got != 0 {
+		t.Errorf("to balance = %s, want 0.00", got)
+	}
+	f.assertReconciled()
+}